@@ -0,0 +1,74 @@
+//go:build !race
+
+package benchmark
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog"
+)
+
+// TestZeroAllocs checks that logging a message, with or without typed fields, does not allocate,
+// neither on the goroutine that logs nor on the report goroutine that writes the entry.
+// The pool of reports drops items at random under the race detector, so it only runs without it.
+func TestZeroAllocs(t *testing.T) {
+	ionlog.SetAttributes(
+		ionlog.WithWriters(io.Discard),
+		ionlog.WithQueueSize(10000),
+		ionlog.WithStaticFields(map[string]string{"service": "benchmark"}),
+	)
+	ionlog.Start()
+	defer ionlog.Reset()
+
+	tests := []struct {
+		name string
+		log  func()
+	}{
+		{"Info", func() { ionlog.Info(fakeMessage) }},
+		{"Error", func() { ionlog.Error(fakeMessage) }},
+		{"Warn", func() { ionlog.Warn(fakeMessage) }},
+		{"Debug", func() { ionlog.Debug(fakeMessage) }},
+		{"Info with fields", func() {
+			ionlog.Info(fakeMessage,
+				ionlog.String("user", "alice"),
+				ionlog.Int("attempt", 3),
+				ionlog.Bool("cached", true),
+				ionlog.Float64("ratio", 0.25),
+				ionlog.Time("deadline", time.Time{}),
+			)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the first entries resolve the call site, grow the buffers and fill the pool of reports
+			for range 1000 {
+				tt.log()
+			}
+			ionlog.Flush()
+
+			allocs := testing.AllocsPerRun(1000, tt.log)
+			ionlog.Flush()
+
+			if allocs != 0 {
+				t.Errorf("expected no allocations, got %v per entry", allocs)
+			}
+		})
+	}
+}
+
+func BenchmarkTypedFields(b *testing.B) {
+	ionlog.SetAttributes(
+		ionlog.WithWriters(io.Discard),
+		ionlog.WithQueueSize(1000),
+	)
+	ionlog.Start()
+	defer ionlog.Reset()
+
+	b.ReportAllocs()
+	for range b.N {
+		ionlog.Info(fakeMessage, ionlog.String("user", "alice"), ionlog.Int("attempt", 3))
+	}
+}
@@ -15,7 +15,7 @@ func BenchmarkBasicLogs(b *testing.B) {
 	)
 
 	ionlog.Start()
-	defer ionlog.Stop()
+	defer ionlog.Reset()
 
 	b.Run("Trace", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
@@ -85,7 +85,7 @@ func BenchmarkBasicLogsParallel(b *testing.B) {
 	)
 
 	ionlog.Start()
-	defer ionlog.Stop()
+	defer ionlog.Reset()
 
 	b.Run("Trace", func(b *testing.B) {
 		b.RunParallel(func(pb *testing.PB) {
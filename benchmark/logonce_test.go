@@ -10,7 +10,7 @@ import (
 func BenchmarkLogOnceNoChange(b *testing.B) {
 	ionlog.SetAttributes(ionlog.WithQueueSize(1000))
 	ionlog.Start()
-	defer ionlog.Stop()
+	defer ionlog.Reset()
 
 	b.ResetTimer()
 
@@ -66,7 +66,7 @@ func BenchmarkLogOnceNoChange(b *testing.B) {
 func BenchmarkLogOnceWithChange(b *testing.B) {
 	ionlog.SetAttributes(ionlog.WithQueueSize(1000))
 	ionlog.Start()
-	defer ionlog.Stop()
+	defer ionlog.Reset()
 
 	b.Run("LogOnceDebug", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
@@ -120,7 +120,7 @@ func BenchmarkLogOnceWithChange(b *testing.B) {
 func BenchmarkLogOnceNoChangeParallel(b *testing.B) {
 	ionlog.SetAttributes(ionlog.WithQueueSize(1000))
 	ionlog.Start()
-	defer ionlog.Stop()
+	defer ionlog.Reset()
 
 	b.Run("LogOnceDebug", func(b *testing.B) {
 		b.RunParallel(func(pb *testing.PB) {
@@ -190,7 +190,7 @@ func BenchmarkLogOnceNoChangeParallel(b *testing.B) {
 func BenchmarkLogOnceWithChangeParallel(b *testing.B) {
 	ionlog.SetAttributes(ionlog.WithQueueSize(1000))
 	ionlog.Start()
-	defer ionlog.Stop()
+	defer ionlog.Reset()
 
 	b.Run("LogOnceDebug", func(b *testing.B) {
 		b.RunParallel(func(pb *testing.PB) {
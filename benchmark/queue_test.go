@@ -0,0 +1,92 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/IonicHealthUsa/ionlog"
+	"github.com/IonicHealthUsa/ionlog/internal/core/ringbuffer"
+)
+
+var procs = []int{1, 2, 4, 8}
+
+// BenchmarkParallelLogs logs from every P, it shows how the logging calls scale with GOMAXPROCS.
+func BenchmarkParallelLogs(b *testing.B) {
+	ionlog.SetAttributes(
+		ionlog.WithWriters(io.Discard),
+		ionlog.WithQueueSize(4096),
+	)
+	ionlog.Start()
+	defer ionlog.Reset()
+
+	for _, n := range procs {
+		b.Run(fmt.Sprintf("procs=%d", n), func(b *testing.B) {
+			defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(n))
+
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					ionlog.Info(fakeMessage)
+				}
+			})
+			ionlog.Flush()
+		})
+	}
+}
+
+// BenchmarkReportQueue compares the queue of reports with a channel,
+// many producers push while a single consumer pops.
+func BenchmarkReportQueue(b *testing.B) {
+	for _, n := range procs {
+		b.Run(fmt.Sprintf("ringbuffer/procs=%d", n), func(b *testing.B) {
+			defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(n))
+
+			q := ringbuffer.NewRingBuffer[*int](4096)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for {
+					for _, ok := q.Pop(); ok; _, ok = q.Pop() {
+					}
+					if !q.Wait(ctx) {
+						return
+					}
+				}
+			}()
+
+			v := new(int)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					q.PushWait(ctx, v)
+				}
+			})
+			cancel()
+			<-done
+		})
+
+		b.Run(fmt.Sprintf("channel/procs=%d", n), func(b *testing.B) {
+			defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(n))
+
+			ch := make(chan *int, 4096)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for range ch {
+				}
+			}()
+
+			v := new(int)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					ch <- v
+				}
+			})
+			close(ch)
+			<-done
+		})
+	}
+}
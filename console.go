@@ -0,0 +1,61 @@
+package ionlog
+
+import (
+	"io"
+
+	"github.com/IonicHealthUsa/ionlog/internal/styles"
+)
+
+type ColorMode = styles.ColorMode
+
+const (
+	ColorAuto   = styles.ColorAuto
+	ColorAlways = styles.ColorAlways
+	ColorNever  = styles.ColorNever
+)
+
+type LayoutElement = styles.LayoutElement
+
+const (
+	LayoutTime    = styles.LayoutTime
+	LayoutLevel   = styles.LayoutLevel
+	LayoutCaller  = styles.LayoutCaller
+	LayoutMessage = styles.LayoutMessage
+	LayoutSource  = styles.LayoutSource
+	LayoutFields  = styles.LayoutFields
+)
+
+// Theme maps a level to the ANSI sequence used to paint it,
+// e.g. Theme{ionlog.WarnLevel: "\033[35m"}.
+type Theme = styles.Theme
+
+type ConsoleOption = styles.ConsoleOption
+
+// NewConsoleWriter creates a colorful, human readable writer targeting w.
+// The colors are enabled only when w is a terminal, unless the
+// NO_COLOR or FORCE_COLOR environment variables or ConsoleColorMode say otherwise.
+func NewConsoleWriter(w io.Writer, opts ...ConsoleOption) io.Writer {
+	return styles.NewConsoleWriter(w, opts...)
+}
+
+// ConsoleColorMode sets when the console writer paints the output.
+func ConsoleColorMode(mode ColorMode) ConsoleOption {
+	return styles.WithColorMode(mode)
+}
+
+// ConsoleTheme overrides the colors of the given levels.
+func ConsoleTheme(theme Theme) ConsoleOption {
+	return styles.WithTheme(theme)
+}
+
+// ConsoleLayout sets which parts compose a line and in which order.
+func ConsoleLayout(elements ...LayoutElement) ConsoleOption {
+	return styles.WithLayout(elements...)
+}
+
+// ConsoleRelativeTime shows the time elapsed since the writer creation
+// instead of the wall clock time.
+// The entries carry their time to the second, so it is only accurate to the second.
+func ConsoleRelativeTime(enabled bool) ConsoleOption {
+	return styles.WithRelativeTime(enabled)
+}
@@ -3,7 +3,9 @@ package ionlog
 import (
 	"os"
 
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
 	"github.com/IonicHealthUsa/ionlog/internal/core/rotationengine"
+	"github.com/IonicHealthUsa/ionlog/internal/core/runtimeinfo"
 	"github.com/IonicHealthUsa/ionlog/internal/service"
 	"github.com/IonicHealthUsa/ionlog/internal/styles"
 )
@@ -21,9 +23,45 @@ const (
 	Gibibyte        uint = 1024 * Mebibyte
 )
 
+type Level = logengine.Level
+
+const (
+	TraceLevel = logengine.Trace
+	DebugLevel = logengine.Debug
+	InfoLevel  = logengine.Info
+	WarnLevel  = logengine.Warn
+	ErrorLevel = logengine.Error
+	PanicLevel = logengine.Panic
+	FatalLevel = logengine.Fatal
+)
+
+// CallerFormat selects how the caller of the entries is written.
+type CallerFormat = runtimeinfo.Format
+
+const (
+	// CallerFullPackage writes the import path of the package, e.g. "example.com/app/internal/db".
+	CallerFullPackage = runtimeinfo.FullPackage
+	// CallerModuleFile writes the file relative to the root of its module, e.g. "internal/db/db.go".
+	CallerModuleFile = runtimeinfo.ModuleFile
+	// CallerCleanFunction writes methods as "Type.Method", without type parameters,
+	// and the closures as "Func.closure".
+	CallerCleanFunction = runtimeinfo.CleanFunction
+)
+
 const DefaultLogFolder = "logs"
 
-var logger = service.NewCoreService()
+// defaultEarlyBufferSize is the number of logs made before the first start that are kept.
+const defaultEarlyBufferSize = 10000
+
+var logger = initialLogger()
+
+// initialLogger keeps the logs made before the first start. The loggers made by Reset and
+// Shutdown do not, their logs would wait for a start that may never come.
+func initialLogger() service.ICoreService {
+	cs := service.NewCoreService()
+	cs.LogEngine().SetEarlyBuffer(defaultEarlyBufferSize)
+	return cs
+}
 
 var DefaultOutput = os.Stdout
 
@@ -0,0 +1,201 @@
+package ionlog
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+	"github.com/IonicHealthUsa/ionlog/internal/core/runtimeinfo"
+)
+
+// Field is a typed field of a single entry, its value is encoded by the report goroutine.
+type Field = logengine.Field
+
+// String is a field with a string value.
+func String(key string, value string) Field {
+	return Field{Key: key, Type: logengine.StringField, String: value}
+}
+
+// Int is a field with an integer value.
+func Int(key string, value int) Field {
+	return Field{Key: key, Type: logengine.IntField, Integer: int64(value)}
+}
+
+// Int64 is a field with an integer value.
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Type: logengine.IntField, Integer: value}
+}
+
+// Uint64 is a field with an unsigned integer value.
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, Type: logengine.UintField, Integer: int64(value)}
+}
+
+// Float64 is a field with a floating point value.
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Type: logengine.FloatField, Integer: int64(math.Float64bits(value))}
+}
+
+// Bool is a field with a boolean value.
+func Bool(key string, value bool) Field {
+	f := Field{Key: key, Type: logengine.BoolField}
+	if value {
+		f.Integer = 1
+	}
+	return f
+}
+
+// Duration is a field with a duration, written as "1.5s".
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Type: logengine.DurationField, Integer: int64(value)}
+}
+
+// Time is a field with a time, written as RFC 3339 with nanoseconds.
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Type: logengine.TimeField, Integer: value.UnixNano(), Interface: value.Location()}
+}
+
+// Err is an "error" field with the message of err.
+func Err(err error) Field {
+	return Field{Key: "error", Type: logengine.ErrorField, Interface: err}
+}
+
+// LogValuer is a value evaluated by the report goroutine, only when the entry that holds it
+// is written. It can be a field, with Lazy, or an argument of the functions that format.
+type LogValuer = logengine.LogValuer
+
+// Lazy is a field whose value is returned by v when the entry is written.
+func Lazy(key string, v LogValuer) Field {
+	return Field{Key: key, Type: logengine.LazyField, Interface: v}
+}
+
+// Stringer is a field whose value is the String of v, called when the entry is written.
+func Stringer(key string, v fmt.Stringer) Field {
+	return Field{Key: key, Type: logengine.LazyField, Interface: v}
+}
+
+// Any is a field with any value, written as its JSON encoding.
+func Any(key string, value any) Field {
+	return Field{Key: key, Type: logengine.AnyField, Interface: value}
+}
+
+// Stack is a field with the stack trace of the goroutine that calls it,
+// written as the "stack" array of frames.
+func Stack() Field {
+	return logengine.StackField(runtimeinfo.TrimInternal(runtimeinfo.Stack(2)))
+}
+
+// EntryLogger logs entries with the fields it was given.
+type EntryLogger struct {
+	fields  []Field
+	sampler Sampler
+	skip    int
+}
+
+// With returns a logger whose entries have the given fields.
+// usage: ionlog.With(ionlog.Stack()).Errorf("request failed: %v", err)
+func With(fields ...Field) EntryLogger {
+	return EntryLogger{fields: fields}
+}
+
+// With returns a logger with the fields of e and the given fields.
+func (e EntryLogger) With(fields ...Field) EntryLogger {
+	e.fields = append(append([]Field{}, e.fields...), fields...)
+	return e
+}
+
+// Sampled returns a logger whose entries are sampled by s, per call site,
+// in place of the sampler of the level.
+// usage: ionlog.Sampled(ionlog.Burst(10, time.Second)).Errorf("request failed: %v", err)
+func Sampled(s Sampler) EntryLogger {
+	return EntryLogger{sampler: s}
+}
+
+// WithCallerSkip returns a logger that reports as the caller the function
+// skip frames above the one that logs, for the functions that wrap ionlog.
+// usage: var dbLog = ionlog.WithCallerSkip(1)
+func WithCallerSkip(skip int) EntryLogger {
+	return EntryLogger{skip: skip}
+}
+
+// WithCallerSkip returns a logger with the fields of e that skips skip more frames.
+func (e EntryLogger) WithCallerSkip(skip int) EntryLogger {
+	e.skip += skip
+	return e
+}
+
+// Helper marks the calling function as a logging helper, so the entries it logs
+// report the function that called it, as testing.T.Helper does.
+func Helper() {
+	runtimeinfo.MarkHelper(2)
+}
+
+// Info logs a message with level info.
+func (e EntryLogger) Info(msg string, fields ...Field) {
+	e.report(logengine.Info, msg, fields)
+}
+
+// Infof logs a message with level info.
+// Arguments are handled in the manner of fmt.Printf, by the report goroutine.
+func (e EntryLogger) Infof(msg string, args ...any) {
+	e.reportf(logengine.Info, msg, args)
+}
+
+// Warn logs a message with level warn.
+func (e EntryLogger) Warn(msg string, fields ...Field) {
+	e.report(logengine.Warn, msg, fields)
+}
+
+// Warnf logs a message with level warn.
+// Arguments are handled in the manner of fmt.Printf, by the report goroutine.
+func (e EntryLogger) Warnf(msg string, args ...any) {
+	e.reportf(logengine.Warn, msg, args)
+}
+
+// Error logs a message with level error.
+func (e EntryLogger) Error(msg string, fields ...Field) {
+	e.report(logengine.Error, msg, fields)
+}
+
+// Errorf logs a message with level error.
+// Arguments are handled in the manner of fmt.Printf, by the report goroutine.
+func (e EntryLogger) Errorf(msg string, args ...any) {
+	e.reportf(logengine.Error, msg, args)
+}
+
+// Debug logs a message with level debug.
+func (e EntryLogger) Debug(msg string, fields ...Field) {
+	e.report(logengine.Debug, msg, fields)
+}
+
+// Debugf logs a message with level debug.
+// Arguments are handled in the manner of fmt.Printf, by the report goroutine.
+func (e EntryLogger) Debugf(msg string, args ...any) {
+	e.reportf(logengine.Debug, msg, args)
+}
+
+func (e EntryLogger) report(level logengine.Level, msg string, fields []Field) {
+	r := logengine.AcquireReport()
+	r.At = time.Now()
+	r.Level = level
+	r.Msg = msg
+	r.PC = callerPC(3 + e.skip)
+	r.Sampler = e.sampler
+	r.Fields = append(append(r.Fields, e.fields...), fields...)
+
+	logger.LogEngine().Submit(r)
+}
+
+func (e EntryLogger) reportf(level logengine.Level, format string, args []any) {
+	r := logengine.AcquireReport()
+	r.At = time.Now()
+	r.Level = level
+	r.Format = format
+	r.PC = callerPC(3 + e.skip)
+	r.Sampler = e.sampler
+	r.Fields = append(r.Fields, e.fields...)
+	r.Args = append(r.Args, args...)
+
+	logger.LogEngine().Submit(r)
+}
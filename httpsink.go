@@ -0,0 +1,59 @@
+package ionlog
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/infrastructure/httpsink"
+)
+
+type HTTPAdapter = httpsink.IAdapter
+
+// LokiAdapter posts to the Loki push API, the given fields become stream labels ("level" by default).
+func LokiAdapter(labels ...string) HTTPAdapter {
+	return httpsink.NewLokiAdapter(labels...)
+}
+
+// ElasticsearchAdapter posts to the Elasticsearch bulk API, indexing the entries in index.
+func ElasticsearchAdapter(index string) HTTPAdapter {
+	return httpsink.NewElasticsearchAdapter(index)
+}
+
+// NDJSONAdapter posts the entries as newline delimited JSON.
+func NDJSONAdapter() HTTPAdapter {
+	return httpsink.NewNDJSONAdapter()
+}
+
+type HTTPOption = httpsink.Option
+
+// NewHTTPWriter ships the entries in batches to endpoint, encoded by adapter.
+// The pending batch is delivered when the logger stops.
+func NewHTTPWriter(endpoint string, adapter HTTPAdapter, opts ...HTTPOption) (io.WriteCloser, error) {
+	return httpsink.NewHTTPWriter(endpoint, adapter, opts...)
+}
+
+// HTTPGzip compresses the body of the requests.
+func HTTPGzip(enabled bool) HTTPOption {
+	return httpsink.WithGzip(enabled)
+}
+
+// HTTPHeaders adds headers to every request.
+func HTTPHeaders(headers map[string]string) HTTPOption {
+	return httpsink.WithHeaders(headers)
+}
+
+// HTTPClient sets the client used to send the requests.
+func HTTPClient(client *http.Client) HTTPOption {
+	return httpsink.WithHTTPClient(client)
+}
+
+// HTTPTimeout bounds every request.
+func HTTPTimeout(timeout time.Duration) HTTPOption {
+	return httpsink.WithTimeout(timeout)
+}
+
+// HTTPBatching sets how the entries are grouped in requests.
+func HTTPBatching(opts ...BatchOption) HTTPOption {
+	return httpsink.WithBatching(opts...)
+}
@@ -0,0 +1,419 @@
+// Package batchwriter groups the entries in batches delivered by a sender on their own goroutine,
+// it is the engine of the network sinks that send many entries per request.
+package batchwriter
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxEntries   = 512
+	defaultMaxBytes     = 1024 * 1024 // 1 MB
+	defaultInterval     = 1 * time.Second
+	defaultMaxPending   = 16 * 1024 * 1024 // 16 MB
+	defaultRetries      = 3
+	defaultBackoff      = 200 * time.Millisecond
+	defaultFlushTimeout = 10 * time.Second
+)
+
+// permanentError marks a failure that retrying does not fix, e.g. a rejected request.
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p *permanentError) Unwrap() error {
+	return p.err
+}
+
+// Permanent wraps err so the batch is dropped without retries.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Sender delivers a batch of entries, in the order they were written.
+// The entries must not be retained after it returns.
+type Sender func(entries [][]byte) error
+
+type batch struct {
+	entries [][]byte
+	bytes   int
+}
+
+// batchWriter seals a batch when it reaches the count or size limit or when the interval
+// expires. The sealed batches wait in a queue, bounded in bytes, for the sender.
+type batchWriter struct {
+	send Sender
+
+	maxEntries   int
+	maxBytes     int
+	interval     time.Duration
+	maxPending   int
+	retries      int
+	backoff      time.Duration
+	flushTimeout time.Duration
+
+	lock      sync.Mutex
+	current   batch
+	queue     []batch
+	pending   int // bytes of the current batch and of the queue
+	inFlight  bool
+	dropped   uint64
+	failures  uint64
+	lastError error
+	closed    bool
+	reporter  func(n int, err error)
+
+	// the flushes wait on changed, it is closed and replaced when a batch leaves the queue
+	flushers int
+	changed  chan struct{}
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+type IBatchWriter interface {
+	io.WriteCloser
+	Flush() error
+	Dropped() uint64
+	SetReporter(report func(n int, err error))
+}
+
+type Option func(b *batchWriter)
+
+// NewBatchWriter creates a writer that delivers the entries in batches through send.
+func NewBatchWriter(send Sender, opts ...Option) IBatchWriter {
+	b := &batchWriter{
+		send:         send,
+		maxEntries:   defaultMaxEntries,
+		maxBytes:     defaultMaxBytes,
+		interval:     defaultInterval,
+		maxPending:   defaultMaxPending,
+		retries:      defaultRetries,
+		backoff:      defaultBackoff,
+		flushTimeout: defaultFlushTimeout,
+		changed:      make(chan struct{}),
+		wake:         make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.interval <= 0 {
+		b.interval = defaultInterval // the ticker panics otherwise
+	}
+
+	go b.run()
+
+	return b
+}
+
+// WithBatchSize seals a batch when it holds maxEntries entries or maxBytes bytes.
+func WithBatchSize(maxEntries int, maxBytes int) Option {
+	return func(b *batchWriter) {
+		b.maxEntries = maxEntries
+		b.maxBytes = maxBytes
+	}
+}
+
+// WithInterval seals the batch being filled after interval, even if it is not full.
+// An interval that is not positive keeps the default.
+func WithInterval(interval time.Duration) Option {
+	return func(b *batchWriter) {
+		if interval <= 0 {
+			interval = defaultInterval
+		}
+		b.interval = interval
+	}
+}
+
+// WithMaxPending bounds the bytes held in memory, the oldest batches are dropped first.
+func WithMaxPending(maxBytes int) Option {
+	return func(b *batchWriter) {
+		b.maxPending = maxBytes
+	}
+}
+
+// WithRetries sets how many times a failed batch is sent again,
+// waiting backoff before the first retry and doubling it on each one.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(b *batchWriter) {
+		b.retries = retries
+		b.backoff = backoff
+	}
+}
+
+// WithFlushTimeout bounds how long Flush and Close wait for the batches to be delivered.
+func WithFlushTimeout(timeout time.Duration) Option {
+	return func(b *batchWriter) {
+		b.flushTimeout = timeout
+	}
+}
+
+// Write adds a copy of p to the current batch.
+func (b *batchWriter) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return 0, ErrWriterClosed
+	}
+
+	b.current.entries = append(b.current.entries, slices.Clone(p))
+	b.current.bytes += len(p)
+	b.pending += len(p)
+
+	if len(b.current.entries) >= b.maxEntries || b.current.bytes >= b.maxBytes {
+		b.seal()
+	}
+	b.enforceLimit()
+
+	return len(p), nil
+}
+
+// seal moves the current batch to the queue and wakes the sender.
+func (b *batchWriter) seal() {
+	if len(b.current.entries) == 0 {
+		return
+	}
+
+	b.queue = append(b.queue, b.current)
+	b.current = batch{}
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enforceLimit drops the oldest batches while the memory limit is exceeded,
+// the batch in flight is never dropped.
+func (b *batchWriter) enforceLimit() {
+	for b.pending > b.maxPending {
+		first := 0
+		if b.inFlight {
+			first = 1
+		}
+
+		if len(b.queue) > first {
+			b.pending -= b.queue[first].bytes
+			b.dropped += uint64(len(b.queue[first].entries))
+			b.queue = slices.Delete(b.queue, first, first+1)
+			b.notify()
+			continue
+		}
+
+		if len(b.current.entries) <= 1 {
+			return
+		}
+		b.pending -= len(b.current.entries[0])
+		b.current.bytes -= len(b.current.entries[0])
+		b.current.entries = slices.Delete(b.current.entries, 0, 1)
+		b.dropped++
+	}
+}
+
+// notify wakes the flushes waiting, it must be called with the lock held.
+func (b *batchWriter) notify() {
+	if b.flushers == 0 {
+		return
+	}
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (b *batchWriter) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.wake:
+		case <-ticker.C:
+			b.lock.Lock()
+			b.seal()
+			b.lock.Unlock()
+		case <-b.stop:
+			return
+		}
+
+		for {
+			next, ok := b.next()
+			if !ok {
+				break
+			}
+			if !b.deliver(next) {
+				return
+			}
+		}
+	}
+}
+
+// next returns the oldest sealed batch and marks it as in flight.
+func (b *batchWriter) next() (batch, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if len(b.queue) == 0 {
+		return batch{}, false
+	}
+	b.inFlight = true
+	return b.queue[0], true
+}
+
+// deliver sends the batch with retries, it returns false when the writer stops meanwhile.
+func (b *batchWriter) deliver(next batch) bool {
+	backoff := b.backoff
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = b.send(next.entries)
+		if _, permanent := err.(*permanentError); err == nil || permanent || attempt >= b.retries {
+			break
+		}
+		if !b.sleep(backoff) {
+			b.finish(next, fmt.Errorf("%w: %d entries lost: %w", ErrUndelivered, len(next.entries), err))
+			return false
+		}
+		backoff *= 2
+	}
+
+	if err != nil {
+		err = fmt.Errorf("%w: %d entries lost: %w", ErrUndelivered, len(next.entries), err)
+	}
+	b.finish(next, err)
+	return true
+}
+
+// finish removes the batch in flight and reports the result of its delivery.
+func (b *batchWriter) finish(next batch, err error) {
+	b.lock.Lock()
+	b.queue = slices.Delete(b.queue, 0, 1)
+	b.pending -= next.bytes
+	b.inFlight = false
+	if err != nil {
+		b.failures++
+		b.lastError = err
+		b.dropped += uint64(len(next.entries))
+	}
+	b.notify()
+	report := b.reporter
+	b.lock.Unlock()
+
+	if report == nil {
+		return
+	}
+	if err != nil {
+		report(0, err)
+		return
+	}
+	for _, e := range next.entries {
+		report(len(e), nil)
+	}
+}
+
+// sleep waits for d, it returns false when the writer stops meanwhile.
+func (b *batchWriter) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-b.stop:
+		return false
+	}
+}
+
+// Flush seals the current batch and waits until every batch is delivered or dropped.
+// It returns the error of the batches that failed meanwhile.
+func (b *batchWriter) Flush() error {
+	timer := time.NewTimer(b.flushTimeout)
+	defer timer.Stop()
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.seal()
+	failures := b.failures
+
+	b.flushers++
+	defer func() { b.flushers-- }()
+
+	for len(b.queue) > 0 {
+		changed := b.changed
+		b.lock.Unlock()
+		select {
+		case <-changed:
+		case <-timer.C:
+			b.lock.Lock()
+			return ErrFlushTimeout
+		}
+		b.lock.Lock()
+	}
+
+	if b.failures > failures {
+		return b.lastError
+	}
+	return nil
+}
+
+// Close delivers the pending batches and stops the writer.
+func (b *batchWriter) Close() error {
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.lock.Unlock()
+
+	flushErr := b.Flush()
+
+	close(b.stop)
+	select {
+	case <-b.done:
+	case <-time.After(b.flushTimeout):
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	lost := len(b.current.entries)
+	for _, q := range b.queue {
+		lost += len(q.entries)
+	}
+	if lost > 0 {
+		return fmt.Errorf("%w: %d entries lost", ErrUndelivered, lost)
+	}
+	return flushErr
+}
+
+// SetReporter sets the function called with the result of every delivery.
+func (b *batchWriter) SetReporter(report func(n int, err error)) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.reporter = report
+}
+
+// Dropped returns how many entries were discarded, by the memory limit or after failed retries.
+func (b *batchWriter) Dropped() uint64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.dropped
+}
@@ -0,0 +1,271 @@
+package batchwriter
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSender records the batches and fails while err is set.
+type fakeSender struct {
+	lock    sync.Mutex
+	batches [][]string
+	calls   int
+	err     error
+}
+
+func (f *fakeSender) send(entries [][]byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.calls++
+	if f.err != nil {
+		return f.err
+	}
+
+	batch := make([]string, len(entries))
+	for i, e := range entries {
+		batch[i] = string(e)
+	}
+	f.batches = append(f.batches, batch)
+	return nil
+}
+
+func (f *fakeSender) setErr(err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.err = err
+}
+
+func (f *fakeSender) snapshot() ([][]string, int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([][]string(nil), f.batches...), f.calls
+}
+
+func TestBatchWriter(t *testing.T) {
+	t.Run("should seal a batch when it is full", func(t *testing.T) {
+		s := &fakeSender{}
+		w := NewBatchWriter(s.send, WithBatchSize(2, 1024), WithInterval(time.Hour))
+		defer w.Close()
+
+		for _, e := range []string{"a", "b", "c"} {
+			w.Write([]byte(e))
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for {
+			batches, _ := s.snapshot()
+			if len(batches) == 1 {
+				if strings.Join(batches[0], ",") != "a,b" {
+					t.Errorf("expected the batch to be a,b, but got %v", batches[0])
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("expected a batch, but timeout")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	t.Run("should seal a batch when it reaches the size limit", func(t *testing.T) {
+		s := &fakeSender{}
+		w := NewBatchWriter(s.send, WithBatchSize(100, 4), WithInterval(time.Hour))
+		defer w.Close()
+
+		w.Write([]byte("abcd"))
+		w.Write([]byte("e"))
+		w.Flush()
+
+		batches, _ := s.snapshot()
+		if len(batches) != 2 || batches[0][0] != "abcd" || batches[1][0] != "e" {
+			t.Errorf("expected two batches, but got %v", batches)
+		}
+	})
+
+	t.Run("should seal a batch when the interval expires", func(t *testing.T) {
+		s := &fakeSender{}
+		w := NewBatchWriter(s.send, WithInterval(10*time.Millisecond))
+		defer w.Close()
+
+		w.Write([]byte("a"))
+
+		deadline := time.Now().Add(time.Second)
+		for {
+			if batches, _ := s.snapshot(); len(batches) == 1 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("expected a batch, but timeout")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	t.Run("should keep the default interval when it is not positive", func(t *testing.T) {
+		s := &fakeSender{}
+		w := NewBatchWriter(s.send, WithInterval(0))
+		defer w.Close()
+
+		if interval := w.(*batchWriter).interval; interval != defaultInterval {
+			t.Errorf("expected the interval to be %v, but got %v", defaultInterval, interval)
+		}
+	})
+
+	t.Run("should deliver everything on flush in order", func(t *testing.T) {
+		s := &fakeSender{}
+		w := NewBatchWriter(s.send, WithBatchSize(3, 1024), WithInterval(time.Hour))
+		defer w.Close()
+
+		for i := range 10 {
+			w.Write([]byte{byte('0' + i)})
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		batches, _ := s.snapshot()
+		var got []string
+		for _, b := range batches {
+			got = append(got, b...)
+		}
+		if strings.Join(got, "") != "0123456789" {
+			t.Errorf("expected the entries in order, but got %v", got)
+		}
+	})
+
+	t.Run("should retry a failed batch", func(t *testing.T) {
+		s := &fakeSender{err: errors.New("down")}
+		w := NewBatchWriter(s.send, WithRetries(5, time.Millisecond), WithInterval(time.Hour))
+		defer w.Close()
+
+		w.Write([]byte("a"))
+		go func() {
+			time.Sleep(5 * time.Millisecond)
+			s.setErr(nil)
+		}()
+
+		if err := w.Flush(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		batches, calls := s.snapshot()
+		if len(batches) != 1 || calls < 2 {
+			t.Errorf("expected the batch to be retried, but got %d batches in %d calls", len(batches), calls)
+		}
+	})
+
+	t.Run("should drop the batch after the retries", func(t *testing.T) {
+		s := &fakeSender{err: errors.New("down")}
+		w := NewBatchWriter(s.send, WithRetries(2, time.Millisecond), WithInterval(time.Hour))
+		defer w.Close()
+
+		var reported error
+		var lock sync.Mutex
+		w.SetReporter(func(n int, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			reported = err
+		})
+
+		w.Write([]byte("a"))
+		w.Write([]byte("b"))
+
+		if err := w.Flush(); !errors.Is(err, ErrUndelivered) {
+			t.Errorf("expected error to be %v, but got %v", ErrUndelivered, err)
+		}
+		if _, calls := s.snapshot(); calls != 3 {
+			t.Errorf("expected 3 attempts, but got %d", calls)
+		}
+		if w.Dropped() != 2 {
+			t.Errorf("expected 2 dropped entries, but got %d", w.Dropped())
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		if !errors.Is(reported, ErrUndelivered) {
+			t.Errorf("expected the failure to be reported, but got %v", reported)
+		}
+	})
+
+	t.Run("should bound the memory dropping the oldest entries", func(t *testing.T) {
+		block := make(chan struct{})
+		started := make(chan struct{}, 1)
+		var lock sync.Mutex
+		var got []string
+		send := func(entries [][]byte) error {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-block
+			lock.Lock()
+			defer lock.Unlock()
+			for _, e := range entries {
+				got = append(got, string(e))
+			}
+			return nil
+		}
+
+		w := NewBatchWriter(send, WithBatchSize(1, 1024), WithMaxPending(3), WithInterval(time.Hour))
+		defer w.Close()
+
+		w.Write([]byte("a"))
+		<-started
+		for _, e := range []string{"b", "c", "d", "e"} {
+			w.Write([]byte(e))
+		}
+		close(block)
+		w.Flush()
+
+		lock.Lock()
+		defer lock.Unlock()
+		if len(got) != 3 || got[0] != "a" || got[2] != "e" {
+			t.Errorf("expected the in flight entry and the newest ones, but got %v", got)
+		}
+		if w.Dropped() != 2 {
+			t.Errorf("expected 2 dropped entries, but got %d", w.Dropped())
+		}
+	})
+
+	t.Run("should deliver on close and refuse later writes", func(t *testing.T) {
+		s := &fakeSender{}
+		w := NewBatchWriter(s.send, WithInterval(time.Hour))
+
+		w.Write([]byte("a"))
+		if err := w.Close(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if batches, _ := s.snapshot(); len(batches) != 1 {
+			t.Errorf("expected the batch to be delivered, but got %v", batches)
+		}
+		if _, err := w.Write([]byte("b")); !errors.Is(err, ErrWriterClosed) {
+			t.Errorf("expected error to be %v, but got %v", ErrWriterClosed, err)
+		}
+	})
+}
+
+func TestPermanent(t *testing.T) {
+	t.Run("should not retry permanent failures", func(t *testing.T) {
+		rejected := errors.New("rejected")
+		s := &fakeSender{err: Permanent(rejected)}
+		w := NewBatchWriter(s.send, WithRetries(5, time.Millisecond), WithInterval(time.Hour))
+		defer w.Close()
+
+		w.Write([]byte("a"))
+		if err := w.Flush(); !errors.Is(err, rejected) {
+			t.Errorf("expected error to be %v, but got %v", rejected, err)
+		}
+		if _, calls := s.snapshot(); calls != 1 {
+			t.Errorf("expected a single attempt, but got %d", calls)
+		}
+	})
+
+	t.Run("should keep nil errors", func(t *testing.T) {
+		if Permanent(nil) != nil {
+			t.Error("expected nil")
+		}
+	})
+}
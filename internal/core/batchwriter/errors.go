@@ -0,0 +1,9 @@
+package batchwriter
+
+import "errors"
+
+var (
+	ErrWriterClosed = errors.New("batch writer is closed")
+	ErrUndelivered  = errors.New("batches were not delivered")
+	ErrFlushTimeout = errors.New("flush timeout")
+)
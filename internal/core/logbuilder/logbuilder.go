@@ -3,11 +3,14 @@ package logbuilder
 import (
 	"fmt"
 	"os"
+	"unicode/utf8"
 )
 
 const bufsize = 1024
 const maxBufsize = bufsize * 512 // 1/2 MB
 
+const hex = "0123456789abcdef"
+
 type logBuilder struct {
 	buf []byte
 	p   uint
@@ -15,6 +18,10 @@ type logBuilder struct {
 
 type ILogBuilder interface {
 	AddFields(args ...string)
+	AddString(key string, value string)
+	AddRawFields(args ...string)
+	AddField(key string, value []byte)
+	AddRawField(key string, value []byte)
 	Compile() []byte
 }
 
@@ -26,24 +33,102 @@ func NewLogBuilder() ILogBuilder {
 	return lb
 }
 
-func (l *logBuilder) writeByte(b byte) {
-	if len(l.buf) >= maxBufsize {
+// grow makes room for n more bytes, it reports false when the buffer would exceed its limit.
+func (l *logBuilder) grow(n int) bool {
+	need := int(l.p) + n
+	if need <= len(l.buf) {
+		return true
+	}
+	if need > maxBufsize {
 		fmt.Fprintf(os.Stderr, "logBuilder buffer is full, cannot handle more strings for this log entry.\n")
-		return
+		return false
 	}
-	if l.p == uint(len(l.buf)) {
-		newBuf := make([]byte, len(l.buf)+bufsize)
-		copy(newBuf, l.buf)
-		l.buf = newBuf
+
+	size := len(l.buf)
+	for size < need {
+		size += bufsize
+	}
+	newBuf := make([]byte, min(size, maxBufsize))
+	copy(newBuf, l.buf[:l.p])
+	l.buf = newBuf
+	return true
+}
+
+func (l *logBuilder) writeByte(b byte) {
+	if !l.grow(1) {
+		return
 	}
 	l.buf[l.p] = b
 	l.p++
 }
 
 func (l *logBuilder) writeString(str string) {
-	for _, s := range []byte(str) {
-		l.writeByte(s)
+	if !l.grow(len(str)) {
+		return
+	}
+	l.p += uint(copy(l.buf[l.p:], str))
+}
+
+func (l *logBuilder) writeBytes(b []byte) {
+	if !l.grow(len(b)) {
+		return
+	}
+	l.p += uint(copy(l.buf[l.p:], b))
+}
+
+// writeEscaped writes s as the content of a JSON string, escaping the quotes,
+// the backslashes, the control characters and the invalid UTF-8.
+func writeEscaped(l *logBuilder, s string) {
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= 0x20 && c != '"' && c != '\\' && c < utf8.RuneSelf {
+			i++
+			continue
+		}
+
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r != utf8.RuneError || size != 1 {
+				i += size
+				continue
+			}
+		}
+
+		l.writeString(s[start:i])
+		switch c {
+		case '"', '\\':
+			l.writeByte('\\')
+			l.writeByte(c)
+		case '\n':
+			l.writeString(`\n`)
+		case '\r':
+			l.writeString(`\r`)
+		case '\t':
+			l.writeString(`\t`)
+		default:
+			if c >= utf8.RuneSelf {
+				l.writeString(`�`)
+			} else {
+				l.writeString(`\u00`)
+				l.writeByte(hex[c>>4])
+				l.writeByte(hex[c&0xf])
+			}
+		}
+		i++
+		start = i
+	}
+	l.writeString(s[start:])
+}
+
+// needsEscape reports whether b has a byte that writeEscaped would change.
+func needsEscape(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 || c == '"' || c == '\\' || c >= utf8.RuneSelf {
+			return true
+		}
 	}
+	return false
 }
 
 func (l *logBuilder) resetBuff() {
@@ -51,26 +136,64 @@ func (l *logBuilder) resetBuff() {
 	l.writeByte('{')
 }
 
+// writeKey writes the separator and the quoted key
+func (l *logBuilder) writeKey(key string) {
+	if l.p > 1 {
+		l.writeByte(',')
+	}
+	l.writeByte('"')
+	writeEscaped(l, key)
+	l.writeByte('"')
+	l.writeByte(':')
+}
+
 // AddFields adds a single field
 func (l *logBuilder) AddFields(args ...string) {
 	if len(args)%2 != 0 {
 		return
 	}
 	for i := 0; i < len(args); i += 2 {
-		if l.p > 1 {
-			l.writeByte(',')
-		}
-		l.writeByte('"')
-		l.writeString(args[i])
-		l.writeByte('"')
-		l.writeByte(':')
+		l.AddString(args[i], args[i+1])
+	}
+}
 
-		l.writeByte('"')
+// AddString adds a single string field, through an interface the slice of AddFields is allocated
+func (l *logBuilder) AddString(key string, value string) {
+	l.writeKey(key)
+	l.writeByte('"')
+	writeEscaped(l, value)
+	l.writeByte('"')
+}
+
+// AddRawFields adds fields whose values are JSON text, written without quotes
+func (l *logBuilder) AddRawFields(args ...string) {
+	if len(args)%2 != 0 {
+		return
+	}
+	for i := 0; i < len(args); i += 2 {
+		l.writeKey(args[i])
 		l.writeString(args[i+1])
-		l.writeByte('"')
 	}
 }
 
+// AddField adds a string field whose value is in bytes, so it can be appended to a reused buffer
+func (l *logBuilder) AddField(key string, value []byte) {
+	l.writeKey(key)
+	l.writeByte('"')
+	if needsEscape(value) {
+		writeEscaped(l, string(value))
+	} else {
+		l.writeBytes(value)
+	}
+	l.writeByte('"')
+}
+
+// AddRawField adds a field whose value is JSON text
+func (l *logBuilder) AddRawField(key string, value []byte) {
+	l.writeKey(key)
+	l.writeBytes(value)
+}
+
 func (l *logBuilder) Compile() []byte {
 	defer l.resetBuff()
 	l.writeString("}\n")
@@ -298,3 +298,77 @@ func TestWriteByte(t *testing.T) {
 		}
 	})
 }
+
+func TestAddRawFields(t *testing.T) {
+	l := NewLogBuilder()
+	l.AddFields("msg", "hello")
+	l.AddRawFields("stack", `[{"line":1}]`, "odd")
+	l.AddRawFields("stack", `[{"line":1}]`)
+
+	expected := `{"msg":"hello","stack":[{"line":1}]}` + "\n"
+	if got := string(l.Compile()); got != expected {
+		t.Errorf("expected %q, but got %q", expected, got)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"quotes and backslashes", `say "hi" \o/`, `say \"hi\" \\o/`},
+		{"control characters", "a\nb\tc\rd\x01", `a\nb\tc\rd\u0001`},
+		{"unicode", "ação 😀", "ação 😀"},
+		{"invalid utf-8", "a\xffb", "a\ufffdb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLogBuilder()
+			lb.AddFields("msg", tt.value)
+			lb.AddField("bytes", []byte(tt.value))
+
+			want := `{"msg":"` + tt.want + `","bytes":"` + tt.want + `"}` + "\n"
+			if got := string(lb.Compile()); got != want {
+				t.Errorf("expected %q, but got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestAddRawField(t *testing.T) {
+	lb := NewLogBuilder()
+	lb.AddRawField("n", strconv.AppendInt(nil, 42, 10))
+	lb.AddRawField("ok", []byte("true"))
+
+	expected := `{"n":42,"ok":true}` + "\n"
+	if got := string(lb.Compile()); got != expected {
+		t.Errorf("expected %q, but got %q", expected, got)
+	}
+}
+
+func TestAddString(t *testing.T) {
+	lb := NewLogBuilder()
+	lb.AddString("msg", `say "hi"`)
+
+	expected := `{"msg":"say \"hi\""}` + "\n"
+	if got := string(lb.Compile()); got != expected {
+		t.Errorf("expected %q, but got %q", expected, got)
+	}
+}
+
+func TestBuilderAllocs(t *testing.T) {
+	lb := NewLogBuilder()
+	line := []byte("42")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		lb.AddFields("level", "INFO", "msg", fakeMessage)
+		lb.AddString("file", "main.go")
+		lb.AddField("line", line)
+		lb.AddRawField("n", line)
+		lb.Compile()
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
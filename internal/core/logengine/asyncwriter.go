@@ -0,0 +1,304 @@
+package logengine
+
+import (
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DropPolicy decides what happens to an entry when the queue of an async writer is full.
+type DropPolicy int
+
+const (
+	// DropNewest discards the entry being written.
+	DropNewest DropPolicy = iota
+	// DropOldest discards the oldest queued entry to make room for the new one.
+	DropOldest
+	// BlockWithTimeout waits for room up to the enqueue timeout, then discards the entry.
+	BlockWithTimeout
+)
+
+// WriterHealth is the state of a writer as seen by the logger.
+type WriterHealth int
+
+const (
+	Healthy WriterHealth = iota
+	// Degraded means the last write failed or entries were dropped since the last successful write.
+	Degraded
+	// Stalled means a write has been in progress for longer than the write timeout.
+	Stalled
+)
+
+func (h WriterHealth) String() string {
+	switch h {
+	case Healthy:
+		return "HEALTHY"
+	case Degraded:
+		return "DEGRADED"
+	case Stalled:
+		return "STALLED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+const (
+	defaultAsyncQueueCapacity = 1000
+	defaultEnqueueTimeout     = 100 * time.Millisecond
+	defaultWriteTimeout       = 5 * time.Second
+)
+
+// asyncWriter isolates a writer in its own goroutine behind a bounded queue,
+// so a slow or hung target only delays its own entries.
+type asyncWriter struct {
+	target io.Writer
+	queue  chan []byte
+
+	policy         DropPolicy
+	enqueueTimeout time.Duration
+	writeTimeout   time.Duration
+
+	enqueued   atomic.Uint64
+	processed  atomic.Uint64
+	dropped    atomic.Uint64
+	writeStart atomic.Int64 // unix nano of the write in progress, 0 when idle
+	degraded   atomic.Bool
+	reporter   atomic.Pointer[func(n int, err error)]
+
+	// the flushes wait on progress, it is closed and replaced when an entry is processed
+	flushers     atomic.Int32
+	progress     chan struct{}
+	progressLock sync.Mutex
+
+	closed    bool
+	closeLock sync.RWMutex
+	done      chan struct{}
+}
+
+type IAsyncWriter interface {
+	io.WriteCloser
+	Flush() error
+	Health() WriterHealth
+	Dropped() uint64
+	Unwrap() io.Writer
+}
+
+type AsyncOption func(a *asyncWriter)
+
+// NewAsyncWriter wraps target with a bounded queue consumed by a dedicated goroutine.
+func NewAsyncWriter(target io.Writer, opts ...AsyncOption) IAsyncWriter {
+	a := &asyncWriter{
+		target:         target,
+		policy:         DropNewest,
+		enqueueTimeout: defaultEnqueueTimeout,
+		writeTimeout:   defaultWriteTimeout,
+		done:           make(chan struct{}),
+		progress:       make(chan struct{}),
+	}
+	a.queue = make(chan []byte, defaultAsyncQueueCapacity)
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	go a.run()
+
+	return a
+}
+
+// WithQueueCapacity sets how many entries may wait for the target.
+func WithQueueCapacity(capacity uint) AsyncOption {
+	return func(a *asyncWriter) {
+		a.queue = make(chan []byte, capacity)
+	}
+}
+
+// WithDropPolicy sets what to do with entries when the queue is full.
+func WithDropPolicy(policy DropPolicy) AsyncOption {
+	return func(a *asyncWriter) {
+		a.policy = policy
+	}
+}
+
+// WithEnqueueTimeout sets how long BlockWithTimeout waits for room in the queue.
+func WithEnqueueTimeout(timeout time.Duration) AsyncOption {
+	return func(a *asyncWriter) {
+		a.enqueueTimeout = timeout
+	}
+}
+
+// WithWriteTimeout sets how long a single write may take before the writer is
+// considered stalled, it also bounds how long Flush waits without progress.
+func WithWriteTimeout(timeout time.Duration) AsyncOption {
+	return func(a *asyncWriter) {
+		a.writeTimeout = timeout
+	}
+}
+
+// Write queues a copy of p, it never waits for the target.
+func (a *asyncWriter) Write(p []byte) (int, error) {
+	a.closeLock.RLock()
+	defer a.closeLock.RUnlock()
+
+	if a.closed {
+		return 0, ErrWriterClosed
+	}
+
+	entry := slices.Clone(p)
+
+	select {
+	case a.queue <- entry:
+		a.enqueued.Add(1)
+		return len(p), nil
+	default:
+	}
+
+	switch a.policy {
+	case DropOldest:
+		for {
+			select {
+			case <-a.queue:
+				a.drop()
+			default:
+			}
+
+			select {
+			case a.queue <- entry:
+				a.enqueued.Add(1)
+				return len(p), nil
+			default:
+			}
+		}
+
+	case BlockWithTimeout:
+		timer := time.NewTimer(a.enqueueTimeout)
+		defer timer.Stop()
+
+		select {
+		case a.queue <- entry:
+			a.enqueued.Add(1)
+			return len(p), nil
+		case <-timer.C:
+		}
+	}
+
+	a.dropped.Add(1)
+	a.degraded.Store(true)
+	return 0, ErrQueueFull
+}
+
+// drop accounts an entry removed from the queue without being written.
+func (a *asyncWriter) drop() {
+	a.dropped.Add(1)
+	a.degraded.Store(true)
+	a.processedOne()
+}
+
+// processedOne accounts an entry taken from the queue and wakes the flushes waiting.
+func (a *asyncWriter) processedOne() {
+	a.processed.Add(1)
+	if a.flushers.Load() == 0 {
+		return
+	}
+
+	a.progressLock.Lock()
+	close(a.progress)
+	a.progress = make(chan struct{})
+	a.progressLock.Unlock()
+}
+
+// progressed returns the channel closed once the next entry is processed.
+func (a *asyncWriter) progressed() <-chan struct{} {
+	a.progressLock.Lock()
+	defer a.progressLock.Unlock()
+	return a.progress
+}
+
+func (a *asyncWriter) run() {
+	defer close(a.done)
+
+	for p := range a.queue {
+		a.writeStart.Store(time.Now().UnixNano())
+		n, err := a.target.Write(p)
+		a.writeStart.Store(0)
+
+		a.degraded.Store(err != nil)
+
+		if report := a.reporter.Load(); report != nil {
+			(*report)(n, err)
+		}
+		a.processedOne()
+	}
+}
+
+// Flush waits until every entry queued before the call was handed to the target.
+// It gives up with ErrWriterStalled when the target makes no progress within the write timeout.
+func (a *asyncWriter) Flush() error {
+	a.flushers.Add(1)
+	defer a.flushers.Add(-1)
+
+	target := a.enqueued.Load()
+	timer := time.NewTimer(a.writeTimeout)
+	defer timer.Stop()
+
+	for {
+		progressed := a.progressed() // taken before the count, so no progress is missed
+		if a.processed.Load() >= target {
+			return nil
+		}
+
+		select {
+		case <-progressed:
+			timer.Reset(a.writeTimeout)
+		case <-timer.C:
+			return ErrWriterStalled
+		}
+	}
+}
+
+// Close stops accepting entries and waits for the queued ones to be written.
+// The target itself is not closed.
+func (a *asyncWriter) Close() error {
+	a.closeLock.Lock()
+	if a.closed {
+		a.closeLock.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.queue)
+	a.closeLock.Unlock()
+
+	err := a.Flush()
+	if err != nil {
+		return err
+	}
+
+	<-a.done
+	return nil
+}
+
+func (a *asyncWriter) Health() WriterHealth {
+	start := a.writeStart.Load()
+	if start != 0 && time.Since(time.Unix(0, start)) > a.writeTimeout {
+		return Stalled
+	}
+	if a.degraded.Load() {
+		return Degraded
+	}
+	return Healthy
+}
+
+// SetReporter sets the function called with the result of every delivery to the target.
+func (a *asyncWriter) SetReporter(report func(n int, err error)) {
+	a.reporter.Store(&report)
+}
+
+func (a *asyncWriter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+func (a *asyncWriter) Unwrap() io.Writer {
+	return a.target
+}
@@ -0,0 +1,250 @@
+package logengine
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingWriter blocks every write until release is closed
+type blockingWriter struct {
+	release chan struct{}
+	lock    sync.Mutex
+	buf     bytes.Buffer
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{release: make(chan struct{})}
+}
+
+func (b *blockingWriter) Write(p []byte) (int, error) {
+	<-b.release
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *blockingWriter) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+func TestNewAsyncWriter(t *testing.T) {
+	t.Run("should apply the options", func(t *testing.T) {
+		a := NewAsyncWriter(io.Discard,
+			WithQueueCapacity(3),
+			WithDropPolicy(DropOldest),
+			WithEnqueueTimeout(time.Second),
+			WithWriteTimeout(time.Minute),
+		).(*asyncWriter)
+		defer a.Close()
+
+		if cap(a.queue) != 3 {
+			t.Errorf("expected queue capacity to be 3, but got %d", cap(a.queue))
+		}
+		if a.policy != DropOldest {
+			t.Errorf("expected policy to be %v, but got %v", DropOldest, a.policy)
+		}
+		if a.enqueueTimeout != time.Second {
+			t.Errorf("expected enqueue timeout to be %v, but got %v", time.Second, a.enqueueTimeout)
+		}
+		if a.writeTimeout != time.Minute {
+			t.Errorf("expected write timeout to be %v, but got %v", time.Minute, a.writeTimeout)
+		}
+		if a.Unwrap() != io.Discard {
+			t.Error("expected Unwrap to return the target")
+		}
+	})
+}
+
+func TestAsyncWriterWrite(t *testing.T) {
+	t.Run("should write the entries in order", func(t *testing.T) {
+		buf := &mockBufferWriter{}
+		a := NewAsyncWriter(buf)
+
+		for _, s := range []string{"a", "b", "c"} {
+			if _, err := a.Write([]byte(s)); err != nil {
+				t.Errorf("expected no error, but got %v", err)
+			}
+		}
+
+		if err := a.Flush(); err != nil {
+			t.Errorf("expected no error, but got %v", err)
+		}
+		if buf.String() != "abc" {
+			t.Errorf("expected buffer to be %q, but got %q", "abc", buf.String())
+		}
+		a.Close()
+	})
+
+	t.Run("should copy the entry before queueing", func(t *testing.T) {
+		target := newBlockingWriter()
+		a := NewAsyncWriter(target)
+
+		p := []byte("first")
+		a.Write(p)
+		copy(p, "xxxxx")
+
+		close(target.release)
+		a.Flush()
+		if target.String() != "first" {
+			t.Errorf("expected the entry to be %q, but got %q", "first", target.String())
+		}
+		a.Close()
+	})
+
+	t.Run("should drop the newest entries when the queue is full", func(t *testing.T) {
+		target := newBlockingWriter()
+		a := NewAsyncWriter(target, WithQueueCapacity(1), WithDropPolicy(DropNewest))
+
+		a.Write([]byte("1")) // taken by the goroutine
+		waitFor(t, func() bool { return len(a.(*asyncWriter).queue) == 0 })
+		a.Write([]byte("2")) // queued
+
+		if _, err := a.Write([]byte("3")); !errors.Is(err, ErrQueueFull) {
+			t.Errorf("expected error to be %v, but got %v", ErrQueueFull, err)
+		}
+		if a.Dropped() != 1 {
+			t.Errorf("expected 1 dropped entry, but got %d", a.Dropped())
+		}
+		if a.Health() != Degraded {
+			t.Errorf("expected health to be %v, but got %v", Degraded, a.Health())
+		}
+
+		close(target.release)
+		a.Flush()
+		if target.String() != "12" {
+			t.Errorf("expected target to be %q, but got %q", "12", target.String())
+		}
+		a.Close()
+	})
+
+	t.Run("should drop the oldest entries when the queue is full", func(t *testing.T) {
+		target := newBlockingWriter()
+		a := NewAsyncWriter(target, WithQueueCapacity(1), WithDropPolicy(DropOldest))
+
+		a.Write([]byte("1"))
+		waitFor(t, func() bool { return len(a.(*asyncWriter).queue) == 0 })
+		a.Write([]byte("2"))
+
+		if _, err := a.Write([]byte("3")); err != nil {
+			t.Errorf("expected no error, but got %v", err)
+		}
+		if a.Dropped() != 1 {
+			t.Errorf("expected 1 dropped entry, but got %d", a.Dropped())
+		}
+
+		close(target.release)
+		if err := a.Flush(); err != nil {
+			t.Errorf("expected no error, but got %v", err)
+		}
+		if target.String() != "13" {
+			t.Errorf("expected target to be %q, but got %q", "13", target.String())
+		}
+		a.Close()
+	})
+
+	t.Run("should wait for room until the enqueue timeout", func(t *testing.T) {
+		target := newBlockingWriter()
+		a := NewAsyncWriter(target, WithQueueCapacity(1), WithDropPolicy(BlockWithTimeout), WithEnqueueTimeout(20*time.Millisecond))
+
+		a.Write([]byte("1"))
+		waitFor(t, func() bool { return len(a.(*asyncWriter).queue) == 0 })
+		a.Write([]byte("2"))
+
+		start := time.Now()
+		if _, err := a.Write([]byte("3")); !errors.Is(err, ErrQueueFull) {
+			t.Errorf("expected error to be %v, but got %v", ErrQueueFull, err)
+		}
+		if time.Since(start) < 20*time.Millisecond {
+			t.Error("expected the write to wait for the enqueue timeout")
+		}
+
+		close(target.release)
+		a.Close()
+	})
+
+	t.Run("should refuse entries after close", func(t *testing.T) {
+		a := NewAsyncWriter(io.Discard)
+		a.Close()
+
+		if _, err := a.Write([]byte("late")); !errors.Is(err, ErrWriterClosed) {
+			t.Errorf("expected error to be %v, but got %v", ErrWriterClosed, err)
+		}
+		if err := a.Close(); err != nil {
+			t.Errorf("expected close to be idempotent, but got %v", err)
+		}
+	})
+}
+
+func TestAsyncWriterHealth(t *testing.T) {
+	t.Run("should be healthy after a successful write", func(t *testing.T) {
+		a := NewAsyncWriter(io.Discard)
+		a.Write([]byte("ok"))
+		a.Flush()
+
+		if a.Health() != Healthy {
+			t.Errorf("expected health to be %v, but got %v", Healthy, a.Health())
+		}
+		a.Close()
+	})
+
+	t.Run("should be degraded after a failed write", func(t *testing.T) {
+		a := NewAsyncWriter(&ErrorWriter{Err: errors.New("write error")})
+		a.Write([]byte("fail"))
+		a.Flush()
+
+		if a.Health() != Degraded {
+			t.Errorf("expected health to be %v, but got %v", Degraded, a.Health())
+		}
+		a.Close()
+	})
+
+	t.Run("should be stalled while a write exceeds the timeout", func(t *testing.T) {
+		target := newBlockingWriter()
+		a := NewAsyncWriter(target, WithWriteTimeout(10*time.Millisecond))
+		a.Write([]byte("hang"))
+
+		waitFor(t, func() bool { return a.Health() == Stalled })
+
+		if err := a.Flush(); !errors.Is(err, ErrWriterStalled) {
+			t.Errorf("expected error to be %v, but got %v", ErrWriterStalled, err)
+		}
+
+		close(target.release)
+		a.Close()
+
+		if a.Health() != Healthy {
+			t.Errorf("expected health to be %v, but got %v", Healthy, a.Health())
+		}
+	})
+}
+
+func TestWriterHealthString(t *testing.T) {
+	cases := map[WriterHealth]string{
+		Healthy:          "HEALTHY",
+		Degraded:         "DEGRADED",
+		Stalled:          "STALLED",
+		WriterHealth(10): "UNKNOWN",
+	}
+	for h, expected := range cases {
+		if h.String() != expected {
+			t.Errorf("expected %q, but got %q", expected, h.String())
+		}
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached before the deadline")
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+}
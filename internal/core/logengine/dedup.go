@@ -0,0 +1,116 @@
+package logengine
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// dedup collapses the consecutive identical entries into the first one,
+// followed by a summary with the count of repeats.
+type dedup struct {
+	window  time.Duration
+	last    *ReportType
+	repeats uint64
+	start   time.Time
+	timer   *time.Timer
+}
+
+// sameReport reports whether b repeats a, the time is not compared.
+func sameReport(a ReportType, b ReportType) bool {
+	return a.Level == b.Level &&
+		a.Msg == b.Msg &&
+		a.CallerInfo == b.CallerInfo &&
+		slices.EqualFunc(a.Fields, b.Fields, sameField)
+}
+
+// sameField compares the fields, the values of any type are compared deeply, they may be not comparable.
+func sameField(a Field, b Field) bool {
+	return a.Key == b.Key &&
+		a.Type == b.Type &&
+		a.Integer == b.Integer &&
+		a.String == b.String &&
+		reflect.DeepEqual(a.Interface, b.Interface)
+}
+
+// SetDedupWindow collapses the consecutive identical entries for up to window,
+// zero disables the deduplication and writes the pending summary.
+func (l *logger) SetDedupWindow(window time.Duration) {
+	l.reportLock.Lock()
+	defer l.reportLock.Unlock()
+
+	l.writeRepeats()
+	l.dedup.window = window
+	l.dedup.last = nil
+}
+
+// deduplicate reports whether r must be written, it must be called with the report lock held.
+// A repeat of the last entry is counted, and the summary is written when the run ends,
+// or when the window expires since the first repeat.
+func (l *logger) deduplicate(r *ReportType) bool {
+	d := &l.dedup
+	if d.window <= 0 {
+		return true
+	}
+
+	if d.last != nil && sameReport(*d.last, *r) {
+		now := time.Now()
+		if d.repeats == 0 {
+			d.start = now
+			d.timer = time.AfterFunc(d.window, l.expireRepeats)
+		}
+		d.repeats++
+		d.last.Time = r.Time
+		d.last.At = r.At
+
+		if now.Sub(d.start) >= d.window {
+			l.writeRepeats()
+		}
+		return false
+	}
+
+	l.writeRepeats()
+	// copied only here, so the entries are not moved to the heap when it is off
+	last := *r
+	last.Fields = slices.Clone(r.Fields) // the fields of a pooled report are reused
+	d.last = &last
+	return true
+}
+
+// writeRepeats writes the summary of the pending repeats, it must be called with the report lock held.
+func (l *logger) writeRepeats() {
+	d := &l.dedup
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.repeats == 0 || d.last == nil {
+		return
+	}
+
+	summary := *d.last
+	summary.Msg = fmt.Sprintf("message repeated %d times", d.repeats)
+	summary.Fields = append(slices.Clone(d.last.Fields), stringField("repeated", strconv.FormatUint(d.repeats, 10)))
+	d.repeats = 0
+
+	l.writeReport(summary)
+}
+
+// expireRepeats writes the summary when the window of the repeats expired with no new entry.
+func (l *logger) expireRepeats() {
+	l.reportLock.Lock()
+	defer l.reportLock.Unlock()
+
+	if l.dedup.repeats > 0 && time.Since(l.dedup.start) >= l.dedup.window {
+		l.writeRepeats()
+	}
+}
+
+// flushRepeats writes the summary of the pending repeats.
+func (l *logger) flushRepeats() {
+	l.reportLock.Lock()
+	defer l.reportLock.Unlock()
+	l.writeRepeats()
+}
@@ -0,0 +1,148 @@
+package logengine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/runtimeinfo"
+)
+
+func reportLines(t *testing.T, buf *bytes.Buffer) []Entry {
+	t.Helper()
+
+	var entries []Entry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		e, err := ParseEntry([]byte(line))
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestDedup(t *testing.T) {
+	caller := runtimeinfo.GetCallerInfo(1)
+	flap := ReportType{Level: Warn, Msg: "link down", CallerInfo: caller}
+	other := ReportType{Level: Info, Msg: "link up", CallerInfo: caller}
+
+	t.Run("should summarize the repeats when the run ends", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+		l.SetDedupWindow(time.Hour)
+
+		for range 5 {
+			l.Report(flap)
+		}
+		l.Report(other)
+
+		entries := reportLines(t, buf)
+		if len(entries) != 3 {
+			t.Fatalf("expected 3 entries, got %d: %q", len(entries), buf.String())
+		}
+		if entries[0].Msg != "link down" {
+			t.Errorf("expected the first entry to be written, got %q", entries[0].Msg)
+		}
+		if entries[1].Msg != "message repeated 4 times" || entries[1].Fields["repeated"] != "4" {
+			t.Errorf("expected the summary of 4 repeats, got %+v", entries[1])
+		}
+		if entries[1].Level != Warn || entries[1].Line != caller.Line {
+			t.Errorf("expected the summary to keep the level and caller, got %+v", entries[1])
+		}
+		if entries[2].Msg != "link up" {
+			t.Errorf("expected the different entry last, got %q", entries[2].Msg)
+		}
+	})
+
+	t.Run("should give the summary the time of the last repeat", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+		l.SetDedupWindow(time.Hour)
+
+		first := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		for i := range 3 {
+			r := flap
+			r.At = first.Add(time.Duration(i) * time.Minute)
+			l.Report(r)
+		}
+		l.Report(other)
+
+		entries := reportLines(t, buf)
+		if len(entries) != 3 {
+			t.Fatalf("expected 3 entries, got %d: %q", len(entries), buf.String())
+		}
+		if last := first.Add(2 * time.Minute); !entries[1].Time.Equal(last) {
+			t.Errorf("expected the summary at %v, got %v", last, entries[1].Time)
+		}
+	})
+
+	t.Run("should not summarize a single entry", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+		l.SetDedupWindow(time.Hour)
+
+		l.Report(flap)
+		l.Report(other)
+		l.FlushReports()
+
+		if n := len(reportLines(t, buf)); n != 2 {
+			t.Errorf("expected 2 entries, got %d", n)
+		}
+	})
+
+	t.Run("should summarize when the window expires", func(t *testing.T) {
+		l := NewLogger()
+		buf := &mockBufferWriter{}
+		l.Writer().AddWriter(buf)
+		l.SetDedupWindow(20 * time.Millisecond)
+
+		for range 3 {
+			l.Report(flap)
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for !strings.Contains(buf.String(), "message repeated 2 times") {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected the summary after the window, got %q", buf.String())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+
+	t.Run("should write the pending summary on flush", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+		l.SetDedupWindow(time.Hour)
+
+		l.Report(flap)
+		l.Report(flap)
+		l.FlushReports()
+
+		if !strings.Contains(buf.String(), "message repeated 1 times") {
+			t.Errorf("expected the summary on flush, got %q", buf.String())
+		}
+	})
+
+	t.Run("should write every entry when disabled", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+
+		for range 3 {
+			l.Report(flap)
+		}
+
+		if n := len(reportLines(t, buf)); n != 3 {
+			t.Errorf("expected 3 entries, got %d", n)
+		}
+	})
+}
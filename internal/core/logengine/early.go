@@ -0,0 +1,130 @@
+package logengine
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// early handles the entries logged before the report goroutine first starts: they are kept
+// in a capped buffer and written in order once it starts, or written at once to a bootstrap writer.
+type early struct {
+	active  atomic.Bool // the entries are kept or written to the bootstrap writer
+	lock    sync.Mutex
+	done    bool // the report goroutine started, or the reports were closed
+	size    int
+	full    bool
+	reports []*ReportType
+	writer  io.Writer
+}
+
+// SetEarlyBuffer keeps up to size entries logged before the report goroutine first starts,
+// they are written in order once it starts, and the entries beyond size are dropped.
+// Zero stops keeping them, the ones kept are written by the next flush.
+func (l *logger) SetEarlyBuffer(size uint) {
+	e := &l.early
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.size = int(size)
+	e.active.Store(!e.done && (e.size > 0 || e.writer != nil))
+}
+
+// SetBootstrapWriter writes the entries logged before the report goroutine first starts
+// to w at once, instead of keeping them in the early buffer. Nil keeps them again.
+func (l *logger) SetBootstrapWriter(w io.Writer) {
+	e := &l.early
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.writer = w
+	e.active.Store(!e.done && (e.size > 0 || e.writer != nil))
+}
+
+// keepEarly reports whether r was taken by the early buffer or written to the bootstrap writer.
+func (l *logger) keepEarly(r *ReportType) bool {
+	e := &l.early
+	if !e.active.Load() {
+		return false
+	}
+
+	r.render() // the arguments may change until the entry is written
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	switch {
+	case e.done:
+		return false
+
+	case e.writer != nil:
+		l.writeBootstrap(e.writer, r)
+		ReleaseReport(r)
+
+	case len(e.reports) < e.size:
+		e.reports = append(e.reports, r)
+
+	case e.size > 0:
+		if !e.full {
+			e.full = true
+			fmt.Fprintf(os.Stderr, "logger early buffer is full, the entries logged before the start are dropped\n")
+		}
+		l.dropped.Add(1)
+		ReleaseReport(r)
+
+	default:
+		return false
+	}
+	return true
+}
+
+// closeEarly stops keeping the entries, the ones kept are written by the next drain.
+func (l *logger) closeEarly() {
+	e := &l.early
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.done = true
+	e.active.Store(false)
+}
+
+// writeEarly writes the kept entries once they are not kept anymore, it must be called with the drain lock held.
+func (l *logger) writeEarly() {
+	e := &l.early
+	e.lock.Lock()
+	if e.active.Load() || len(e.reports) == 0 {
+		e.lock.Unlock()
+		return
+	}
+	reports := e.reports
+	e.reports = nil
+	e.lock.Unlock()
+
+	for i := 0; i < len(reports); i += maxBatchEntries {
+		batch := reports[i:min(i+maxBatchEntries, len(reports))]
+		for _, r := range batch {
+			l.resolveCaller(r)
+		}
+		l.writeReports(batch)
+		for _, r := range batch {
+			ReleaseReport(r)
+		}
+	}
+}
+
+// writeBootstrap writes r to w alone, without the deduplication.
+func (l *logger) writeBootstrap(w io.Writer, r *ReportType) {
+	l.resolveCaller(r)
+
+	l.reportLock.Lock()
+	defer l.reportLock.Unlock()
+
+	l.encodeReport(*r)
+	if _, err := w.Write(l.batch); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to the bootstrap writer: %v\n", err)
+	}
+	l.batch = l.batch[:0]
+	l.ends = l.ends[:0]
+}
@@ -0,0 +1,140 @@
+package logengine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEarlyBuffer(t *testing.T) {
+	submit := func(l ILogger, msg string) {
+		r := AcquireReport()
+		r.Level = Info
+		r.Msg = msg
+		l.Submit(r)
+	}
+
+	t.Run("should write the kept entries in order once the reports are handled", func(t *testing.T) {
+		l := NewLogger()
+		buf := &mockBufferWriter{}
+		l.Writer().AddWriter(buf)
+		l.SetReportQueueSize(2)
+		l.SetEarlyBuffer(10)
+
+		for i := range 5 {
+			submit(l, fmt.Sprint("early ", i))
+		}
+		l.FlushReports()
+		if buf.String() != "" {
+			t.Fatalf("expected the entries to be kept until the start, got %q", buf.String())
+		}
+
+		defer handleReports(l)()
+		submit(l, "late")
+		l.FlushReports()
+
+		var msgs []string
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			msgs = append(msgs, line[strings.Index(line, `"msg":`):strings.Index(line, `,"file"`)])
+		}
+		want := []string{`"msg":"early 0"`, `"msg":"early 1"`, `"msg":"early 2"`, `"msg":"early 3"`, `"msg":"early 4"`, `"msg":"late"`}
+		if strings.Join(msgs, " ") != strings.Join(want, " ") {
+			t.Errorf("expected %v, got %v", want, msgs)
+		}
+	})
+
+	t.Run("should drop the entries beyond the size", func(t *testing.T) {
+		l := NewLogger()
+		buf := &mockBufferWriter{}
+		l.Writer().AddWriter(buf)
+		l.SetEarlyBuffer(2)
+
+		for i := range 3 {
+			submit(l, fmt.Sprint("early ", i))
+		}
+
+		defer handleReports(l)()
+		l.FlushReports()
+
+		if n := strings.Count(buf.String(), "\n"); n != 2 {
+			t.Errorf("expected 2 entries, got %q", buf.String())
+		}
+		if n := l.Dropped(); n != 1 {
+			t.Errorf("expected 1 dropped entry, got %d", n)
+		}
+	})
+
+	t.Run("should write the kept entries once the reports are closed", func(t *testing.T) {
+		l := NewLogger()
+		buf := &mockBufferWriter{}
+		l.Writer().AddWriter(buf)
+		l.SetEarlyBuffer(10)
+
+		submit(l, "early")
+		l.CloseReports()
+
+		if err := l.FlushContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), `"msg":"early"`) {
+			t.Errorf("expected the kept entry, got %q", buf.String())
+		}
+	})
+
+	t.Run("should format the messages when they are kept", func(t *testing.T) {
+		l := NewLogger()
+		buf := &mockBufferWriter{}
+		l.Writer().AddWriter(buf)
+		l.SetEarlyBuffer(10)
+
+		args := []int{1}
+		r := AcquireReport()
+		r.Level = Info
+		r.Format = "value %v"
+		r.Args = append(r.Args, args)
+		l.Submit(r)
+		args[0] = 2
+
+		defer handleReports(l)()
+		l.FlushReports()
+
+		if !strings.Contains(buf.String(), `"msg":"value [1]"`) {
+			t.Errorf("expected the value at the time of the call, got %q", buf.String())
+		}
+	})
+}
+
+func TestBootstrapWriter(t *testing.T) {
+	l := NewLogger()
+	buf := &mockBufferWriter{}
+	l.Writer().AddWriter(buf)
+	bootstrap := &bytes.Buffer{}
+	l.SetEarlyBuffer(10)
+	l.SetBootstrapWriter(bootstrap)
+
+	r := AcquireReport()
+	r.Level = Info
+	r.Msg = "bootstrap"
+	l.Submit(r)
+
+	if !strings.Contains(bootstrap.String(), `"msg":"bootstrap"`) {
+		t.Fatalf("expected the entry on the bootstrap writer at once, got %q", bootstrap.String())
+	}
+
+	defer handleReports(l)()
+
+	r = AcquireReport()
+	r.Level = Info
+	r.Msg = "started"
+	l.Submit(r)
+	l.FlushReports()
+
+	if got := buf.String(); strings.Contains(got, "bootstrap") || !strings.Contains(got, `"msg":"started"`) {
+		t.Errorf("expected only the entry logged after the start on the writers, got %q", got)
+	}
+	if strings.Contains(bootstrap.String(), "started") {
+		t.Errorf("expected the bootstrap writer to stop once started, got %q", bootstrap.String())
+	}
+}
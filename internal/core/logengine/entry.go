@@ -0,0 +1,63 @@
+package logengine
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Entry is a log line decoded back into its parts,
+// used by the writers that need more than the JSON line.
+type Entry struct {
+	Time     time.Time
+	Level    Level
+	Msg      string
+	File     string
+	Package  string
+	Function string
+	Line     int
+
+	// Fields holds every other field, e.g. the static fields.
+	// The values that are not JSON strings keep their JSON text.
+	Fields map[string]string
+}
+
+// ParseEntry decodes a line written by the logger.
+func ParseEntry(line []byte) (Entry, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return Entry{}, err
+	}
+
+	e := Entry{Fields: make(map[string]string, len(raw))}
+
+	for key, value := range raw {
+		s := string(value)
+		if len(value) > 0 && value[0] == '"' {
+			if err := json.Unmarshal(value, &s); err != nil {
+				return Entry{}, err
+			}
+		}
+
+		switch key {
+		case "time":
+			e.Time, _ = time.Parse(time.RFC3339Nano, s)
+		case "level":
+			e.Level, _ = ParseLevel(s)
+		case "msg":
+			e.Msg = s
+		case "file":
+			e.File = s
+		case "package":
+			e.Package = s
+		case "function":
+			e.Function = s
+		case "line":
+			e.Line, _ = strconv.Atoi(s)
+		default:
+			e.Fields[key] = s
+		}
+	}
+
+	return e, nil
+}
@@ -0,0 +1,39 @@
+package logengine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEntry(t *testing.T) {
+	t.Run("should decode the fields of a log line", func(t *testing.T) {
+		line := []byte(`{"app":"api","time":"2025-06-17T10:00:00Z","level":"WARN","msg":"disk \"almost\" full","file":"main.go","package":"main","function":"run","line":"42","stack":[{"line":1}]}` + "\n")
+
+		e, err := ParseEntry(line)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		if !e.Time.Equal(time.Date(2025, 6, 17, 10, 0, 0, 0, time.UTC)) {
+			t.Errorf("unexpected time: %v", e.Time)
+		}
+		if e.Level != Warn {
+			t.Errorf("expected level to be %v, but got %v", Warn, e.Level)
+		}
+		if e.Msg != `disk "almost" full` {
+			t.Errorf("unexpected message: %q", e.Msg)
+		}
+		if e.File != "main.go" || e.Package != "main" || e.Function != "run" || e.Line != 42 {
+			t.Errorf("unexpected caller: %s %s %s %d", e.File, e.Package, e.Function, e.Line)
+		}
+		if len(e.Fields) != 2 || e.Fields["app"] != "api" || e.Fields["stack"] != `[{"line":1}]` {
+			t.Errorf("unexpected fields: %v", e.Fields)
+		}
+	})
+
+	t.Run("should fail when the line is not JSON", func(t *testing.T) {
+		if _, err := ParseEntry([]byte("not a log")); err == nil {
+			t.Error("expected an error, but got nil")
+		}
+	})
+}
@@ -0,0 +1,12 @@
+package logengine
+
+import "errors"
+
+var (
+	ErrWriterClosed   = errors.New("writer is closed")
+	ErrWriterStalled  = errors.New("writer stalled")
+	ErrQueueFull      = errors.New("writer queue is full")
+	ErrEntriesLost    = errors.New("log entries were lost")
+	ErrWriterPanicked = errors.New("writer panicked")
+	ErrFlushReentrant = errors.New("flush called while the reports are written, by an error handler or a LogValuer")
+)
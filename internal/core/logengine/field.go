@@ -0,0 +1,94 @@
+package logengine
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/runtimeinfo"
+)
+
+// FieldType is the type of the value of a Field.
+type FieldType uint8
+
+const (
+	StringField FieldType = iota + 1
+	IntField
+	UintField
+	FloatField
+	BoolField
+	DurationField
+	TimeField
+	ErrorField
+	AnyField
+	// LazyField holds a LogValuer or a fmt.Stringer, evaluated by the report goroutine.
+	LazyField
+	// RawField holds a value already encoded as JSON in String.
+	RawField
+)
+
+// Field is a typed field of a single entry, its value is kept as is and
+// encoded by the report goroutine, so creating it does not allocate.
+type Field struct {
+	Key       string
+	Type      FieldType
+	Integer   int64
+	String    string
+	Interface any
+}
+
+// appendValue appends the JSON value of the field to b,
+// it reports whether the value is a string to be quoted.
+func (f Field) appendValue(b []byte) ([]byte, bool) {
+	switch f.Type {
+	case StringField:
+		return append(b, f.String...), true
+	case IntField:
+		return strconv.AppendInt(b, f.Integer, 10), false
+	case UintField:
+		return strconv.AppendUint(b, uint64(f.Integer), 10), false
+	case FloatField:
+		v := math.Float64frombits(uint64(f.Integer))
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return strconv.AppendFloat(b, v, 'g', -1, 64), true
+		}
+		return strconv.AppendFloat(b, v, 'g', -1, 64), false
+	case BoolField:
+		return strconv.AppendBool(b, f.Integer != 0), false
+	case DurationField:
+		return append(b, time.Duration(f.Integer).String()...), true
+	case TimeField:
+		t := time.Unix(0, f.Integer)
+		if loc, ok := f.Interface.(*time.Location); ok && loc != nil {
+			t = t.In(loc)
+		}
+		return t.AppendFormat(b, time.RFC3339Nano), true
+	case ErrorField:
+		err, _ := f.Interface.(error)
+		if err == nil {
+			return append(b, "null"...), false
+		}
+		return append(b, err.Error()...), true
+	case LazyField:
+		return f.evaluate().appendValue(b)
+	case RawField:
+		return append(b, f.String...), false
+	default:
+		raw, err := json.Marshal(f.Interface)
+		if err != nil {
+			return append(b, err.Error()...), true
+		}
+		return append(b, raw...), false
+	}
+}
+
+// stringField returns a field with a string value.
+func stringField(key string, value string) Field {
+	return Field{Key: key, Type: StringField, String: value}
+}
+
+// StackField returns the "stack" field with the frames.
+func StackField(stack []runtimeinfo.Frame) Field {
+	return Field{Key: "stack", Type: AnyField, Interface: stack}
+}
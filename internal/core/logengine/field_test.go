@@ -0,0 +1,69 @@
+package logengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFieldEncoding(t *testing.T) {
+	at := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		field Field
+		want  any
+	}{
+		{"string", Field{Key: "k", Type: StringField, String: "say \"hi\"\n"}, "say \"hi\"\n"},
+		{"int", Field{Key: "k", Type: IntField, Integer: -42}, float64(-42)},
+		{"uint", Field{Key: "k", Type: UintField, Integer: 42}, float64(42)},
+		{"float", Field{Key: "k", Type: FloatField, Integer: int64(math.Float64bits(0.5))}, 0.5},
+		{"NaN float", Field{Key: "k", Type: FloatField, Integer: int64(math.Float64bits(math.NaN()))}, "NaN"},
+		{"bool", Field{Key: "k", Type: BoolField, Integer: 1}, true},
+		{"duration", Field{Key: "k", Type: DurationField, Integer: int64(1500 * time.Millisecond)}, "1.5s"},
+		{"time", Field{Key: "k", Type: TimeField, Integer: at.UnixNano(), Interface: time.UTC}, "2024-05-01T10:30:00Z"},
+		{"error", Field{Key: "k", Type: ErrorField, Interface: errors.New("boom")}, "boom"},
+		{"nil error", Field{Key: "k", Type: ErrorField}, nil},
+		{"any", Field{Key: "k", Type: AnyField, Interface: map[string]int{"a": 1}}, map[string]any{"a": float64(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLogger()
+			buf := &bytes.Buffer{}
+			l.Writer().AddWriter(buf)
+
+			l.Report(ReportType{Level: Info, Msg: "hello", Fields: []Field{tt.field}})
+
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+			}
+			got, err := json.Marshal(entry["k"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := json.Marshal(tt.want)
+			if !bytes.Equal(got, want) {
+				t.Errorf("expected %s, but got %s", want, got)
+			}
+		})
+	}
+}
+
+func TestReportPool(t *testing.T) {
+	r := AcquireReport()
+	r.Msg = "hello"
+	r.Fields = append(r.Fields, Field{Key: "k", Type: AnyField, Interface: "v"})
+	ReleaseReport(r)
+
+	if r.Msg != "" || len(r.Fields) != 0 {
+		t.Errorf("expected a released report to be empty, got %+v", r)
+	}
+	if f := r.Fields[:1][0]; f.Interface != nil {
+		t.Errorf("expected the released fields to be cleared, got %+v", f)
+	}
+}
@@ -0,0 +1,94 @@
+package logengine
+
+import (
+	"fmt"
+)
+
+// LogValuer is a value evaluated only when the entry that holds it is written,
+// so an expensive value costs nothing when the entry is dropped.
+type LogValuer interface {
+	LogValue() any
+}
+
+// render formats the message and evaluates the lazy fields, it runs once the entry passed
+// the filters, on the report goroutine, or on the goroutine that logs when the formatting is eager.
+func (r *ReportType) render() {
+	if r.Format != "" {
+		for i, arg := range r.Args {
+			if v, ok := arg.(LogValuer); ok {
+				r.Args[i] = logValue(v)
+			}
+		}
+		r.Msg = fmt.Sprintf(r.Format, r.Args...)
+		r.Format = ""
+		r.Args = r.Args[:0]
+	}
+
+	for i := range r.Fields {
+		if r.Fields[i].Type == LazyField {
+			r.Fields[i] = r.Fields[i].evaluate()
+		}
+	}
+}
+
+// snapshot encodes the errors and the values of any type, so the entry no longer refers
+// to the values of the caller, it runs on the goroutine that logs when the formatting is eager.
+func (r *ReportType) snapshot() {
+	for i, f := range r.Fields {
+		switch f.Type {
+		case ErrorField:
+			if f.Interface != nil {
+				r.Fields[i] = f.encode()
+			}
+		case AnyField:
+			r.Fields[i] = f.encode()
+		}
+	}
+}
+
+// encode returns the field with its value encoded, as a string or as raw JSON.
+func (f Field) encode() Field {
+	value, quoted := f.appendValue(nil)
+	if quoted {
+		return stringField(f.Key, string(value))
+	}
+	return Field{Key: f.Key, Type: RawField, String: string(value)}
+}
+
+// evaluate returns the field with the value of its LogValuer or fmt.Stringer.
+func (f Field) evaluate() Field {
+	switch v := f.Interface.(type) {
+	case LogValuer:
+		value := logValue(v)
+		if s, ok := value.(string); ok {
+			return stringField(f.Key, s)
+		}
+		return Field{Key: f.Key, Type: AnyField, Interface: value}
+	case fmt.Stringer:
+		return stringField(f.Key, stringValue(v))
+	default:
+		return Field{Key: f.Key, Type: AnyField, Interface: v}
+	}
+}
+
+// logValue returns the value of v, a panic is written as the value instead of
+// stopping the report goroutine.
+func logValue(v LogValuer) (value any) {
+	defer func() {
+		if p := recover(); p != nil {
+			value = fmt.Sprintf("!PANIC=LogValue: %v", p)
+		}
+	}()
+	return v.LogValue()
+}
+
+// stringValue returns the string of v, a panic is written as the value instead of
+// stopping the report goroutine.
+func stringValue(v fmt.Stringer) (value string) {
+	defer func() {
+		if p := recover(); p != nil {
+			value = fmt.Sprintf("!PANIC=String: %v", p)
+		}
+	}()
+	return v.String()
+}
@@ -0,0 +1,183 @@
+package logengine
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/sampler"
+)
+
+// countingValue counts how many times it is evaluated.
+type countingValue struct {
+	calls int
+	value any
+}
+
+func (v *countingValue) LogValue() any {
+	v.calls++
+	return v.value
+}
+
+type panicValue struct{}
+
+func (panicValue) LogValue() any  { panic("boom") }
+func (panicValue) String() string { panic("boom") }
+
+type name string
+
+func (n name) String() string { return "name:" + string(n) }
+
+type mutableError struct{ msg string }
+
+func (e *mutableError) Error() string { return e.msg }
+
+func TestRender(t *testing.T) {
+	t.Run("should format the message and evaluate the lazy values", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+
+		l.Report(ReportType{
+			Level:  Info,
+			Format: "user %s has %d items, %v",
+			Args:   []any{name("alice"), 3, &countingValue{value: "lazy"}},
+			Fields: []Field{
+				{Key: "owner", Type: LazyField, Interface: name("bob")},
+				{Key: "size", Type: LazyField, Interface: &countingValue{value: 42}},
+			},
+		})
+
+		e, err := ParseEntry(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Msg != "user name:alice has 3 items, lazy" {
+			t.Errorf("unexpected message %q", e.Msg)
+		}
+		if !strings.Contains(buf.String(), `"owner":"name:bob"`) || !strings.Contains(buf.String(), `"size":42`) {
+			t.Errorf("expected the lazy fields, got %q", buf.String())
+		}
+	})
+
+	t.Run("should not evaluate the values of dropped entries", func(t *testing.T) {
+		l := NewLogger()
+		l.Writer().AddWriter(&bytes.Buffer{})
+
+		v := &countingValue{value: "lazy"}
+		r := ReportType{
+			Level:   Debug,
+			Format:  "%v",
+			Args:    []any{v},
+			Fields:  []Field{{Key: "v", Type: LazyField, Interface: v}},
+			Sampler: sampler.NewBurst(1, time.Hour),
+		}
+		for range 5 {
+			l.Report(r)
+		}
+
+		if v.calls != 2 {
+			t.Errorf("expected the values of the single written entry to be evaluated, got %d calls", v.calls)
+		}
+	})
+
+	t.Run("should write the panics of the values", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+
+		l.Report(ReportType{
+			Level:  Error,
+			Format: "%v",
+			Args:   []any{panicValue{}},
+			Fields: []Field{{Key: "v", Type: LazyField, Interface: name("x")}, {Key: "p", Type: LazyField, Interface: panicValue{}}},
+		})
+
+		if !strings.Contains(buf.String(), `"msg":"!PANIC=LogValue: boom"`) || !strings.Contains(buf.String(), `"p":"!PANIC=LogValue: boom"`) {
+			t.Errorf("expected the panics to be written, got %q", buf.String())
+		}
+	})
+
+	t.Run("should snapshot the arguments when eager", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+		l.SetEagerFormat(true)
+
+		items := []int{1, 2}
+		r := AcquireReport()
+		r.Level = Info
+		r.Format = "items %v"
+		r.Args = append(r.Args, items)
+		l.Submit(r)
+		items[0] = 9
+
+		l.FlushReports()
+		e, err := ParseEntry(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Msg != "items [1 2]" {
+			t.Errorf("expected the arguments at the time of the call, got %q", e.Msg)
+		}
+	})
+
+	t.Run("should snapshot the values of any type when eager", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+		l.SetEagerFormat(true)
+
+		items := map[string]int{"a": 1}
+		err := &mutableError{msg: "before"}
+		r := AcquireReport()
+		r.Level = Info
+		r.Msg = "items"
+		r.Fields = append(r.Fields,
+			Field{Key: "items", Type: AnyField, Interface: items},
+			Field{Key: "error", Type: ErrorField, Interface: err},
+			Field{Key: "none", Type: ErrorField},
+		)
+		l.Submit(r)
+		items["a"] = 9
+		err.msg = "after"
+
+		l.FlushReports()
+		for _, want := range []string{`"items":{"a":1}`, `"error":"before"`, `"none":null`} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("expected %s, got %q", want, buf.String())
+			}
+		}
+	})
+
+	t.Run("should format on the report goroutine", func(t *testing.T) {
+		l := NewLogger()
+		buf := &mockBufferWriter{}
+		l.Writer().AddWriter(buf)
+
+		v := &countingValue{value: "lazy"}
+		r := AcquireReport()
+		r.Level = Info
+		r.Format = "%v"
+		r.Args = append(r.Args, v)
+		l.Submit(r)
+
+		if v.calls != 0 {
+			t.Errorf("expected the value not to be evaluated before the entry is handled")
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go l.HandleReports(ctx, nil)
+
+		deadline := time.Now().Add(time.Second)
+		for buf.String() == "" && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if !strings.Contains(buf.String(), `"msg":"lazy"`) {
+			t.Errorf("expected the formatted message, got %q", buf.String())
+		}
+	})
+}
@@ -1,6 +1,9 @@
 package logengine
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+)
 
 type Level int
 
@@ -34,3 +37,26 @@ func (l Level) String() string {
 		return strconv.Itoa(int(l))
 	}
 }
+
+// ParseLevel returns the level named by s, as produced by Level.String.
+// The lookup is case insensitive.
+func ParseLevel(s string) (Level, bool) {
+	switch strings.ToUpper(s) {
+	case "TRACE":
+		return Trace, true
+	case "DEBUG":
+		return Debug, true
+	case "INFO":
+		return Info, true
+	case "WARN":
+		return Warn, true
+	case "ERROR":
+		return Error, true
+	case "PANIC":
+		return Panic, true
+	case "FATAL":
+		return Fatal, true
+	default:
+		return 0, false
+	}
+}
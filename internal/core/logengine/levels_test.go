@@ -107,3 +107,30 @@ func TestLevelString(t *testing.T) {
 		}
 	})
 }
+
+func TestParseLevel(t *testing.T) {
+	t.Run("should parse every named level", func(t *testing.T) {
+		for _, level := range []Level{Trace, Debug, Info, Warn, Error, Panic, Fatal} {
+			got, ok := ParseLevel(level.String())
+			if !ok {
+				t.Errorf("expected %q to be parsed", level.String())
+			}
+			if got != level {
+				t.Errorf("expected level to be %v, but got %v", level, got)
+			}
+		}
+	})
+
+	t.Run("should ignore the case of the name", func(t *testing.T) {
+		got, ok := ParseLevel("warn")
+		if !ok || got != Warn {
+			t.Errorf("expected level to be %v, but got %v (ok=%v)", Warn, got, ok)
+		}
+	})
+
+	t.Run("should not parse unknown names", func(t *testing.T) {
+		if _, ok := ParseLevel("verbose"); ok {
+			t.Error("expected unknown level not to be parsed")
+		}
+	})
+}
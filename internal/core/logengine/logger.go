@@ -2,45 +2,118 @@ package logengine
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"maps"
 	"os"
 	"slices"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/IonicHealthUsa/ionlog/internal/core/logbuilder"
+	"github.com/IonicHealthUsa/ionlog/internal/core/ringbuffer"
 	"github.com/IonicHealthUsa/ionlog/internal/core/runtimeinfo"
+	"github.com/IonicHealthUsa/ionlog/internal/core/sampler"
 	"github.com/IonicHealthUsa/ionlog/internal/infrastructure/memory"
 )
 
 type ReportType struct {
+	// Time is the formatted time, when it is empty At is formatted by the report goroutine.
 	Time       string
+	At         time.Time
 	Level      Level
 	Msg        string
 	CallerInfo runtimeinfo.CallerInfo
+	// PC is the program counter of the caller, it is resolved into the CallerInfo
+	// by the report goroutine when the CallerInfo is empty.
+	PC uintptr
+
+	// Format and Args are formatted into Msg by the report goroutine, in the manner of fmt.Sprintf,
+	// when Format is not empty.
+	Format string
+	Args   []any
+
+	// Fields are written after the caller information.
+	Fields []Field
+	// Sampler limits the entries of the call site, it replaces the sampler of the level.
+	Sampler sampler.ISampler
+	// Stack is written as the "stack" array of frames.
+	Stack []runtimeinfo.Frame
+
+	// barrier marks a flush, it is not an entry.
+	barrier *barrier
 }
 
+// barrier is queued by a flush, it is done once every report queued before it is written.
+type barrier struct {
+	done chan struct{}
+	err  error
+}
+
+const (
+	// maxBatchEntries is the number of reports the report goroutine takes from the queue at once.
+	maxBatchEntries = 256
+	// maxBatchBytes is the size of the encoded entries that makes a batch to be written.
+	maxBatchBytes = 64 * 1024
+)
+
 type logger struct {
 	builder    logbuilder.ILogBuilder
 	logsMemory memory.IRecordMemory
-	closed     bool
-	reports    chan ReportType
+	closed     atomic.Bool
+	closedDrop atomic.Bool   // a report dropped since the reports were closed was reported
+	handling   atomic.Bool   // a report goroutine takes the reports
+	stopped    chan struct{} // closed when the report goroutine stops, guarded by the handler lock
+	dropped    atomic.Uint64 // the entries lost by the queue
+	writing    atomic.Int64  // the reports taken from the queue and not written yet
+	reports    ringbuffer.IRingBuffer[*ReportType]
 	writer     IWriter
+	scratch    []byte
+
+	// the batch of encoded entries, guarded by the report lock
+	batch   []byte
+	ends    []int
+	entries [][]byte
+
+	// the reports taken from the queue and the barrier after them, guarded by the drain lock
+	pending   []*ReportType
+	flushing  *barrier
+	drainer   atomic.Uint64 // the goroutine holding the drain lock
+	drainLock sync.Mutex
 
 	staticFields map[string]string
-	traceMode    bool
+	traceMode    atomic.Bool
+	callerInfo   atomic.Bool
+	eagerFormat  atomic.Bool
+	callerFormat runtimeinfo.Format
+
+	dedup dedup
+	early early
+
+	// the options read by the goroutines that log
+	stackTrace      bool
+	stackTraceLevel Level
+	samplers        map[Level]sampler.ISampler
+	optionsLock     sync.RWMutex
 
-	reportLock sync.Mutex
-	closeLock  sync.Mutex
+	reportLock  sync.Mutex
+	handlerLock sync.Mutex
 }
 
 type ILogger interface {
 	AsyncReport(r ReportType)
+	Submit(r *ReportType)
 	Report(r ReportType)
 	FlushReports()
-	HandleReports(ctx context.Context)
+	FlushContext(ctx context.Context) error
+	HandleReports(ctx context.Context, ready chan<- struct{})
+	CloseReports()
+	OpenReports()
+	Discard() int
+	Dropped() uint64
 	Writer() IWriter
 	Memory() memory.IRecordMemory
 	AddStaticFields(attrs map[string]string)
@@ -48,6 +121,15 @@ type ILogger interface {
 	SetReportQueueSize(size uint)
 	SetTraceMode(mode bool)
 	TraceMode() bool
+	SetLevelSampler(level Level, s sampler.ISampler)
+	SetDedupWindow(window time.Duration)
+	SetStackTrace(enabled bool, level Level)
+	SetCallerInfo(enabled bool)
+	SetCallerFormat(format runtimeinfo.Format)
+	CallerInfoEnabled() bool
+	SetEagerFormat(enabled bool)
+	SetEarlyBuffer(size uint)
+	SetBootstrapWriter(w io.Writer)
 }
 
 func NewLogger() ILogger {
@@ -55,81 +137,473 @@ func NewLogger() ILogger {
 
 	logger.builder = logbuilder.NewLogBuilder()
 	logger.logsMemory = memory.NewRecordMemory()
-	logger.reports = make(chan ReportType, 100)
+	logger.reports = ringbuffer.NewRingBuffer[*ReportType](100)
 	logger.writer = NewWriter()
+	logger.callerInfo.Store(true)
 
 	return logger
 }
 
 func (l *logger) closeReport() {
-	l.closeLock.Lock()
-	defer l.closeLock.Unlock()
-	l.closed = true
+	l.closed.Store(true)
 }
 
 func (l *logger) getStatusCloseReport() bool {
-	l.closeLock.Lock()
-	defer l.closeLock.Unlock()
-	return l.closed
+	return l.closed.Load()
+}
+
+// CloseReports stops accepting reports, the ones already queued, or kept before the start, are still written.
+func (l *logger) CloseReports() {
+	l.closeReport()
+	l.closeEarly()
+}
+
+// OpenReports accepts the reports again, after they were closed.
+func (l *logger) OpenReports() {
+	l.closed.Store(false)
+	l.closedDrop.Store(false)
+}
+
+// Discard removes the queued reports without writing them, and returns how many entries
+// are not written, the discarded ones and the ones being written, they are counted as dropped.
+func (l *logger) Discard() int {
+	discarded := int(l.writing.Load())
+	for {
+		r, ok := l.reports.Pop()
+		if !ok {
+			break
+		}
+		if r.barrier != nil {
+			continue // the flush waiting on it gave up already
+		}
+		ReleaseReport(r)
+		discarded++
+	}
+	l.dropped.Add(uint64(discarded))
+	return discarded
 }
 
+// Dropped returns how many entries were lost by the queue since the logger was created,
+// because the reports were closed, the queue stayed full or they were discarded.
+func (l *logger) Dropped() uint64 {
+	return l.dropped.Load()
+}
+
+// AsyncReport queues a copy of r to be written by the report goroutine.
 func (l *logger) AsyncReport(r ReportType) {
+	p := AcquireReport()
+	fields := p.Fields
+	args := p.Args
+	*p = r
+	p.Fields = append(fields, r.Fields...)
+	p.Args = append(args, r.Args...)
+	l.Submit(p)
+}
+
+// Submit queues r to be written by the report goroutine, r must come from AcquireReport
+// and it is released once written, so it must not be used after the call.
+func (l *logger) Submit(r *ReportType) {
 	if l.getStatusCloseReport() {
+		if l.closedDrop.CompareAndSwap(false, true) {
+			fmt.Fprintf(os.Stderr, "logger is stopped, the logs are dropped until it starts\n")
+		}
+		l.dropped.Add(1)
+		ReleaseReport(r)
+		return
+	}
+	if !l.sample(r) {
+		ReleaseReport(r)
+		return
+	}
+	l.captureStack(r)
+	if l.keepEarly(r) {
+		return
+	}
+	if l.eagerFormat.Load() {
+		r.render()
+		r.snapshot()
+	}
+
+	if l.reports.Push(r) {
 		return
 	}
-	select {
-	case l.reports <- r:
-	case <-time.After(1 * time.Second):
-		fmt.Fprintf(os.Stderr, "logger reports channel is full\n")
+
+	// the queue is full, wait for the report goroutine to make room
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	if !l.reports.PushWait(ctx, r) {
+		fmt.Fprintf(os.Stderr, "logger reports queue is full\n")
+		l.dropped.Add(1)
+		ReleaseReport(r)
 	}
 }
 
 func (l *logger) Report(r ReportType) {
+	if !l.sample(&r) {
+		return
+	}
+	l.captureStack(&r)
+	l.report(r)
+}
+
+// captureStack attaches the stack of the caller when the level of the entry requires it,
+// it runs on the goroutine that logs.
+func (l *logger) captureStack(r *ReportType) {
+	l.optionsLock.RLock()
+	enabled := l.stackTrace && r.Level >= l.stackTraceLevel
+	l.optionsLock.RUnlock()
+
+	if !enabled || r.Stack != nil || slices.ContainsFunc(r.Fields, isStackField) {
+		return
+	}
+	r.Stack = runtimeinfo.TrimInternal(runtimeinfo.Stack(1))
+}
+
+func isStackField(f Field) bool {
+	return f.Key == "stack"
+}
+
+// sample reports whether the entry is emitted by the sampler of the entry or of its level,
+// adding how many entries of the call site were suppressed before it.
+func (l *logger) sample(r *ReportType) bool {
+	s := r.Sampler
+	if s == nil {
+		l.optionsLock.RLock()
+		s = l.samplers[r.Level]
+		l.optionsLock.RUnlock()
+	}
+	if s == nil {
+		return true
+	}
+
+	var id uint64
+	if r.CallerInfo == (runtimeinfo.CallerInfo{}) && !l.callerInfo.Load() {
+		id = sampler.CallSitePCID(r.PC) // the caller is not resolved when the caller info is disabled
+	} else {
+		ci := r.caller()
+		id = sampler.CallSiteID(ci.File, ci.PackagePath, ci.Function, ci.Line)
+	}
+	emit, suppressed := sampler.Sample(l.logsMemory, id, s, time.Now())
+	if emit && suppressed > 0 {
+		r.Fields = append(r.Fields, stringField("suppressed", strconv.FormatUint(suppressed, 10)))
+	}
+	return emit
+}
+
+// caller returns the CallerInfo, resolving the PC when it is empty.
+func (r *ReportType) caller() runtimeinfo.CallerInfo {
+	if r.CallerInfo == (runtimeinfo.CallerInfo{}) {
+		return runtimeinfo.Resolve(r.PC)
+	}
+	return r.CallerInfo
+}
+
+// resolveCaller sets the CallerInfo of r from its PC, only when the caller info is written.
+func (l *logger) resolveCaller(r *ReportType) {
+	if l.callerInfo.Load() {
+		r.CallerInfo = r.caller()
+	}
+}
+
+func (l *logger) report(r ReportType) {
+	l.resolveCaller(&r)
+	r.render()
+
 	l.reportLock.Lock()
 	defer l.reportLock.Unlock()
 
+	if !l.deduplicate(&r) {
+		return
+	}
+	l.writeReport(r)
+}
+
+// writeReports writes the reports as a batch, they were already rendered.
+func (l *logger) writeReports(reports []*ReportType) {
+	l.reportLock.Lock()
+	defer l.reportLock.Unlock()
+
+	for _, r := range reports {
+		if !l.deduplicate(r) {
+			continue
+		}
+		l.encodeReport(*r)
+		if len(l.batch) >= maxBatchBytes {
+			l.writeBatch()
+		}
+	}
+	l.writeBatch()
+}
+
+// writeReport writes the entry, after the entries of the batch,
+// it must be called with the report lock held.
+func (l *logger) writeReport(r ReportType) {
+	l.encodeReport(r)
+	l.writeBatch()
+}
+
+// writeBatch writes the encoded entries at once, it must be called with the report lock held.
+func (l *logger) writeBatch() {
+	if len(l.ends) == 0 {
+		return
+	}
+
+	start := 0
+	for _, end := range l.ends {
+		l.entries = append(l.entries, l.batch[start:end])
+		start = end
+	}
+	_ = l.writer.WriteBatch(l.batch, l.entries)
+
+	clear(l.entries)
+	l.entries = l.entries[:0]
+	l.ends = l.ends[:0]
+	l.batch = l.batch[:0]
+	if cap(l.batch) > 4*maxBatchBytes {
+		l.batch = nil // an entry far larger than a batch is not kept
+	}
+}
+
+// encodeReport builds the entry and appends it to the batch, it must be called with the report lock held.
+// The values are appended to a reused buffer, so encoding an entry does not allocate.
+func (l *logger) encodeReport(r ReportType) {
 	if l.staticFields != nil {
 		for key, value := range l.staticFields {
-			l.builder.AddFields(key, value)
+			l.builder.AddString(key, value)
 		}
 	}
 
-	l.builder.AddFields(
-		"time", r.Time,
-		"level", r.Level.String(),
-		"msg", r.Msg,
-		"file", r.CallerInfo.File,
-		"package", r.CallerInfo.Package,
-		"function", r.CallerInfo.Function,
-		"line", strconv.Itoa(r.CallerInfo.Line),
-	)
+	if r.Time != "" {
+		l.builder.AddString("time", r.Time)
+	} else {
+		l.scratch = r.At.AppendFormat(l.scratch[:0], time.RFC3339)
+		l.builder.AddField("time", l.scratch)
+	}
+	l.builder.AddString("level", r.Level.String())
+	l.builder.AddString("msg", r.Msg)
+	if l.callerInfo.Load() {
+		file, pkg, function := r.CallerInfo.Format(l.callerFormat)
+		l.builder.AddString("file", file)
+		l.builder.AddString("package", pkg)
+		l.builder.AddString("function", function)
+		l.scratch = strconv.AppendInt(l.scratch[:0], int64(r.CallerInfo.Line), 10)
+		l.builder.AddField("line", l.scratch)
+	}
+
+	for _, f := range r.Fields {
+		var quoted bool
+		l.scratch, quoted = f.appendValue(l.scratch[:0])
+		if quoted {
+			l.builder.AddField(f.Key, l.scratch)
+		} else {
+			l.builder.AddRawField(f.Key, l.scratch)
+		}
+	}
 
-	_, _ = l.writer.Write(l.builder.Compile())
+	if len(r.Stack) > 0 {
+		if stack, err := json.Marshal(r.Stack); err == nil {
+			l.builder.AddRawField("stack", stack)
+		}
+	}
+
+	l.batch = append(l.batch, l.builder.Compile()...)
+	l.ends = append(l.ends, len(l.batch))
 }
 
+// FlushReports returns once every report queued before the call is written and the writers are flushed.
 func (l *logger) FlushReports() {
-	for {
-		select {
-		case r := <-l.reports:
-			l.Report(r)
+	if err := l.FlushContext(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush the writers: %v\n", err)
+	}
+}
 
-		case <-time.After(1 * time.Millisecond):
-			return
+// FlushContext queues a barrier behind the reports and returns once the barrier is reached,
+// so every report queued before the call is written, and the writers are flushed.
+// It returns the error of ctx when it is done first, and the errors of the writers otherwise.
+// Without a report goroutine the reports are written by the caller, and ctx is not watched meanwhile.
+// It returns ErrFlushReentrant when called by the goroutine writing the reports, from a writer error
+// handler or a LogValuer, since it would wait for itself.
+func (l *logger) FlushContext(ctx context.Context) error {
+	id := runtimeinfo.GoroutineID()
+	if l.drainer.Load() == id {
+		return ErrFlushReentrant
+	}
+
+	b := &barrier{done: make(chan struct{})}
+	r := &ReportType{barrier: b}
+	for !l.reports.Push(r) {
+		if l.handling.Load() {
+			if !l.reports.PushWait(ctx, r) {
+				return ctx.Err()
+			}
+			break
 		}
+		l.drain(id) // a full queue with no report goroutine
 	}
-}
 
-func (l *logger) HandleReports(ctx context.Context) {
 	for {
+		stopped, handling := l.handler()
+
+		// the report goroutine drains the queue when it stops, after it is marked as stopped,
+		// so a barrier queued while it runs is reached in any case
+		if !handling {
+			select {
+			case <-b.done:
+				return b.err
+			default:
+			}
+			l.drain(id)
+			continue
+		}
+
 		select {
+		case <-b.done:
+			return b.err
 		case <-ctx.Done():
+			return ctx.Err()
+		case <-stopped: // a writer panicked, the reports left are written by the caller
+		}
+	}
+}
+
+// HandleReports writes the reports until ctx is done, the entries kept before the first start are written first.
+// It closes ready, when not nil, once it takes the reports. When a writer panics the goroutine
+// is marked as stopped before the panic goes on, so the flushes do not wait for it.
+func (l *logger) HandleReports(ctx context.Context, ready chan<- struct{}) {
+	id := runtimeinfo.GoroutineID()
+	l.closeEarly()
+	l.startHandling()
+	defer l.stopHandling()
+	if ready != nil {
+		close(ready)
+	}
+
+	for {
+		l.drain(id)
+
+		if !l.reports.Wait(ctx) {
 			l.closeReport()
+			l.stopHandling()
+			l.drain(id)
 			return
+		}
+	}
+}
+
+func (l *logger) startHandling() {
+	l.handlerLock.Lock()
+	defer l.handlerLock.Unlock()
+
+	l.stopped = make(chan struct{})
+	l.handling.Store(true)
+}
+
+// stopHandling marks the report goroutine as stopped and wakes the flushes waiting on it.
+func (l *logger) stopHandling() {
+	l.handlerLock.Lock()
+	defer l.handlerLock.Unlock()
+
+	if l.handling.Load() {
+		l.handling.Store(false)
+		close(l.stopped)
+	}
+}
+
+// handler returns the channel closed when the report goroutine stops and whether it runs.
+func (l *logger) handler() (<-chan struct{}, bool) {
+	l.handlerLock.Lock()
+	defer l.handlerLock.Unlock()
+	return l.stopped, l.handling.Load()
+}
+
+// flushBarrier writes the pending repeats, flushes the writers and releases the flush waiting on b.
+func (l *logger) flushBarrier(b *barrier) {
+	l.flushRepeats()
+	b.err = l.writer.Flush()
+	close(b.done)
+}
 
-		case r := <-l.reports:
-			l.Report(r)
+// drain writes up to a queue of reports, or until the queue is empty,
+// so the producers cannot keep the report goroutine from seeing its cancellation.
+// The reports are taken in batches, rendered without the report lock, since the lazy
+// values may log, and written with a single write per writer. id is the goroutine ID of the caller.
+func (l *logger) drain(id uint64) {
+	l.drainLock.Lock()
+	defer l.drainLock.Unlock()
+
+	l.drainer.Store(id)
+	defer l.drainer.Store(0)
+
+	drained := false
+	defer func() {
+		if !drained {
+			l.abortDrain() // a writer panicked
+		}
+	}()
+
+	l.writeEarly()
+	l.drainReports()
+	drained = true
+}
+
+// drainReports writes the batches of reports, it must be called with the drain lock held.
+func (l *logger) drainReports() {
+	for left := l.reports.Cap(); left > 0; {
+		pending := l.pending[:0]
+		for len(pending) < min(maxBatchEntries, left) {
+			r, ok := l.reports.Pop()
+			if !ok {
+				break
+			}
+			if r.barrier != nil {
+				l.flushing = r.barrier
+				left--
+				break
+			}
+			l.writing.Add(1)
+			l.resolveCaller(r)
+			r.render()
+			pending = append(pending, r)
+		}
+		if len(pending) == 0 && l.flushing == nil {
+			return
+		}
+		left -= len(pending)
+
+		l.writeReports(pending)
+		l.writing.Store(0)
+		for i, r := range pending {
+			ReleaseReport(r)
+			pending[i] = nil
 		}
+		l.pending = pending[:0]
+
+		if b := l.flushing; b != nil {
+			l.flushBarrier(b)
+			l.flushing = nil
+		}
+	}
+}
+
+// abortDrain releases the flush waiting on the barrier taken and clears the batch,
+// after a writer panicked. It must be called with the drain lock held.
+func (l *logger) abortDrain() {
+	if b := l.flushing; b != nil {
+		l.flushing = nil
+		b.err = ErrWriterPanicked
+		close(b.done)
 	}
+
+	l.writing.Store(0)
+	clear(l.pending)
+	l.pending = l.pending[:0]
+
+	l.reportLock.Lock()
+	defer l.reportLock.Unlock()
+	clear(l.entries)
+	l.entries = l.entries[:0]
+	l.ends = l.ends[:0]
+	l.batch = l.batch[:0]
 }
 
 func (l *logger) Writer() IWriter {
@@ -145,11 +619,11 @@ func (l *logger) AddStaticFields(attrs map[string]string) {
 	defer l.reportLock.Unlock()
 
 	if l.staticFields == nil {
-		l.staticFields = attrs
-		return
+		l.staticFields = make(map[string]string, len(attrs))
 	}
 
 	maps.Copy(l.staticFields, attrs)
+	l.writer.SetStaticFields(l.staticFields)
 }
 
 func (l *logger) DeleteStaticField(fields ...string) {
@@ -159,22 +633,65 @@ func (l *logger) DeleteStaticField(fields ...string) {
 	maps.DeleteFunc(l.staticFields, func(k string, v string) bool {
 		return slices.Contains(fields, k)
 	})
+	l.writer.SetStaticFields(l.staticFields)
 }
 
+// SetReportQueueSize resizes the queue of reports, at least two,
+// the queued reports are kept.
 func (l *logger) SetReportQueueSize(size uint) {
-	l.reportLock.Lock()
-	defer l.reportLock.Unlock()
-	l.reports = make(chan ReportType, size)
+	l.reports.Resize(size)
 }
 
 func (l *logger) SetTraceMode(mode bool) {
-	l.reportLock.Lock()
-	defer l.reportLock.Unlock()
-	l.traceMode = mode
+	l.traceMode.Store(mode)
 }
 
 func (l *logger) TraceMode() bool {
+	return l.traceMode.Load()
+}
+
+// SetLevelSampler sets the sampler of the entries of level, nil removes it.
+func (l *logger) SetLevelSampler(level Level, s sampler.ISampler) {
+	l.optionsLock.Lock()
+	defer l.optionsLock.Unlock()
+
+	if s == nil {
+		delete(l.samplers, level)
+		return
+	}
+	if l.samplers == nil {
+		l.samplers = make(map[Level]sampler.ISampler)
+	}
+	l.samplers[level] = s
+}
+
+// SetStackTrace attaches the stack of the caller to the entries at or above level.
+func (l *logger) SetStackTrace(enabled bool, level Level) {
+	l.optionsLock.Lock()
+	defer l.optionsLock.Unlock()
+	l.stackTrace = enabled
+	l.stackTraceLevel = level
+}
+
+// SetCallerInfo sets whether the entries have the file, package, function and line of the caller,
+// without them the callers are not looked up, for the highest throughput.
+func (l *logger) SetCallerInfo(enabled bool) {
+	l.callerInfo.Store(enabled)
+}
+
+func (l *logger) CallerInfoEnabled() bool {
+	return l.callerInfo.Load()
+}
+
+// SetEagerFormat sets whether the messages, the lazy fields and the values of any type are
+// formatted by the goroutine that logs, before the entry is queued, instead of by the report goroutine.
+func (l *logger) SetEagerFormat(enabled bool) {
+	l.eagerFormat.Store(enabled)
+}
+
+// SetCallerFormat sets how the file, package and function of the caller are written.
+func (l *logger) SetCallerFormat(format runtimeinfo.Format) {
 	l.reportLock.Lock()
 	defer l.reportLock.Unlock()
-	return l.traceMode
+	l.callerFormat = format
 }
@@ -3,13 +3,21 @@ package logengine
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/IonicHealthUsa/ionlog/internal/core/ringbuffer"
 	"github.com/IonicHealthUsa/ionlog/internal/core/runtimeinfo"
+	"github.com/IonicHealthUsa/ionlog/internal/core/sampler"
 )
 
 func TestNewLogger(t *testing.T) {
@@ -42,7 +50,7 @@ func TestNewLogger(t *testing.T) {
 		}
 
 		if _l.reports == nil {
-			t.Error("expected a chan to reports")
+			t.Error("expected a queue to reports")
 		}
 
 		if _l.writer == nil {
@@ -62,43 +70,15 @@ func TestCloseReport(t *testing.T) {
 			t.Fatalf("NewLogger did not returned a instance of logger")
 		}
 
-		_l.closeLock.Lock()
-		if _l.closed {
-			t.Errorf("expected the closed report to be %v, but got %v", false, _l.closed)
+		if _l.closed.Load() {
+			t.Errorf("expected the closed report to be %v, but got %v", false, _l.closed.Load())
 		}
-		_l.closeLock.Unlock()
 
 		_l.closeReport()
 
-		_l.closeLock.Lock()
-		if !_l.closed {
-			t.Errorf("expected the closed report to be %v, but got %v", true, _l.closed)
+		if !_l.closed.Load() {
+			t.Errorf("expected the closed report to be %v, but got %v", true, _l.closed.Load())
 		}
-		_l.closeLock.Unlock()
-	})
-
-	t.Run("should timeout when the mutex is lock", func(t *testing.T) {
-		l := NewLogger()
-		_l, ok := l.(*logger)
-		if !ok {
-			t.Fatalf("NewLogger did not returned a instance of logger")
-		}
-
-		_l.closeLock.Lock()
-		go _l.closeReport()
-
-		if _l.closed {
-			t.Errorf("expected the closed report to be false, but got %v", _l.closed)
-		}
-
-		_l.closeLock.Unlock()
-		<-time.After(time.Millisecond)
-
-		_l.closeLock.Lock()
-		if !_l.closed {
-			t.Errorf("expected the closed report to be true, but got %v", _l.closed)
-		}
-		_l.closeLock.Unlock()
 	})
 }
 
@@ -114,39 +94,12 @@ func TestGetStatusCloseReport(t *testing.T) {
 			t.Errorf("expected the closed report to be %v, but got %v", false, _l.getStatusCloseReport())
 		}
 
-		_l.closed = true
+		_l.closed.Store(true)
 
 		if !_l.getStatusCloseReport() {
 			t.Errorf("expected the closed report to be %v, but got %v", true, _l.getStatusCloseReport())
 		}
 	})
-
-	t.Run("should timeout when mutex is lock", func(t *testing.T) {
-		l := NewLogger()
-		_l, ok := l.(*logger)
-		if !ok {
-			t.Fatalf("NewLogger did not returned a instance of logger")
-		}
-
-		_l.closeLock.Lock()
-		comm := make(chan bool, 1)
-		go func() {
-			comm <- _l.getStatusCloseReport()
-		}()
-
-		select {
-		case c := <-comm:
-			t.Errorf("expected no response, but got %v", c)
-		case <-time.After(time.Millisecond):
-		}
-
-		_l.closeLock.Unlock()
-		select {
-		case <-comm:
-		case <-time.After(time.Millisecond):
-			t.Error("expected a response, but got nothing")
-		}
-	})
 }
 
 func TestAsyncReport(t *testing.T) {
@@ -166,8 +119,11 @@ func TestAsyncReport(t *testing.T) {
 
 		l.AsyncReport(r)
 
-		select {
-		case report := <-_l.reports:
+		report, ok := _l.reports.Pop()
+		if !ok {
+			t.Fatal("expected a report, but the queue is empty")
+		}
+		{
 			if report.Time != r.Time {
 				t.Errorf("expected time to be %q, but got %q", r.Time, report.Time)
 			}
@@ -189,8 +145,6 @@ func TestAsyncReport(t *testing.T) {
 			if report.CallerInfo.Function != r.CallerInfo.Function {
 				t.Errorf("expected function info to be %q, but got %q", r.CallerInfo.Function, report.CallerInfo.Function)
 			}
-		case <-time.After(time.Second):
-			t.Error("expected a report, but timeout")
 		}
 	})
 
@@ -201,30 +155,55 @@ func TestAsyncReport(t *testing.T) {
 			t.Fatalf("NewLogger did not returned a instance of logger")
 		}
 
-		_l.closed = true
+		_l.closed.Store(true)
 		l.AsyncReport(r)
 
-		select {
-		case <-_l.reports:
+		if _l.reports.Len() != 0 {
 			t.Error("expected no report, but got")
-		case <-time.After(time.Second):
 		}
 	})
 
-	t.Run("should timeout when report channel is full", func(t *testing.T) {
+	t.Run("should timeout when report queue is full", func(t *testing.T) {
 		l := NewLogger()
 		_l, ok := l.(*logger)
 		if !ok {
 			t.Fatalf("NewLogger did not returned a instance of logger")
 		}
-		_l.reports = make(chan ReportType)
+		_l.reports = ringbuffer.NewRingBuffer[*ReportType](2)
+		for range _l.reports.Cap() {
+			l.AsyncReport(r)
+		}
 
+		start := time.Now()
 		l.AsyncReport(r)
 
-		select {
-		case <-_l.reports:
-			t.Error("expected no report, but got")
-		case <-time.After(time.Second):
+		if time.Since(start) < time.Second {
+			t.Error("expected the report to wait for room in the queue")
+		}
+		if n := _l.reports.Len(); n != 2 {
+			t.Errorf("expected 2 reports, but got %d", n)
+		}
+	})
+
+	t.Run("should wait for room in the queue", func(t *testing.T) {
+		l := NewLogger()
+		_l, ok := l.(*logger)
+		if !ok {
+			t.Fatalf("NewLogger did not returned a instance of logger")
+		}
+		_l.reports = ringbuffer.NewRingBuffer[*ReportType](2)
+		for range _l.reports.Cap() {
+			l.AsyncReport(r)
+		}
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			_l.reports.Pop()
+		}()
+		l.AsyncReport(r)
+
+		if n := _l.reports.Len(); n != 2 {
+			t.Errorf("expected 2 reports, but got %d", n)
 		}
 	})
 }
@@ -369,7 +348,7 @@ func TestFlushReports(t *testing.T) {
 		buf := &bytes.Buffer{}
 		_l.writer.AddWriter(buf)
 
-		_l.reports <- r
+		_l.reports.Push(&r)
 
 		l.FlushReports()
 
@@ -401,9 +380,9 @@ func TestHandleReports(t *testing.T) {
 		_l.writer.AddWriter(buf)
 
 		ctx, cancel := context.WithCancel(context.Background())
-		go l.HandleReports(ctx)
+		go l.HandleReports(ctx, nil)
 
-		_l.reports <- r
+		_l.reports.Push(&r)
 		time.Sleep(time.Millisecond)
 
 		if buf.String() != reportLog {
@@ -417,7 +396,7 @@ func TestHandleReports(t *testing.T) {
 		}
 
 		buf.buf.Reset()
-		_l.reports <- r
+		_l.reports.Push(&r)
 		time.Sleep(time.Millisecond)
 
 		if buf.String() != "" {
@@ -629,8 +608,8 @@ func TestSetReportQueueSize(t *testing.T) {
 		size := 100
 		l.SetReportQueueSize(uint(size))
 
-		if cap(_l.reports) != size {
-			t.Errorf("expected the size of report to be %v, but got %q", size, cap(_l.reports))
+		if _l.reports.Cap() != size {
+			t.Errorf("expected the size of report to be %v, but got %q", size, _l.reports.Cap())
 		}
 	})
 }
@@ -646,8 +625,8 @@ func TestSetTraceMode(t *testing.T) {
 		mode := true
 		l.SetTraceMode(mode)
 
-		if _l.traceMode != mode {
-			t.Errorf("expected the trace mode to be %v, but got %v", mode, _l.traceMode)
+		if _l.traceMode.Load() != mode {
+			t.Errorf("expected the trace mode to be %v, but got %v", mode, _l.traceMode.Load())
 		}
 	})
 
@@ -661,8 +640,8 @@ func TestSetTraceMode(t *testing.T) {
 		mode := false
 		l.SetTraceMode(mode)
 
-		if _l.traceMode != mode {
-			t.Errorf("expected the trace mode to be %v, but got %v", mode, _l.traceMode)
+		if _l.traceMode.Load() != mode {
+			t.Errorf("expected the trace mode to be %v, but got %v", mode, _l.traceMode.Load())
 		}
 	})
 }
@@ -676,7 +655,7 @@ func TestTraceMode(t *testing.T) {
 		}
 
 		mode := true
-		_l.traceMode = mode
+		_l.traceMode.Store(mode)
 
 		if l.TraceMode() != mode {
 			t.Errorf("expected the trace mode to be %v, but got %v", mode, l.TraceMode())
@@ -691,10 +670,468 @@ func TestTraceMode(t *testing.T) {
 		}
 
 		mode := false
-		_l.traceMode = mode
+		_l.traceMode.Store(mode)
 
 		if l.TraceMode() != mode {
 			t.Errorf("expected the trace mode to be %v, but got %v", mode, l.TraceMode())
 		}
 	})
 }
+
+func TestSampling(t *testing.T) {
+	r := ReportType{
+		Level:      Error,
+		Msg:        "failed",
+		CallerInfo: runtimeinfo.GetCallerInfo(1),
+	}
+
+	t.Run("should apply the sampler of the level", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+
+		l.SetLevelSampler(Error, sampler.NewFirstThenEvery(1, 3))
+		for range 4 {
+			l.Report(r)
+		}
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 entries, got %d: %q", len(lines), buf.String())
+		}
+		if strings.Contains(lines[0], "suppressed") {
+			t.Errorf("expected the first entry without suppressed field, got %q", lines[0])
+		}
+		if !strings.HasSuffix(lines[1], `"suppressed":"2"}`) {
+			t.Errorf("expected the second entry to report 2 suppressed, got %q", lines[1])
+		}
+
+		l.SetLevelSampler(Error, nil)
+		buf.Reset()
+		l.Report(r)
+		if buf.Len() == 0 {
+			t.Error("expected the entry to be written after removing the sampler")
+		}
+	})
+
+	t.Run("should prefer the sampler of the entry", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+
+		l.SetLevelSampler(Error, sampler.NewFirstThenEvery(0, 100))
+
+		sampled := r
+		sampled.Sampler = sampler.NewBurst(2, time.Hour)
+		for range 3 {
+			l.Report(sampled)
+		}
+
+		if n := strings.Count(buf.String(), "\n"); n != 2 {
+			t.Errorf("expected 2 entries, got %d", n)
+		}
+	})
+}
+
+func TestStackTrace(t *testing.T) {
+	r := ReportType{
+		Level:      Error,
+		Msg:        "failed",
+		CallerInfo: runtimeinfo.GetCallerInfo(1),
+	}
+
+	l := NewLogger()
+	buf := &bytes.Buffer{}
+	l.Writer().AddWriter(buf)
+	l.SetStackTrace(true, Error)
+
+	l.Report(r)
+
+	e, err := ParseEntry(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stack []runtimeinfo.Frame
+	if err := json.Unmarshal([]byte(e.Fields["stack"]), &stack); err != nil {
+		t.Fatalf("expected the stack to be an array of frames, got %q: %v", e.Fields["stack"], err)
+	}
+	if len(stack) == 0 || !strings.HasSuffix(stack[0].Function, "logengine.TestStackTrace") {
+		t.Errorf("expected the stack to start at the caller, got %+v", stack)
+	}
+
+	buf.Reset()
+	r.Level = Warn
+	l.Report(r)
+	if strings.Contains(buf.String(), "stack") {
+		t.Errorf("expected no stack below the level, got %q", buf.String())
+	}
+
+	buf.Reset()
+	l.SetStackTrace(false, Error)
+	r.Level = Error
+	l.Report(r)
+	if strings.Contains(buf.String(), "stack") {
+		t.Errorf("expected no stack when disabled, got %q", buf.String())
+	}
+}
+
+func TestCallerInfo(t *testing.T) {
+	pc := runtimeinfo.CallerPC(1)
+
+	t.Run("should resolve the program counter", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+
+		l.Report(ReportType{Level: Info, Msg: "hello", PC: pc})
+
+		e, err := ParseEntry(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Function != "TestCallerInfo" || e.File != "logger_test.go" || e.Line == 0 {
+			t.Errorf("expected the caller of the test, got %+v", e)
+		}
+	})
+
+	t.Run("should leave out the caller when disabled", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+
+		if !l.CallerInfoEnabled() {
+			t.Fatal("expected the caller info to be enabled by default")
+		}
+		l.SetCallerInfo(false)
+		l.Report(ReportType{Level: Info, Msg: "hello", PC: pc})
+
+		for _, key := range []string{"file", "package", "function", "line"} {
+			if strings.Contains(buf.String(), `"`+key+`"`) {
+				t.Errorf("expected no %q field, got %q", key, buf.String())
+			}
+		}
+	})
+
+	t.Run("should sample the call sites apart when disabled", func(t *testing.T) {
+		l := NewLogger()
+		buf := &bytes.Buffer{}
+		l.Writer().AddWriter(buf)
+		l.SetCallerInfo(false)
+
+		s := sampler.NewBurst(1, time.Hour)
+		l.Report(ReportType{Level: Info, Msg: "first", PC: pc, Sampler: s})
+		l.Report(ReportType{Level: Info, Msg: "second", PC: runtimeinfo.CallerPC(1), Sampler: s})
+		l.Report(ReportType{Level: Info, Msg: "first again", PC: pc, Sampler: s})
+
+		if entries := reportLines(t, buf); len(entries) != 2 || entries[0].Msg != "first" || entries[1].Msg != "second" {
+			t.Errorf("expected an entry of every call site, got %q", buf.String())
+		}
+	})
+}
+
+func TestCallerFormat(t *testing.T) {
+	l := NewLogger()
+	buf := &bytes.Buffer{}
+	l.Writer().AddWriter(buf)
+	l.SetCallerFormat(runtimeinfo.FullPackage | runtimeinfo.ModuleFile | runtimeinfo.CleanFunction)
+
+	func() {
+		l.Report(ReportType{Level: Info, Msg: "hello", PC: runtimeinfo.CallerPC(1)})
+	}()
+
+	e, err := ParseEntry(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Package != "github.com/IonicHealthUsa/ionlog/internal/core/logengine" {
+		t.Errorf("expected the full package path, got %q", e.Package)
+	}
+	if e.File != "internal/core/logengine/logger_test.go" {
+		t.Errorf("expected the module file, got %q", e.File)
+	}
+	if e.Function != "TestCallerFormat.closure" {
+		t.Errorf("expected the clean function name, got %q", e.Function)
+	}
+}
+
+func TestBatchedReports(t *testing.T) {
+	l := NewLogger()
+	l.SetReportQueueSize(1000)
+
+	var lock sync.Mutex
+	var msgs []string
+	l.Writer().AddWriter(&MockWriter{WriteFunc: func(p []byte) (int, error) {
+		e, err := ParseEntry(p)
+		if err != nil {
+			t.Errorf("expected an entry per write, got %q: %v", p, err)
+		}
+		lock.Lock()
+		msgs = append(msgs, e.Msg)
+		lock.Unlock()
+		return len(p), nil
+	}})
+
+	const count = 3*maxBatchEntries + 10
+	for i := range count {
+		r := AcquireReport()
+		r.Level = Info
+		r.Msg = fmt.Sprint(i)
+		l.Submit(r)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.HandleReports(ctx, nil)
+	l.FlushReports()
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(msgs) != count {
+		t.Fatalf("expected %d entries, got %d", count, len(msgs))
+	}
+	for i, msg := range msgs {
+		if msg != fmt.Sprint(i) {
+			t.Fatalf("expected the entries in order, got %q at %d", msg, i)
+		}
+	}
+}
+
+// handleReports runs the report goroutine of l until the returned function is called.
+func handleReports(l ILogger) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{})
+	go l.HandleReports(ctx, ready)
+	<-ready
+	return cancel
+}
+
+func TestFlushContext(t *testing.T) {
+	submit := func(l ILogger, msg string) {
+		r := AcquireReport()
+		r.Level = Info
+		r.Msg = msg
+		l.Submit(r)
+	}
+
+	t.Run("should return once the queued reports are written", func(t *testing.T) {
+		l := NewLogger()
+		var written atomic.Int64
+		l.Writer().AddWriter(&MockWriter{WriteFunc: func(p []byte) (int, error) {
+			time.Sleep(100 * time.Microsecond)
+			written.Add(1)
+			return len(p), nil
+		}})
+
+		defer handleReports(l)()
+
+		for i := range 50 {
+			submit(l, fmt.Sprint(i))
+		}
+		if err := l.FlushContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if n := written.Load(); n != 50 {
+			t.Errorf("expected 50 entries written, got %d", n)
+		}
+	})
+
+	t.Run("should return while the producers keep logging", func(t *testing.T) {
+		l := NewLogger()
+		l.Writer().AddWriter(io.Discard)
+
+		defer handleReports(l)()
+
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					submit(l, "busy")
+				}
+			}
+		}()
+
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer flushCancel()
+		if err := l.FlushContext(flushCtx); err != nil {
+			t.Errorf("expected the flush to return, got %v", err)
+		}
+	})
+
+	t.Run("should stop waiting when the context is done", func(t *testing.T) {
+		l := NewLogger()
+		w := newBlockingWriter()
+		defer close(w.release)
+		l.Writer().AddWriter(w)
+
+		defer handleReports(l)()
+
+		submit(l, "blocked")
+
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer flushCancel()
+		if err := l.FlushContext(flushCtx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the deadline to be exceeded, got %v", err)
+		}
+	})
+
+	t.Run("should write the reports without a report goroutine", func(t *testing.T) {
+		l := NewLogger()
+		buf := &mockBufferWriter{}
+		l.Writer().AddWriter(buf)
+		l.SetReportQueueSize(2)
+
+		submit(l, "first")
+		submit(l, "second")
+
+		if err := l.FlushContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(buf.String(), "\n"); n != 2 {
+			t.Errorf("expected 2 entries, got %d", n)
+		}
+	})
+
+	t.Run("should not wait for a report goroutine stopped by a panicking writer", func(t *testing.T) {
+		l := NewLogger()
+		var panicked atomic.Bool
+		l.Writer().AddWriter(&MockWriter{WriteFunc: func(p []byte) (int, error) {
+			if panicked.CompareAndSwap(false, true) {
+				panic("boom")
+			}
+			return len(p), nil
+		}})
+
+		ready, stopped := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(stopped)
+			defer func() { recover() }()
+			l.HandleReports(context.Background(), ready)
+		}()
+		<-ready
+
+		submit(l, "boom")
+
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer flushCancel()
+		if err := l.FlushContext(flushCtx); err != nil && !errors.Is(err, ErrWriterPanicked) {
+			t.Errorf("expected the flush to return, got %v", err)
+		}
+
+		<-stopped
+		if l.(*logger).handling.Load() {
+			t.Error("expected the report goroutine to be marked as stopped")
+		}
+		if err := l.FlushContext(flushCtx); err != nil {
+			t.Errorf("expected the caller to write the reports, got %v", err)
+		}
+	})
+
+	t.Run("should refuse a flush from the goroutine writing the reports", func(t *testing.T) {
+		l := NewLogger()
+		l.Writer().AddWriter(&ErrorWriter{Err: errors.New("disk full")})
+
+		flushErr := make(chan error, 1)
+		l.Writer().SetErrorHandler(func(w io.Writer, err error) {
+			flushErr <- l.FlushContext(context.Background())
+		})
+
+		defer handleReports(l)()
+		submit(l, "fails")
+
+		select {
+		case err := <-flushErr:
+			if !errors.Is(err, ErrFlushReentrant) {
+				t.Errorf("expected %v, got %v", ErrFlushReentrant, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the flush to return")
+		}
+	})
+
+	t.Run("should flush the async writers", func(t *testing.T) {
+		l := NewLogger()
+		buf := &mockBufferWriter{}
+		l.Writer().AddWriter(NewAsyncWriter(buf))
+
+		defer handleReports(l)()
+
+		submit(l, "async")
+		l.FlushReports()
+
+		if !strings.Contains(buf.String(), `"msg":"async"`) {
+			t.Errorf("expected the entry to be written by the async writer, got %q", buf.String())
+		}
+	})
+}
+
+func TestDropped(t *testing.T) {
+	submit := func(l ILogger, msg string) {
+		r := AcquireReport()
+		r.Level = Info
+		r.Msg = msg
+		l.Submit(r)
+	}
+
+	t.Run("should count the reports submitted once the reports are closed", func(t *testing.T) {
+		l := NewLogger()
+		buf := &mockBufferWriter{}
+		l.Writer().AddWriter(buf)
+
+		submit(l, "kept")
+		l.CloseReports()
+		submit(l, "late")
+
+		l.FlushReports()
+		if got := buf.String(); !strings.Contains(got, `"msg":"kept"`) || strings.Contains(got, `"msg":"late"`) {
+			t.Errorf("expected only the entry queued before the close, got %q", got)
+		}
+		if n := l.Dropped(); n != 1 {
+			t.Errorf("expected 1 dropped entry, got %d", n)
+		}
+	})
+
+	t.Run("should report the first drop once the reports are closed", func(t *testing.T) {
+		oldStderr := os.Stderr
+		defer func() { os.Stderr = oldStderr }()
+		r, w, _ := os.Pipe()
+		os.Stderr = w
+
+		l := NewLogger()
+		l.Writer().AddWriter(io.Discard)
+		l.CloseReports()
+		submit(l, "late")
+		submit(l, "later")
+		l.OpenReports()
+		l.CloseReports()
+		submit(l, "after the restart")
+
+		w.Close()
+		out, _ := io.ReadAll(r)
+		if n := strings.Count(string(out), "logger is stopped"); n != 2 {
+			t.Errorf("expected the drop reported once per close, got %q", out)
+		}
+	})
+
+	t.Run("should count the discarded reports", func(t *testing.T) {
+		l := NewLogger()
+		l.Writer().AddWriter(io.Discard)
+
+		submit(l, "first")
+		submit(l, "second")
+
+		if n := l.Discard(); n != 2 {
+			t.Errorf("expected 2 discarded entries, got %d", n)
+		}
+		if n := l.Dropped(); n != 2 {
+			t.Errorf("expected 2 dropped entries, got %d", n)
+		}
+		if n := l.(*logger).reports.Len(); n != 0 {
+			t.Errorf("expected an empty queue, got %d", n)
+		}
+	})
+}
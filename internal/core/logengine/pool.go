@@ -0,0 +1,37 @@
+package logengine
+
+import "sync"
+
+// maxPooledFields bounds the fields kept by a pooled report, so a rare entry
+// with many fields does not keep its memory forever.
+const maxPooledFields = 64
+
+var reportPool = sync.Pool{
+	New: func() any {
+		return &ReportType{Fields: make([]Field, 0, 8)}
+	},
+}
+
+// AcquireReport returns an empty report from the pool, it is handed to Submit,
+// which releases it once the entry is written.
+func AcquireReport() *ReportType {
+	return reportPool.Get().(*ReportType)
+}
+
+// ReleaseReport returns r to the pool, r must not be used after the call.
+func ReleaseReport(r *ReportType) {
+	fields := r.Fields
+	if cap(fields) > maxPooledFields {
+		fields = nil
+	}
+	clear(fields)
+
+	args := r.Args
+	if cap(args) > maxPooledFields {
+		args = nil
+	}
+	clear(args)
+
+	*r = ReportType{Fields: fields[:0], Args: args[:0]}
+	reportPool.Put(r)
+}
@@ -1,48 +1,203 @@
 package logengine
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"net"
 	"os"
 	"slices"
 	"sync"
+	"sync/atomic"
 )
 
 type ionWriter struct {
 	writeLock sync.Mutex
 	writers   []io.Writer
+	published atomic.Pointer[[]io.Writer] // a copy of the writers, read without waiting for a write
+	stats     map[io.Writer]*writerStats
+
+	staticFields map[string]string
+	bufs         net.Buffers // the entries of a batch, consumed by a vectored write
+
+	handlerLock   sync.RWMutex
+	errorHandler  func(w io.Writer, err error)
+	healthHandler func(w io.Writer, health WriterHealth)
 }
 
 type IWriter interface {
 	io.Writer
+	WriteBatch(batch []byte, entries [][]byte) error
 	AddWriter(writer ...io.Writer)
 	DeleteWriter(writer ...io.Writer)
+	Flush() error
+	Close() error
+	Shutdown() error
+	Dropped() uint64
+	Health(writer io.Writer) (WriterHealth, bool)
+	Stats() []WriterStats
+	SetErrorHandler(handler func(w io.Writer, err error))
+	SetHealthHandler(handler func(w io.Writer, health WriterHealth))
+	SetStaticFields(fields map[string]string)
+}
+
+// reporter is implemented by the writers that deliver entries on their own goroutine,
+// they report the result of each delivery through the given function.
+type reporter interface {
+	SetReporter(report func(n int, err error))
+}
+
+// staticFieldsAware is implemented by the writers that handle the static fields apart
+// from the other fields, e.g. as the resource of an OpenTelemetry exporter.
+type staticFieldsAware interface {
+	SetStaticFields(fields map[string]string)
 }
 
 func NewWriter() IWriter {
-	return &ionWriter{}
+	return &ionWriter{
+		stats: make(map[io.Writer]*writerStats),
+	}
 }
 
-// Write writes the contents of p to all writeTargets
-// This function returns no error nor the number of bytes written
+// Write writes the contents of p to all writeTargets.
+// The failures are reported to the error handler and returned joined,
+// a failed target does not prevent the others from being written.
 func (i *ionWriter) Write(p []byte) (int, error) {
+	events, errs := i.write(p)
+	i.dispatch(events...)
+
+	return len(p), errors.Join(errs...)
+}
+
+// WriteBatch writes the entries, stored one after the other in batch, to all writeTargets
+// holding the lock once. A file gets the batch in a single write and a stream connection
+// gets the entries in a single vectored write, the other targets get an entry per write.
+func (i *ionWriter) WriteBatch(batch []byte, entries [][]byte) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	events, errs := i.writeBatch(batch, entries)
+	i.dispatch(events...)
+
+	return errors.Join(errs...)
+}
+
+func (i *ionWriter) write(p []byte) ([]writerEvent, []error) {
+	i.writeLock.Lock()
+	defer i.writeLock.Unlock()
+
+	var events []writerEvent
+	var errs []error
+
+	for index, w := range i.writers {
+		if w == nil {
+			fmt.Fprintf(os.Stderr, "Expected the %v° target to be not nil\n", index+1)
+			continue
+		}
+
+		n, err := w.Write(p)
+		events, errs = i.record(w, 1, n, err, events, errs)
+	}
+
+	return events, errs
+}
+
+func (i *ionWriter) writeBatch(batch []byte, entries [][]byte) ([]writerEvent, []error) {
 	i.writeLock.Lock()
 	defer i.writeLock.Unlock()
 
+	var events []writerEvent
+	var errs []error
+
 	for index, w := range i.writers {
 		if w == nil {
 			fmt.Fprintf(os.Stderr, "Expected the %v° target to be not nil\n", index+1)
 			continue
 		}
 
-		_, err := w.Write(p)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to write to in the %v° target, error: %v\n", index+1, err)
+		if !isStream(w) {
+			for _, p := range entries {
+				n, err := w.Write(p)
+				events, errs = i.record(w, 1, n, err, events, errs)
+			}
 			continue
 		}
+
+		var n int
+		var err error
+		if _, ok := w.(*os.File); ok {
+			n, err = w.Write(batch)
+		} else {
+			i.bufs = append(i.bufs[:0], entries...)
+			var written int64
+			written, err = i.bufs.WriteTo(w)
+			n = int(written)
+		}
+		events, errs = i.record(w, len(entries), n, err, events, errs)
 	}
 
-	return 0, nil
+	return events, errs
+}
+
+// record accounts the result of a write of count entries to w.
+func (i *ionWriter) record(w io.Writer, count int, n int, err error, events []writerEvent, errs []error) ([]writerEvent, []error) {
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", WriterName(w), err))
+	}
+
+	if _, ok := w.(reporter); ok && err == nil {
+		return events, errs // the delivery is reported by the writer itself
+	}
+
+	st := i.stats[w]
+	if st == nil {
+		return events, errs
+	}
+	if ev, ok := st.recordEntries(w, count, n, err); ok {
+		events = append(events, ev)
+	}
+	return events, errs
+}
+
+// isStream reports whether w takes bytes with no boundaries, so several entries can be written
+// at once: a file, or a TCP or unix stream connection. The datagram connections take an entry per write.
+func isStream(w io.Writer) bool {
+	switch c := w.(type) {
+	case *os.File, *net.TCPConn:
+		return true
+	case *net.UnixConn:
+		return c.LocalAddr() != nil && c.LocalAddr().Network() == "unix"
+	default:
+		return false
+	}
+}
+
+// dispatch sends the events to the handlers, without holding the write lock,
+// so the handlers are free to log.
+func (i *ionWriter) dispatch(events ...writerEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	i.handlerLock.RLock()
+	errorHandler := i.errorHandler
+	healthHandler := i.healthHandler
+	i.handlerLock.RUnlock()
+
+	for _, ev := range events {
+		if ev.err != nil {
+			if errorHandler != nil {
+				errorHandler(ev.writer, ev.err)
+			} else {
+				fmt.Fprintf(os.Stderr, "Failed to write to the %q writer, error: %v\n", WriterName(ev.writer), ev.err)
+			}
+		}
+		if ev.healthChanged && healthHandler != nil {
+			healthHandler(ev.writer, ev.health)
+		}
+	}
 }
 
 func (i *ionWriter) AddWriter(writer ...io.Writer) {
@@ -54,9 +209,61 @@ func (i *ionWriter) AddWriter(writer ...io.Writer) {
 			continue
 		}
 		i.writers = append(i.writers, w)
+		i.publish()
+
+		if w == nil {
+			continue
+		}
+
+		st := &writerStats{}
+		i.stats[w] = st
+
+		for _, layer := range layers(w) {
+			if sf, ok := layer.(staticFieldsAware); ok {
+				sf.SetStaticFields(maps.Clone(i.staticFields))
+			}
+		}
+
+		if r, ok := w.(reporter); ok {
+			r.SetReporter(func(n int, err error) {
+				if ev, ok := st.record(w, n, err); ok {
+					i.dispatch(ev)
+				}
+			})
+		}
 	}
 }
 
+// Stats returns the counters of every registered writer, in the order they were added.
+func (i *ionWriter) Stats() []WriterStats {
+	i.writeLock.Lock()
+	defer i.writeLock.Unlock()
+
+	stats := make([]WriterStats, 0, len(i.writers))
+	for _, w := range i.writers {
+		if st := i.stats[w]; st != nil {
+			stats = append(stats, st.snapshot(w))
+		}
+	}
+	return stats
+}
+
+// SetErrorHandler sets the function called when a writer fails,
+// it may be called from the goroutine of an async writer.
+// When no handler is set, the failures are printed to stderr.
+func (i *ionWriter) SetErrorHandler(handler func(w io.Writer, err error)) {
+	i.handlerLock.Lock()
+	defer i.handlerLock.Unlock()
+	i.errorHandler = handler
+}
+
+// SetHealthHandler sets the function called when the health of a writer changes.
+func (i *ionWriter) SetHealthHandler(handler func(w io.Writer, health WriterHealth)) {
+	i.handlerLock.Lock()
+	defer i.handlerLock.Unlock()
+	i.healthHandler = handler
+}
+
 func (i *ionWriter) DeleteWriter(writer ...io.Writer) {
 	i.writeLock.Lock()
 	defer i.writeLock.Unlock()
@@ -64,9 +271,12 @@ func (i *ionWriter) DeleteWriter(writer ...io.Writer) {
 	for _, wd := range writer {
 		isFind := false
 		for index, w := range i.writers {
-			if wd == w {
+			if wd == w || (wd != nil && unwrap(w) == wd) {
 				isFind = true
 				i.writers = slices.Delete(i.writers, index, index+1)
+				i.publish()
+				delete(i.stats, w)
+				closeWrapper(w)
 				break
 			}
 		}
@@ -75,3 +285,175 @@ func (i *ionWriter) DeleteWriter(writer ...io.Writer) {
 		}
 	}
 }
+
+// SetStaticFields tells the writers that handle the static fields apart which they are.
+func (i *ionWriter) SetStaticFields(fields map[string]string) {
+	i.writeLock.Lock()
+	defer i.writeLock.Unlock()
+
+	i.staticFields = maps.Clone(fields)
+	for _, w := range i.writers {
+		for _, layer := range layers(w) {
+			if sf, ok := layer.(staticFieldsAware); ok {
+				sf.SetStaticFields(maps.Clone(fields))
+			}
+		}
+	}
+}
+
+// Flush flushes every writer that buffers entries, such as the async writers,
+// the wrappers are flushed before the writers they wrap.
+func (i *ionWriter) Flush() error {
+	var errs []error
+	for _, w := range i.snapshot() {
+		for _, layer := range layers(w) {
+			f, ok := layer.(interface{ Flush() error })
+			if !ok {
+				continue
+			}
+			if err := f.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close closes the wrappers created around the writers, e.g. the async writers,
+// the writers provided by the user are left open.
+func (i *ionWriter) Close() error {
+	var errs []error
+	for _, w := range i.snapshot() {
+		if err := closeWrapper(w); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Shutdown closes the wrappers, so their entries are delivered, then syncs and closes
+// the writers provided by the user that implement io.Closer, stdout and stderr are left open.
+func (i *ionWriter) Shutdown() error {
+	var errs []error
+	for _, w := range i.snapshot() {
+		all := layers(w)
+		if len(all) == 0 {
+			continue
+		}
+		for _, layer := range all[:len(all)-1] {
+			if err := closeWrapper(layer); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", WriterName(layer), err))
+			}
+		}
+
+		if err := closeTarget(all[len(all)-1]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", WriterName(w), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Dropped returns how many entries the writers lost, summed over every layer that counts them,
+// such as the async and retry writers.
+func (i *ionWriter) Dropped() uint64 {
+	var dropped uint64
+	for _, w := range i.snapshot() {
+		for _, layer := range layers(w) {
+			if d, ok := layer.(interface{ Dropped() uint64 }); ok {
+				dropped += d.Dropped()
+			}
+		}
+	}
+	return dropped
+}
+
+// Health returns the health of the given writer, it is found by
+// its own reference or by the reference of the writer it wraps.
+func (i *ionWriter) Health(writer io.Writer) (WriterHealth, bool) {
+	i.writeLock.Lock()
+	defer i.writeLock.Unlock()
+
+	for _, w := range i.writers {
+		if w != writer && (writer == nil || unwrap(w) != writer) {
+			continue
+		}
+		if h, ok := w.(interface{ Health() WriterHealth }); ok {
+			return h.Health(), true
+		}
+		return Healthy, true
+	}
+
+	return Healthy, false
+}
+
+// unwrap returns the writer wrapped by w, or nil when w is not a wrapper.
+func unwrap(w io.Writer) io.Writer {
+	u, ok := w.(interface{ Unwrap() io.Writer })
+	if !ok {
+		return nil
+	}
+	return u.Unwrap()
+}
+
+// layers returns w followed by every writer it wraps, from the outermost to the innermost.
+func layers(w io.Writer) []io.Writer {
+	var all []io.Writer
+	for w != nil && len(all) < 16 {
+		all = append(all, w)
+		w = unwrap(w)
+	}
+	return all
+}
+
+// closeWrapper closes w when it wraps another writer, such as the async and retry writers,
+// their goroutines stop but the wrapped writer is left open.
+func closeWrapper(w io.Writer) error {
+	c, ok := w.(interface {
+		Unwrap() io.Writer
+		Close() error
+	})
+	if !ok {
+		return nil
+	}
+	return c.Close()
+}
+
+// closeTarget syncs w when it is a file, and closes it when it implements io.Closer,
+// unless it is stdout or stderr.
+func closeTarget(w io.Writer) error {
+	if w == nil || w == io.Writer(os.Stdout) || w == io.Writer(os.Stderr) {
+		return nil
+	}
+
+	var errs []error
+	if s, ok := w.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+			errs = append(errs, err)
+		}
+	}
+	if c, ok := w.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// publish stores a copy of the writers, it must be called with the write lock held.
+func (i *ionWriter) publish() {
+	writers := slices.Clone(i.writers)
+	i.published.Store(&writers)
+}
+
+// snapshot returns the writers, it does not wait for a write in progress,
+// so a hung writer does not keep the others from being flushed or closed.
+func (i *ionWriter) snapshot() []io.Writer {
+	writers := i.published.Load()
+	if writers == nil {
+		return nil
+	}
+	return *writers
+}
@@ -3,8 +3,11 @@ package logengine
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"os"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -242,8 +245,8 @@ func TestWrite(t *testing.T) {
 			t.Errorf("Write returned error: %v", err)
 		}
 
-		if n != 0 {
-			t.Errorf("Expected 0 bytes written, got %d", n)
+		if n != len(testData) {
+			t.Errorf("Expected %d bytes written, got %d", len(testData), n)
 		}
 
 		if buf1.String() != string(testData) {
@@ -266,7 +269,10 @@ func TestWrite(t *testing.T) {
 		writer.AddWriter(buf, errWriter)
 
 		testData := []byte("test data")
-		_, _ = writer.Write(testData)
+		_, err := writer.Write(testData)
+		if err == nil || !strings.Contains(err.Error(), "write error") {
+			t.Errorf("Expected the write error to be returned, got: %v", err)
+		}
 
 		// Close the pipe writer to read from the pipe
 		w.Close()
@@ -276,7 +282,7 @@ func TestWrite(t *testing.T) {
 		n, _ := r.Read(errOutput)
 		errString := string(errOutput[:n])
 
-		if !strings.Contains(errString, "Failed to write to in the 2° target") {
+		if !strings.Contains(errString, "Failed to write to the \"*logengine.ErrorWriter(") {
 			t.Errorf("Expected error message for failed writer, got: %s", errString)
 		}
 
@@ -389,3 +395,436 @@ func TestInterface(t *testing.T) {
 		var _ io.Writer = &ionWriter{}
 	})
 }
+
+func TestWriterAsyncWrappers(t *testing.T) {
+	t.Run("should not delay the other writers when an async writer hangs", func(t *testing.T) {
+		w := NewWriter()
+		hung := newBlockingWriter()
+		buf := &mockBufferWriter{}
+
+		w.AddWriter(NewAsyncWriter(hung), buf)
+
+		done := make(chan struct{})
+		go func() {
+			w.Write([]byte("entry"))
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("expected the write not to wait for the hung writer")
+		}
+		if buf.String() != "entry" {
+			t.Errorf("expected buffer to be %q, but got %q", "entry", buf.String())
+		}
+
+		close(hung.release)
+		if err := w.Flush(); err != nil {
+			t.Errorf("expected no error, but got %v", err)
+		}
+		if hung.String() != "entry" {
+			t.Errorf("expected async target to be %q, but got %q", "entry", hung.String())
+		}
+		w.Close()
+	})
+
+	t.Run("should delete an async writer by the wrapped writer", func(t *testing.T) {
+		w := NewWriter().(*ionWriter)
+		buf := &bytes.Buffer{}
+		a := NewAsyncWriter(buf)
+
+		w.AddWriter(a)
+		w.DeleteWriter(buf)
+
+		if len(w.writers) != 0 {
+			t.Errorf("expected the size of writers to be 0, but got %d", len(w.writers))
+		}
+		if _, err := a.Write([]byte("late")); !errors.Is(err, ErrWriterClosed) {
+			t.Errorf("expected the deleted async writer to be closed, but got %v", err)
+		}
+	})
+
+	t.Run("should report the health of the registered writers", func(t *testing.T) {
+		w := NewWriter()
+		buf := &bytes.Buffer{}
+		failing := &ErrorWriter{Err: errors.New("write error")}
+
+		w.AddWriter(buf, NewAsyncWriter(failing))
+		w.Write([]byte("entry"))
+		w.Flush()
+
+		if h, ok := w.Health(buf); !ok || h != Healthy {
+			t.Errorf("expected %v, but got %v (found=%v)", Healthy, h, ok)
+		}
+		if h, ok := w.Health(failing); !ok || h != Degraded {
+			t.Errorf("expected %v, but got %v (found=%v)", Degraded, h, ok)
+		}
+		if _, ok := w.Health(&bytes.Buffer{}); ok {
+			t.Error("expected an unknown writer not to be found")
+		}
+		w.Close()
+	})
+}
+
+func TestWriterErrorReporting(t *testing.T) {
+	t.Run("should call the error handler with the failed writer", func(t *testing.T) {
+		w := NewWriter()
+		writeErr := errors.New("write error")
+		failing := &ErrorWriter{Err: writeErr}
+		w.AddWriter(&bytes.Buffer{}, failing)
+
+		var gotWriter io.Writer
+		var gotErr error
+		w.SetErrorHandler(func(w io.Writer, err error) {
+			gotWriter = w
+			gotErr = err
+		})
+
+		w.Write([]byte("entry"))
+
+		if gotWriter != failing {
+			t.Errorf("expected the handler to receive the failed writer, but got %v", gotWriter)
+		}
+		if !errors.Is(gotErr, writeErr) {
+			t.Errorf("expected error to be %v, but got %v", writeErr, gotErr)
+		}
+	})
+
+	t.Run("should count bytes, entries and errors per writer", func(t *testing.T) {
+		w := NewWriter()
+		buf := &bytes.Buffer{}
+		failing := NewNamedWriter("remote", &ErrorWriter{Err: errors.New("write error")})
+		w.AddWriter(buf, failing)
+		w.SetErrorHandler(func(io.Writer, error) {})
+
+		w.Write([]byte("first"))
+		w.Write([]byte("second"))
+
+		stats := w.Stats()
+		if len(stats) != 2 {
+			t.Fatalf("expected 2 stats, but got %d", len(stats))
+		}
+
+		if stats[0].Bytes != 11 || stats[0].Entries != 2 || stats[0].Errors != 0 {
+			t.Errorf("unexpected stats for the buffer: %+v", stats[0])
+		}
+		if stats[0].Health != Healthy {
+			t.Errorf("expected health to be %v, but got %v", Healthy, stats[0].Health)
+		}
+
+		if stats[1].Name != "remote" {
+			t.Errorf("expected name to be %q, but got %q", "remote", stats[1].Name)
+		}
+		if stats[1].Entries != 0 || stats[1].Errors != 2 || stats[1].LastError == nil {
+			t.Errorf("unexpected stats for the failing writer: %+v", stats[1])
+		}
+		if stats[1].Health != Degraded {
+			t.Errorf("expected health to be %v, but got %v", Degraded, stats[1].Health)
+		}
+	})
+
+	t.Run("should count the deliveries of async writers", func(t *testing.T) {
+		w := NewWriter()
+		failing := &ErrorWriter{Err: errors.New("write error")}
+		w.AddWriter(NewAsyncWriter(failing))
+
+		var lock sync.Mutex
+		var failures int
+		w.SetErrorHandler(func(io.Writer, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			failures++
+		})
+
+		w.Write([]byte("entry"))
+		w.Flush()
+
+		lock.Lock()
+		if failures != 1 {
+			t.Errorf("expected 1 failure to be reported, but got %d", failures)
+		}
+		lock.Unlock()
+
+		if stats := w.Stats(); stats[0].Errors != 1 {
+			t.Errorf("expected 1 error, but got %d", stats[0].Errors)
+		}
+		w.Close()
+	})
+
+	t.Run("should call the health handler when the health changes", func(t *testing.T) {
+		w := NewWriter()
+		mock := &MockWriter{}
+		w.AddWriter(mock)
+
+		var changes []WriterHealth
+		w.SetHealthHandler(func(_ io.Writer, h WriterHealth) {
+			changes = append(changes, h)
+		})
+		w.SetErrorHandler(func(io.Writer, error) {})
+
+		w.Write([]byte("ok"))
+		mock.WriteFunc = func(p []byte) (int, error) { return 0, errors.New("down") }
+		w.Write([]byte("fail"))
+		w.Write([]byte("fail again"))
+		mock.WriteFunc = nil
+		w.Write([]byte("ok"))
+
+		expected := []WriterHealth{Degraded, Healthy}
+		if !slices.Equal(changes, expected) {
+			t.Errorf("expected health changes to be %v, but got %v", expected, changes)
+		}
+	})
+}
+
+func TestWriterName(t *testing.T) {
+	t.Run("should use the name of named writers", func(t *testing.T) {
+		if name := WriterName(NewNamedWriter("audit", &bytes.Buffer{})); name != "audit" {
+			t.Errorf("expected name to be %q, but got %q", "audit", name)
+		}
+	})
+
+	t.Run("should use the name of files", func(t *testing.T) {
+		if name := WriterName(os.Stdout); name != os.Stdout.Name() {
+			t.Errorf("expected name to be %q, but got %q", os.Stdout.Name(), name)
+		}
+	})
+
+	t.Run("should use the name of the wrapped writer", func(t *testing.T) {
+		a := NewAsyncWriter(NewNamedWriter("slow", io.Discard))
+		defer a.Close()
+
+		if name := WriterName(a); name != "slow" {
+			t.Errorf("expected name to be %q, but got %q", "slow", name)
+		}
+	})
+
+	t.Run("should use the type and pointer otherwise", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		expected := fmt.Sprintf("*bytes.Buffer(%p)", buf)
+
+		if name := WriterName(buf); name != expected {
+			t.Errorf("expected name to be %q, but got %q", expected, name)
+		}
+	})
+}
+
+// staticFieldsWriter records the static fields it is told about
+type staticFieldsWriter struct {
+	bytes.Buffer
+	fields map[string]string
+}
+
+func (s *staticFieldsWriter) SetStaticFields(fields map[string]string) {
+	s.fields = fields
+}
+
+// flushWriter counts the flushes
+type flushWriter struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (f *flushWriter) Flush() error {
+	f.flushes++
+	return nil
+}
+
+func TestWriterLayers(t *testing.T) {
+	t.Run("should tell the static fields to the writers and to the wrapped ones", func(t *testing.T) {
+		l := NewLogger()
+		direct := &staticFieldsWriter{}
+		wrapped := &staticFieldsWriter{}
+
+		l.AddStaticFields(map[string]string{"service": "api"})
+		l.Writer().AddWriter(direct, NewNamedWriter("wrapped", wrapped))
+
+		if direct.fields["service"] != "api" || wrapped.fields["service"] != "api" {
+			t.Fatalf("expected the static fields on add, but got %v and %v", direct.fields, wrapped.fields)
+		}
+
+		l.AddStaticFields(map[string]string{"env": "prod"})
+		l.DeleteStaticField("service")
+
+		if len(direct.fields) != 1 || direct.fields["env"] != "prod" {
+			t.Errorf("expected the static fields to follow the changes, but got %v", direct.fields)
+		}
+		if len(wrapped.fields) != 1 || wrapped.fields["env"] != "prod" {
+			t.Errorf("expected the static fields to follow the changes, but got %v", wrapped.fields)
+		}
+	})
+
+	t.Run("should flush the wrapped writers", func(t *testing.T) {
+		w := NewWriter()
+		target := &flushWriter{}
+
+		w.AddWriter(NewNamedWriter("target", target))
+		if err := w.Flush(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if target.flushes != 1 {
+			t.Errorf("expected the wrapped writer to be flushed once, but got %d", target.flushes)
+		}
+	})
+}
+
+func TestWriteBatch(t *testing.T) {
+	batch := []byte("a\nbb\nccc\n")
+	entries := [][]byte{batch[0:2], batch[2:5], batch[5:9]}
+
+	t.Run("should write an entry per write to the writers that are not streams", func(t *testing.T) {
+		w := NewWriter()
+		var writes []string
+		w.AddWriter(&MockWriter{WriteFunc: func(p []byte) (int, error) {
+			writes = append(writes, string(p))
+			return len(p), nil
+		}})
+
+		if err := w.WriteBatch(batch, entries); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(writes, []string{"a\n", "bb\n", "ccc\n"}) {
+			t.Errorf("expected an entry per write, got %q", writes)
+		}
+		if st := w.Stats()[0]; st.Entries != 3 || st.Bytes != uint64(len(batch)) {
+			t.Errorf("expected 3 entries and %d bytes, got %+v", len(batch), st)
+		}
+	})
+
+	t.Run("should write the batch at once to a file", func(t *testing.T) {
+		f, err := os.CreateTemp(t.TempDir(), "batch")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		w := NewWriter()
+		w.AddWriter(f)
+		if err := w.WriteBatch(batch, entries); err != nil {
+			t.Fatal(err)
+		}
+
+		got, _ := os.ReadFile(f.Name())
+		if string(got) != string(batch) {
+			t.Errorf("expected %q, got %q", batch, got)
+		}
+		if st := w.Stats()[0]; st.Entries != 3 {
+			t.Errorf("expected 3 entries, got %+v", st)
+		}
+	})
+
+	t.Run("should write the batch to a stream connection", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Skip("no loopback network:", err)
+		}
+		defer ln.Close()
+
+		received := make(chan []byte)
+		go func() {
+			c, err := ln.Accept()
+			if err != nil {
+				close(received)
+				return
+			}
+			defer c.Close()
+			b, _ := io.ReadAll(c)
+			received <- b
+		}()
+
+		c, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isStream(c) {
+			t.Fatal("expected a TCP connection to be a stream")
+		}
+
+		w := NewWriter()
+		w.AddWriter(c)
+		if err := w.WriteBatch(batch, entries); err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+
+		if got := <-received; string(got) != string(batch) {
+			t.Errorf("expected %q, got %q", batch, got)
+		}
+	})
+
+	t.Run("should count the failed entries", func(t *testing.T) {
+		w := NewWriter()
+		w.SetErrorHandler(func(w io.Writer, err error) {})
+		w.AddWriter(&ErrorWriter{Err: errors.New("boom")})
+
+		if err := w.WriteBatch(batch, entries); err == nil {
+			t.Error("expected an error")
+		}
+		if st := w.Stats()[0]; st.Errors != 3 || st.Entries != 0 {
+			t.Errorf("expected 3 errors, got %+v", st)
+		}
+	})
+}
+
+// closeWriter records the syncs and the closes
+type closeWriter struct {
+	bytes.Buffer
+	synced bool
+	closed bool
+}
+
+func (c *closeWriter) Sync() error {
+	c.synced = true
+	return nil
+}
+
+func (c *closeWriter) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestWriterShutdown(t *testing.T) {
+	t.Run("should sync and close the writers and the wrapped ones", func(t *testing.T) {
+		w := NewWriter()
+		direct := &closeWriter{}
+		wrapped := &closeWriter{}
+		async := NewAsyncWriter(wrapped)
+
+		w.AddWriter(direct, async, os.Stdout, &MockWriter{})
+		if _, err := async.Write([]byte("queued\n")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := w.Shutdown(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if !direct.synced || !direct.closed {
+			t.Errorf("expected the writer to be synced and closed, got %+v", direct)
+		}
+		if !wrapped.synced || !wrapped.closed {
+			t.Errorf("expected the wrapped writer to be synced and closed, got %+v", wrapped)
+		}
+		if wrapped.String() != "queued\n" {
+			t.Errorf("expected the queued entry to be delivered before the close, got %q", wrapped.String())
+		}
+		if _, err := os.Stdout.Stat(); err != nil {
+			t.Errorf("expected stdout to be left open, got %v", err)
+		}
+	})
+
+	t.Run("should sum the entries dropped by the wrappers", func(t *testing.T) {
+		w := NewWriter()
+		target := newBlockingWriter()
+		defer close(target.release)
+		async := NewAsyncWriter(target, WithQueueCapacity(1), WithEnqueueTimeout(time.Millisecond))
+		w.AddWriter(async)
+
+		for range 5 {
+			_, _ = async.Write([]byte("entry\n"))
+		}
+
+		if n := w.Dropped(); n == 0 || n != async.Dropped() {
+			t.Errorf("expected the entries dropped by the async writer, got %d of %d", n, async.Dropped())
+		}
+	})
+}
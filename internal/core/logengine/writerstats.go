@@ -0,0 +1,116 @@
+package logengine
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// WriterStats is a snapshot of the counters of a registered writer.
+type WriterStats struct {
+	Name      string
+	Bytes     uint64
+	Entries   uint64
+	Errors    uint64
+	LastError error
+	Health    WriterHealth
+}
+
+type writerStats struct {
+	lock      sync.Mutex
+	bytes     uint64
+	entries   uint64
+	errors    uint64
+	lastError error
+	health    WriterHealth
+}
+
+// writerEvent is a failure or a change of health to be reported to the handlers.
+type writerEvent struct {
+	writer        io.Writer
+	err           error
+	health        WriterHealth
+	healthChanged bool
+}
+
+// namedWriter gives a writer the name used in the diagnostics.
+type namedWriter struct {
+	io.Writer
+	name string
+}
+
+// NewNamedWriter wraps w so the diagnostics identify it by name.
+func NewNamedWriter(name string, w io.Writer) io.Writer {
+	return &namedWriter{Writer: w, name: name}
+}
+
+func (n *namedWriter) Name() string {
+	return n.name
+}
+
+func (n *namedWriter) Unwrap() io.Writer {
+	return n.Writer
+}
+
+// WriterName returns the name used to identify w in the diagnostics.
+func WriterName(w io.Writer) string {
+	switch v := w.(type) {
+	case nil:
+		return "<nil>"
+	case interface{ Name() string }:
+		return v.Name()
+	case interface{ Unwrap() io.Writer }:
+		return WriterName(v.Unwrap())
+	}
+
+	if reflect.ValueOf(w).Kind() == reflect.Pointer {
+		return fmt.Sprintf("%T(%p)", w, w)
+	}
+	return fmt.Sprintf("%T", w)
+}
+
+// record accounts the result of a write and returns the event to report, if any.
+func (s *writerStats) record(w io.Writer, n int, err error) (writerEvent, bool) {
+	return s.recordEntries(w, 1, n, err)
+}
+
+// recordEntries accounts the result of a write of count entries at once.
+func (s *writerStats) recordEntries(w io.Writer, count int, n int, err error) (writerEvent, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err != nil {
+		s.errors++
+		s.lastError = err
+	} else {
+		s.entries += uint64(count)
+	}
+	s.bytes += uint64(n)
+
+	health := Healthy
+	if h, ok := w.(interface{ Health() WriterHealth }); ok {
+		health = h.Health()
+	} else if err != nil {
+		health = Degraded
+	}
+
+	changed := health != s.health
+	s.health = health
+
+	return writerEvent{writer: w, err: err, health: health, healthChanged: changed}, err != nil || changed
+}
+
+func (s *writerStats) snapshot(w io.Writer) WriterStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return WriterStats{
+		Name:      WriterName(w),
+		Bytes:     s.bytes,
+		Entries:   s.entries,
+		Errors:    s.errors,
+		LastError: s.lastError,
+		Health:    s.health,
+	}
+}
@@ -0,0 +1,10 @@
+package retrywriter
+
+import "errors"
+
+var (
+	ErrWriterClosed = errors.New("retry writer is closed")
+	ErrCircuitOpen  = errors.New("circuit breaker is open")
+	ErrUndelivered  = errors.New("entries were not delivered")
+	ErrFlushTimeout = errors.New("flush timeout")
+)
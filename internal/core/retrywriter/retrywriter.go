@@ -0,0 +1,578 @@
+package retrywriter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+	"github.com/IonicHealthUsa/ionlog/internal/core/spool"
+)
+
+// BreakerState is the state of the circuit breaker of a retry writer.
+type BreakerState int
+
+const (
+	// Closed delivers the entries to the target.
+	Closed BreakerState = iota
+	// Open holds the entries in memory until the cooldown expires.
+	Open
+	// HalfOpen tries a single entry to check if the target recovered.
+	HalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case Closed:
+		return "CLOSED"
+	case Open:
+		return "OPEN"
+	case HalfOpen:
+		return "HALF-OPEN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+const (
+	defaultMaxEntries       = 1000
+	defaultMaxBytes         = 1024 * 1024 // 1 MB
+	defaultInitialBackoff   = 100 * time.Millisecond
+	defaultMaxBackoff       = 10 * time.Second
+	defaultFailureThreshold = 5
+	defaultCooldown         = 30 * time.Second
+	defaultFlushTimeout     = 5 * time.Second
+)
+
+// retryWriter delivers the entries to the target on its own goroutine, retrying the
+// failed ones with exponential backoff. The entries wait in a bounded memory buffer
+// while the target is down and are replayed in order when it recovers.
+type retryWriter struct {
+	target io.Writer
+	dial   func() (io.Writer, error)
+
+	maxEntries       int
+	maxBytes         int
+	initialBackoff   time.Duration
+	maxBackoff       time.Duration
+	failureThreshold int
+	cooldown         time.Duration
+	flushTimeout     time.Duration
+	spool            spool.ISpool
+
+	lock      sync.Mutex
+	buffer    [][]byte
+	bytes     int
+	inFlight  bool
+	sent      int  // bytes of the entry in flight already written to the target
+	fromSpool bool // the entry in flight is the oldest of the spool
+	abandoned bool // Close gave up on the entry in flight, its result is ignored
+	state     BreakerState
+	failures  int
+	dropped   uint64
+	closed    bool
+	reporter  func(n int, err error)
+
+	// the flushes wait on changed, it is closed and replaced when the pending entries or the state change
+	flushers int
+	changed  chan struct{}
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+type IRetryWriter interface {
+	io.WriteCloser
+	Flush() error
+	State() BreakerState
+	Health() logengine.WriterHealth
+	Dropped() uint64
+	Pending() int
+	Unwrap() io.Writer
+}
+
+type Option func(r *retryWriter)
+
+// NewRetryWriter wraps target with retries, a bounded buffer and a circuit breaker.
+func NewRetryWriter(target io.Writer, opts ...Option) IRetryWriter {
+	r := &retryWriter{
+		target:           target,
+		maxEntries:       defaultMaxEntries,
+		maxBytes:         defaultMaxBytes,
+		initialBackoff:   defaultInitialBackoff,
+		maxBackoff:       defaultMaxBackoff,
+		failureThreshold: defaultFailureThreshold,
+		cooldown:         defaultCooldown,
+		flushTimeout:     defaultFlushTimeout,
+		changed:          make(chan struct{}),
+		wake:             make(chan struct{}, 1),
+		stop:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.spool != nil && r.spool.Len() > 0 {
+		r.wake <- struct{}{} // replay the entries left by a previous process
+	}
+
+	go r.run()
+
+	return r
+}
+
+// WithBuffer bounds the entries held while the target is down,
+// by number of entries and by total bytes. The oldest entries are dropped first.
+func WithBuffer(maxEntries int, maxBytes int) Option {
+	return func(r *retryWriter) {
+		r.maxEntries = maxEntries
+		r.maxBytes = maxBytes
+	}
+}
+
+// WithBackoff sets the first delay between retries, it doubles on every
+// consecutive failure up to max.
+func WithBackoff(initial time.Duration, max time.Duration) Option {
+	return func(r *retryWriter) {
+		r.initialBackoff = initial
+		r.maxBackoff = max
+	}
+}
+
+// WithBreaker trips the circuit breaker after threshold consecutive failures,
+// the target is tried again once the cooldown expires.
+func WithBreaker(threshold int, cooldown time.Duration) Option {
+	return func(r *retryWriter) {
+		r.failureThreshold = threshold
+		r.cooldown = cooldown
+	}
+}
+
+// WithFlushTimeout bounds how long Flush and Close wait for the buffer to drain.
+func WithFlushTimeout(timeout time.Duration) Option {
+	return func(r *retryWriter) {
+		r.flushTimeout = timeout
+	}
+}
+
+// WithDialer replaces the target after a failed delivery with a new one from dial, the previous
+// target is closed when it is an io.Closer. The entry in flight is then written whole again.
+func WithDialer(dial func() (io.Writer, error)) Option {
+	return func(r *retryWriter) {
+		r.dial = dial
+	}
+}
+
+// WithSpool moves the entries to a disk spool when the memory buffer is full or the
+// circuit breaker opens, the spool is closed with the writer.
+func WithSpool(s spool.ISpool) Option {
+	return func(r *retryWriter) {
+		r.spool = s
+	}
+}
+
+// Write buffers a copy of p, the delivery happens on the goroutine of the writer.
+// While the spool holds entries the new ones are appended to it, keeping the order.
+func (r *retryWriter) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.closed {
+		return 0, ErrWriterClosed
+	}
+
+	if r.spool != nil && r.spool.Len() > 0 && r.spill(p) {
+		r.signal()
+		return len(p), nil
+	}
+
+	r.buffer = append(r.buffer, slices.Clone(p))
+	r.bytes += len(p)
+
+	if r.spool != nil && (len(r.buffer) > r.maxEntries || r.bytes > r.maxBytes) {
+		r.spillBuffer()
+	}
+	r.enforceLimits()
+
+	r.signal()
+
+	return len(p), nil
+}
+
+// signal wakes the delivery goroutine.
+func (r *retryWriter) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// notify wakes the flushes waiting, it must be called with the lock held.
+func (r *retryWriter) notify() {
+	if r.flushers == 0 {
+		return
+	}
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// spill appends an entry to the spool, it reports false when the spool failed.
+func (r *retryWriter) spill(entry []byte) bool {
+	if err := r.spool.Append(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to spool the entry: %v\n", err)
+		return false
+	}
+	return true
+}
+
+// spillBuffer moves the buffered entries to the spool, in order. The entry in flight
+// stays in memory, it is older than the spooled ones and is delivered first.
+func (r *retryWriter) spillBuffer() {
+	first := 0
+	if r.inFlight && !r.fromSpool {
+		first = 1
+	}
+
+	moved := first
+	for _, entry := range r.buffer[first:] {
+		if !r.spill(entry) {
+			break
+		}
+		r.bytes -= len(entry)
+		moved++
+	}
+	r.buffer = slices.Delete(r.buffer, first, moved)
+}
+
+// enforceLimits drops the oldest entries that exceed the buffer limits,
+// the entry in flight is never dropped.
+func (r *retryWriter) enforceLimits() {
+	first := 0
+	if r.inFlight {
+		first = 1
+	}
+
+	for len(r.buffer) > first && (len(r.buffer) > r.maxEntries || r.bytes > r.maxBytes) {
+		r.bytes -= len(r.buffer[first])
+		r.buffer = slices.Delete(r.buffer, first, first+1)
+		r.dropped++
+	}
+	r.notify()
+}
+
+func (r *retryWriter) run() {
+	defer close(r.done)
+
+	backoff := r.initialBackoff
+	broken := false
+
+	for {
+		entry, sent, ok := r.next()
+		if !ok {
+			return
+		}
+
+		if r.State() == Open {
+			if !r.sleep(r.cooldown) {
+				return
+			}
+			r.setState(HalfOpen)
+		}
+
+		var n int
+		var err error
+		if broken && r.dial != nil {
+			err = r.redial()
+			sent = 0 // the new target did not receive the head of the entry
+		}
+		if err == nil {
+			n, err = r.currentTarget().Write(entry[sent:])
+			r.report(n, err)
+		} else {
+			r.report(0, err)
+		}
+
+		if err == nil {
+			r.ack()
+			backoff = r.initialBackoff
+			broken = false
+			continue
+		}
+		broken = true
+
+		if r.failed(n) {
+			continue // the breaker is open, the cooldown replaces the backoff
+		}
+
+		if !r.sleep(backoff) {
+			return
+		}
+		backoff = min(backoff*2, r.maxBackoff)
+	}
+}
+
+// next waits for an entry and marks it as in flight, with the bytes of it already written.
+// It returns false when the writer stops.
+func (r *retryWriter) next() ([]byte, int, bool) {
+	for {
+		r.lock.Lock()
+		if r.abandoned {
+			r.lock.Unlock()
+			return nil, 0, false
+		}
+		if len(r.buffer) > 0 {
+			r.inFlight = true
+			r.fromSpool = false
+			entry, sent := r.buffer[0], min(r.sent, len(r.buffer[0]))
+			r.lock.Unlock()
+			return entry, sent, true
+		}
+		if r.spool != nil {
+			entry, err := r.spool.Peek()
+			if err == nil {
+				r.inFlight = true
+				r.fromSpool = true
+				sent := min(r.sent, len(entry))
+				r.lock.Unlock()
+				return entry, sent, true
+			}
+			if !errors.Is(err, spool.ErrEmpty) {
+				fmt.Fprintf(os.Stderr, "Failed to read the spool: %v\n", err)
+			}
+		}
+		r.lock.Unlock()
+
+		select {
+		case <-r.wake:
+		case <-r.stop:
+			return nil, 0, false
+		}
+	}
+}
+
+// redial replaces the target with a new one from the dialer, closing the previous one.
+func (r *retryWriter) redial() error {
+	if c, ok := r.currentTarget().(io.Closer); ok {
+		c.Close()
+	}
+
+	target, err := r.dial()
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.target = target
+	r.sent = 0
+	return nil
+}
+
+func (r *retryWriter) currentTarget() io.Writer {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.target
+}
+
+// ack removes the delivered entry and closes the breaker.
+func (r *retryWriter) ack() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.abandoned {
+		return // the entry was moved to the spool or counted as lost by Close
+	}
+
+	if r.fromSpool {
+		if err := r.spool.Ack(); err != nil && !errors.Is(err, spool.ErrNotPeeked) { // a dropped entry was reported by the spool
+			fmt.Fprintf(os.Stderr, "Failed to remove the entry from the spool: %v\n", err)
+		}
+	} else if len(r.buffer) > 0 {
+		r.bytes -= len(r.buffer[0])
+		r.buffer = slices.Delete(r.buffer, 0, 1)
+	}
+	r.inFlight = false
+	r.fromSpool = false
+	r.sent = 0
+	r.failures = 0
+	r.state = Closed
+	r.notify()
+}
+
+// failed accounts a failed delivery that wrote n bytes and reports if the breaker tripped.
+// An entry partly written stays in flight, only its tail is written again.
+func (r *retryWriter) failed(n int) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.abandoned {
+		return false
+	}
+
+	r.sent += n
+	r.inFlight = r.sent > 0
+	r.failures++
+
+	if r.state == HalfOpen || r.failures >= r.failureThreshold {
+		r.state = Open
+		if r.spool != nil {
+			r.spillBuffer() // the target may stay down for long, keep the entries on disk
+		}
+		r.notify()
+		return true
+	}
+	return false
+}
+
+func (r *retryWriter) report(n int, err error) {
+	r.lock.Lock()
+	report := r.reporter
+	r.lock.Unlock()
+
+	if report != nil {
+		report(n, err)
+	}
+}
+
+// sleep waits for d, it returns false when the writer stops meanwhile.
+func (r *retryWriter) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.stop:
+		return false
+	}
+}
+
+func (r *retryWriter) setState(state BreakerState) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.state = state
+}
+
+// Flush waits until the buffered entries are delivered. It returns ErrCircuitOpen
+// when the target is down and ErrFlushTimeout when the buffer does not drain in time.
+func (r *retryWriter) Flush() error {
+	timer := time.NewTimer(r.flushTimeout)
+	defer timer.Stop()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.flushers++
+	defer func() { r.flushers-- }()
+
+	for {
+		if r.pending() == 0 {
+			return nil
+		}
+		if r.state == Open {
+			return ErrCircuitOpen
+		}
+
+		changed := r.changed
+		r.lock.Unlock()
+		select {
+		case <-changed:
+		case <-timer.C:
+			r.lock.Lock()
+			return ErrFlushTimeout
+		}
+		r.lock.Lock()
+	}
+}
+
+// Close tries to deliver the buffered entries and stops the writer, the entries
+// not delivered are kept in the spool when there is one. The target itself is not closed.
+func (r *retryWriter) Close() error {
+	r.lock.Lock()
+	if r.closed {
+		r.lock.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.lock.Unlock()
+
+	flushErr := r.Flush()
+
+	close(r.stop)
+	stopped := true
+	select {
+	case <-r.done:
+	case <-time.After(r.flushTimeout):
+		stopped = false // the target still holds the entry in flight
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.abandoned = !stopped
+
+	var spoolErr error
+	if r.spool != nil {
+		r.inFlight = false
+		r.spillBuffer() // delivered by the next process
+		spoolErr = r.spool.Close()
+	}
+
+	if lost := len(r.buffer); lost > 0 {
+		return errors.Join(fmt.Errorf("%w: %d entries lost", ErrUndelivered, lost), flushErr, spoolErr)
+	}
+	return spoolErr
+}
+
+// pending counts the entries in memory and in the spool, it must be called with the lock held.
+func (r *retryWriter) pending() int {
+	n := len(r.buffer)
+	if r.spool != nil {
+		n += r.spool.Len()
+	}
+	return n
+}
+
+func (r *retryWriter) State() BreakerState {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.state
+}
+
+func (r *retryWriter) Health() logengine.WriterHealth {
+	if r.State() == Closed {
+		return logengine.Healthy
+	}
+	return logengine.Degraded
+}
+
+// SetReporter sets the function called with the result of every delivery attempt.
+func (r *retryWriter) SetReporter(report func(n int, err error)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.reporter = report
+}
+
+func (r *retryWriter) Dropped() uint64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.dropped
+}
+
+// Pending returns how many entries wait to be delivered, in memory and in the spool.
+func (r *retryWriter) Pending() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.pending()
+}
+
+func (r *retryWriter) Unwrap() io.Writer {
+	return r.currentTarget()
+}
@@ -0,0 +1,476 @@
+package retrywriter
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+	"github.com/IonicHealthUsa/ionlog/internal/core/spool"
+)
+
+// flakyWriter fails every write while down is true
+type flakyWriter struct {
+	lock     sync.Mutex
+	down     bool
+	attempts int
+	lines    []string
+}
+
+func (f *flakyWriter) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.attempts++
+	if f.down {
+		return 0, errors.New("connection refused")
+	}
+	f.lines = append(f.lines, string(p))
+	return len(p), nil
+}
+
+func (f *flakyWriter) setDown(down bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.down = down
+}
+
+func (f *flakyWriter) Attempts() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.attempts
+}
+
+func (f *flakyWriter) Lines() string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return strings.Join(f.lines, "")
+}
+
+// partialWriter writes half of the first entry and fails, then writes everything
+type partialWriter struct {
+	lock   sync.Mutex
+	failed bool
+	data   strings.Builder
+}
+
+func (p *partialWriter) Write(b []byte) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.failed {
+		p.failed = true
+		p.data.Write(b[:len(b)/2])
+		return len(b) / 2, errors.New("connection reset")
+	}
+	p.data.Write(b)
+	return len(b), nil
+}
+
+func (p *partialWriter) String() string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.data.String()
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached before the deadline")
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+}
+
+func TestNewRetryWriter(t *testing.T) {
+	t.Run("should apply the options", func(t *testing.T) {
+		r := NewRetryWriter(io.Discard,
+			WithBuffer(10, 100),
+			WithBackoff(time.Millisecond, time.Second),
+			WithBreaker(3, time.Minute),
+			WithFlushTimeout(time.Hour),
+		).(*retryWriter)
+		defer r.Close()
+
+		if r.maxEntries != 10 || r.maxBytes != 100 {
+			t.Errorf("unexpected buffer limits: %d entries, %d bytes", r.maxEntries, r.maxBytes)
+		}
+		if r.initialBackoff != time.Millisecond || r.maxBackoff != time.Second {
+			t.Errorf("unexpected backoff: %v, %v", r.initialBackoff, r.maxBackoff)
+		}
+		if r.failureThreshold != 3 || r.cooldown != time.Minute {
+			t.Errorf("unexpected breaker: %d, %v", r.failureThreshold, r.cooldown)
+		}
+		if r.flushTimeout != time.Hour {
+			t.Errorf("unexpected flush timeout: %v", r.flushTimeout)
+		}
+		if r.Unwrap() != io.Discard {
+			t.Error("expected Unwrap to return the target")
+		}
+	})
+}
+
+func TestRetryWriterWrite(t *testing.T) {
+	t.Run("should deliver the entries in order", func(t *testing.T) {
+		target := &flakyWriter{}
+		r := NewRetryWriter(target)
+		defer r.Close()
+
+		for _, s := range []string{"a", "b", "c"} {
+			if _, err := r.Write([]byte(s)); err != nil {
+				t.Errorf("expected no error, but got %v", err)
+			}
+		}
+
+		if err := r.Flush(); err != nil {
+			t.Errorf("expected no error, but got %v", err)
+		}
+		if target.Lines() != "abc" {
+			t.Errorf("expected target to be %q, but got %q", "abc", target.Lines())
+		}
+	})
+
+	t.Run("should retry a failed entry until it is delivered", func(t *testing.T) {
+		target := &flakyWriter{down: true}
+		r := NewRetryWriter(target, WithBackoff(time.Millisecond, 2*time.Millisecond), WithBreaker(100, time.Hour))
+		defer r.Close()
+
+		r.Write([]byte("a"))
+		waitFor(t, func() bool { return target.Attempts() >= 3 })
+		target.setDown(false)
+
+		if err := r.Flush(); err != nil {
+			t.Errorf("expected no error, but got %v", err)
+		}
+		if target.Lines() != "a" {
+			t.Errorf("expected target to be %q, but got %q", "a", target.Lines())
+		}
+	})
+
+	t.Run("should drop the oldest entries when the buffer is full", func(t *testing.T) {
+		target := &flakyWriter{down: true}
+		r := NewRetryWriter(target, WithBuffer(2, 1024), WithBreaker(1, time.Hour))
+
+		r.Write([]byte("1"))
+		waitFor(t, func() bool { return r.State() == Open })
+
+		r.Write([]byte("2"))
+		r.Write([]byte("3"))
+		r.Write([]byte("4"))
+
+		if r.Dropped() != 2 {
+			t.Errorf("expected 2 dropped entries, but got %d", r.Dropped())
+		}
+		if r.Pending() != 2 {
+			t.Errorf("expected 2 pending entries, but got %d", r.Pending())
+		}
+		r.Close()
+	})
+
+	t.Run("should drop the oldest entries when the buffer exceeds the bytes", func(t *testing.T) {
+		target := &flakyWriter{down: true}
+		r := NewRetryWriter(target, WithBuffer(100, 4), WithBreaker(1, time.Hour))
+
+		r.Write([]byte("aa"))
+		waitFor(t, func() bool { return r.State() == Open })
+		r.Write([]byte("bb"))
+		r.Write([]byte("cc"))
+
+		if r.Pending() != 2 {
+			t.Errorf("expected 2 pending entries, but got %d", r.Pending())
+		}
+		r.Close()
+	})
+
+	t.Run("should write only the tail of a partly written entry", func(t *testing.T) {
+		target := &partialWriter{}
+		r := NewRetryWriter(target, WithBackoff(time.Millisecond, time.Millisecond))
+		defer r.Close()
+
+		r.Write([]byte("abcdef"))
+		if err := r.Flush(); err != nil {
+			t.Errorf("expected no error, but got %v", err)
+		}
+		if target.String() != "abcdef" {
+			t.Errorf("expected target to be %q, but got %q", "abcdef", target.String())
+		}
+	})
+
+	t.Run("should dial a new target after a failure", func(t *testing.T) {
+		broken := &flakyWriter{down: true}
+		dialed := &flakyWriter{}
+		r := NewRetryWriter(broken,
+			WithBackoff(time.Millisecond, time.Millisecond),
+			WithDialer(func() (io.Writer, error) { return dialed, nil }),
+		)
+		defer r.Close()
+
+		r.Write([]byte("a"))
+		if err := r.Flush(); err != nil {
+			t.Errorf("expected no error, but got %v", err)
+		}
+		if dialed.Lines() != "a" {
+			t.Errorf("expected the entry on the new target, but got %q", dialed.Lines())
+		}
+		if r.Unwrap() != dialed {
+			t.Error("expected the new target to replace the broken one")
+		}
+	})
+
+	t.Run("should refuse entries after close", func(t *testing.T) {
+		r := NewRetryWriter(io.Discard)
+		r.Close()
+
+		if _, err := r.Write([]byte("late")); !errors.Is(err, ErrWriterClosed) {
+			t.Errorf("expected error to be %v, but got %v", ErrWriterClosed, err)
+		}
+		if err := r.Close(); err != nil {
+			t.Errorf("expected close to be idempotent, but got %v", err)
+		}
+	})
+}
+
+func TestRetryWriterBreaker(t *testing.T) {
+	t.Run("should open after consecutive failures and replay once recovered", func(t *testing.T) {
+		target := &flakyWriter{down: true}
+		r := NewRetryWriter(target, WithBackoff(time.Millisecond, time.Millisecond), WithBreaker(2, 20*time.Millisecond))
+		defer r.Close()
+
+		r.Write([]byte("1"))
+		waitFor(t, func() bool { return r.State() == Open })
+
+		if target.Attempts() != 2 {
+			t.Errorf("expected 2 attempts before opening, but got %d", target.Attempts())
+		}
+		if r.Health() != logengine.Degraded {
+			t.Errorf("expected health to be %v, but got %v", logengine.Degraded, r.Health())
+		}
+		if err := r.Flush(); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("expected error to be %v, but got %v", ErrCircuitOpen, err)
+		}
+
+		r.Write([]byte("2"))
+		r.Write([]byte("3"))
+		target.setDown(false)
+
+		waitFor(t, func() bool { return r.Pending() == 0 })
+
+		if target.Lines() != "123" {
+			t.Errorf("expected the entries replayed in order, but got %q", target.Lines())
+		}
+		if r.State() != Closed {
+			t.Errorf("expected state to be %v, but got %v", Closed, r.State())
+		}
+		if r.Health() != logengine.Healthy {
+			t.Errorf("expected health to be %v, but got %v", logengine.Healthy, r.Health())
+		}
+	})
+
+	t.Run("should open again when the half open attempt fails", func(t *testing.T) {
+		target := &flakyWriter{down: true}
+		r := NewRetryWriter(target, WithBackoff(time.Millisecond, time.Millisecond), WithBreaker(1, 5*time.Millisecond))
+		defer r.Close()
+
+		r.Write([]byte("1"))
+		waitFor(t, func() bool { return target.Attempts() >= 3 })
+
+		if r.State() == Closed {
+			t.Errorf("expected the breaker to remain open")
+		}
+	})
+
+	t.Run("should report the entries lost on close", func(t *testing.T) {
+		target := &flakyWriter{down: true}
+		r := NewRetryWriter(target, WithBreaker(1, time.Hour))
+
+		r.Write([]byte("1"))
+		waitFor(t, func() bool { return r.State() == Open })
+		r.Write([]byte("2"))
+
+		if err := r.Close(); !errors.Is(err, ErrUndelivered) {
+			t.Errorf("expected error to be %v, but got %v", ErrUndelivered, err)
+		}
+	})
+}
+
+func TestRetryWriterReporter(t *testing.T) {
+	t.Run("should report every delivery attempt", func(t *testing.T) {
+		target := &flakyWriter{down: true}
+		r := NewRetryWriter(target, WithBackoff(time.Millisecond, time.Millisecond), WithBreaker(100, time.Hour))
+		defer r.Close()
+
+		var lock sync.Mutex
+		var failures, successes int
+		r.(*retryWriter).SetReporter(func(n int, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				failures++
+			} else {
+				successes++
+			}
+		})
+
+		r.Write([]byte("1"))
+		waitFor(t, func() bool { return target.Attempts() >= 2 })
+		target.setDown(false)
+		r.Flush()
+
+		lock.Lock()
+		defer lock.Unlock()
+		if failures < 1 || successes != 1 {
+			t.Errorf("expected failures and one success, but got %d failures and %d successes", failures, successes)
+		}
+	})
+}
+
+func TestBreakerStateString(t *testing.T) {
+	cases := map[BreakerState]string{
+		Closed:          "CLOSED",
+		Open:            "OPEN",
+		HalfOpen:        "HALF-OPEN",
+		BreakerState(9): "UNKNOWN",
+	}
+	for s, expected := range cases {
+		if s.String() != expected {
+			t.Errorf("expected %q, but got %q", expected, s.String())
+		}
+	}
+}
+
+func TestRetryWriterSpool(t *testing.T) {
+	openSpool := func(t *testing.T, folder string) spool.ISpool {
+		t.Helper()
+		s, err := spool.NewSpool(folder)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		return s
+	}
+
+	t.Run("should spool the entries when the memory buffer is full", func(t *testing.T) {
+		target := &flakyWriter{down: true}
+		s := openSpool(t, t.TempDir())
+
+		r := NewRetryWriter(target,
+			WithBuffer(2, 1024),
+			WithBackoff(time.Millisecond, time.Millisecond),
+			WithBreaker(1000, time.Hour),
+			WithSpool(s),
+		)
+		defer r.Close()
+
+		for _, e := range []string{"a", "b", "c", "d", "e"} {
+			r.Write([]byte(e))
+		}
+
+		if r.Dropped() != 0 {
+			t.Errorf("expected no dropped entries, but got %d", r.Dropped())
+		}
+		if s.Len() == 0 {
+			t.Error("expected the entries to be spooled")
+		}
+
+		target.setDown(false)
+		if err := r.Flush(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if target.Lines() != "abcde" {
+			t.Errorf("expected the entries in order, but got %q", target.Lines())
+		}
+	})
+
+	t.Run("should spool the buffer when the breaker opens", func(t *testing.T) {
+		target := &flakyWriter{down: true}
+		s := openSpool(t, t.TempDir())
+
+		r := NewRetryWriter(target,
+			WithBackoff(time.Millisecond, time.Millisecond),
+			WithBreaker(2, 20*time.Millisecond),
+			WithSpool(s),
+		)
+		defer r.Close()
+
+		r.Write([]byte("a"))
+		r.Write([]byte("b"))
+		r.Write([]byte("c"))
+		waitFor(t, func() bool { return r.State() == Open })
+		waitFor(t, func() bool { return s.Len() >= 2 })
+
+		r.Write([]byte("d")) // after the spooled ones
+
+		target.setDown(false)
+		waitFor(t, func() bool { return r.Pending() == 0 })
+		if target.Lines() != "abcd" {
+			t.Errorf("expected the entries in order, but got %q", target.Lines())
+		}
+	})
+
+	t.Run("should replay the spool after a restart", func(t *testing.T) {
+		folder := t.TempDir()
+		down := &flakyWriter{down: true}
+
+		r := NewRetryWriter(down,
+			WithBackoff(time.Millisecond, time.Millisecond),
+			WithBreaker(1, time.Hour),
+			WithFlushTimeout(50*time.Millisecond),
+			WithSpool(openSpool(t, folder)),
+		)
+		r.Write([]byte("a"))
+		r.Write([]byte("b"))
+		waitFor(t, func() bool { return r.State() == Open })
+
+		if err := r.Close(); err != nil {
+			t.Fatalf("expected the entries to be kept in the spool, but got %v", err)
+		}
+
+		up := &flakyWriter{}
+		r = NewRetryWriter(up, WithSpool(openSpool(t, folder)))
+		defer r.Close()
+
+		r.Write([]byte("c"))
+		if err := r.Flush(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if up.Lines() != "abc" {
+			t.Errorf("expected the spooled entries before the new one, but got %q", up.Lines())
+		}
+	})
+	t.Run("should ignore a write that completes after the close", func(t *testing.T) {
+		release := make(chan struct{})
+		target := &blockingWriter{release: release, started: make(chan struct{}, 1)}
+
+		r := NewRetryWriter(target,
+			WithFlushTimeout(20*time.Millisecond),
+			WithSpool(openSpool(t, t.TempDir())),
+		)
+		r.Write([]byte("a"))
+		<-target.started
+
+		if err := r.Close(); err != nil {
+			t.Fatalf("expected the entry to be kept in the spool, but got %v", err)
+		}
+		close(release) // the late success must not touch the spilled buffer
+		time.Sleep(10 * time.Millisecond)
+	})
+}
+
+// blockingWriter holds every write until release is closed
+type blockingWriter struct {
+	release chan struct{}
+	started chan struct{}
+}
+
+func (b *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case b.started <- struct{}{}:
+	default:
+	}
+	<-b.release
+	return len(p), nil
+}
@@ -0,0 +1,255 @@
+package ringbuffer
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// sealedBit marks the tail of a ring replaced by a resize, it takes no more values.
+const sealedBit = 1 << 63
+
+// cacheLine pads the counters written by the producers apart from the ones written by the consumers.
+type cacheLine [64]byte
+
+type IRingBuffer[T any] interface {
+	// Push adds v, it reports false when the buffer is full.
+	Push(v T) bool
+	// PushWait adds v, waiting for room in the buffer, it reports false when ctx is done first.
+	PushWait(ctx context.Context, v T) bool
+	// Pop removes the oldest value, it reports false when the buffer is empty.
+	Pop() (T, bool)
+	// Wait blocks until a value may be available, it reports false when ctx is done.
+	Wait(ctx context.Context) bool
+	// Len returns the number of values in the buffer.
+	Len() int
+	// Cap returns the number of values the buffer holds.
+	Cap() int
+	// Resize replaces the buffer by one that holds size values, the values already
+	// in the buffer are kept and popped before the new ones.
+	Resize(size uint)
+}
+
+// ringBuffer is a bounded queue without locks for many producers and consumers.
+// A resize links a new ring after the current one: the producers move to the new ring
+// and the consumers move to it once the old one is empty.
+type ringBuffer[T any] struct {
+	tail atomic.Pointer[ring[T]] // where the producers push
+	head atomic.Pointer[ring[T]] // where the consumers pop
+
+	waiting atomic.Int32 // the consumers waiting on notify
+	notify  chan struct{}
+
+	blocked atomic.Int32 // the producers waiting on room for a full buffer
+	room    chan struct{}
+
+	resizeLock sync.Mutex
+}
+
+// NewRingBuffer returns a buffer that holds size values, at least two.
+func NewRingBuffer[T any](size uint) IRingBuffer[T] {
+	r := newRing[T](size)
+
+	b := &ringBuffer[T]{
+		notify: make(chan struct{}, 1),
+		room:   make(chan struct{}, 1),
+	}
+	b.tail.Store(r)
+	b.head.Store(r)
+	return b
+}
+
+func (b *ringBuffer[T]) Push(v T) bool {
+	for {
+		ok, sealed := b.tail.Load().push(v)
+		if sealed {
+			continue // the ring was replaced, the new one is already in place
+		}
+		// sending to a full channel without blocking does not take its lock
+		if ok && b.waiting.Load() > 0 {
+			select {
+			case b.notify <- struct{}{}:
+			default:
+			}
+		}
+		return ok
+	}
+}
+
+func (b *ringBuffer[T]) PushWait(ctx context.Context, v T) bool {
+	if b.Push(v) {
+		return true
+	}
+
+	// a consumer that pops after the retry sees the blocked producer and notifies it
+	b.blocked.Add(1)
+	defer b.blocked.Add(-1)
+	for !b.Push(v) {
+		select {
+		case <-b.room:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+func (b *ringBuffer[T]) Pop() (T, bool) {
+	for {
+		r := b.head.Load()
+		if v, ok := r.pop(); ok {
+			if b.blocked.Load() > 0 {
+				select {
+				case b.room <- struct{}{}:
+				default:
+				}
+			}
+			return v, true
+		}
+
+		next := r.next.Load()
+		if next == nil || !r.drained() {
+			var zero T
+			return zero, false
+		}
+		b.head.CompareAndSwap(r, next)
+	}
+}
+
+func (b *ringBuffer[T]) Wait(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	// a producer that pushes after the check sees the waiting consumer and notifies it
+	b.waiting.Add(1)
+	defer b.waiting.Add(-1)
+	if b.Len() > 0 {
+		return true
+	}
+
+	select {
+	case <-b.notify:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (b *ringBuffer[T]) Len() int {
+	n := 0
+	for r := b.head.Load(); r != nil; r = r.next.Load() {
+		n += r.len()
+	}
+	return n
+}
+
+func (b *ringBuffer[T]) Cap() int {
+	return len(b.tail.Load().cells)
+}
+
+func (b *ringBuffer[T]) Resize(size uint) {
+	b.resizeLock.Lock()
+	defer b.resizeLock.Unlock()
+
+	old := b.tail.Load()
+	r := newRing[T](size)
+	old.next.Store(r)
+	b.tail.Store(r)
+	old.tail.Or(sealedBit)
+
+	// the blocked producers have room in the new ring
+	select {
+	case b.room <- struct{}{}:
+	default:
+	}
+}
+
+// ring is a bounded queue of cells whose sequences tell the producers and
+// consumers when a cell is free or holds a value, as in the queue of Dmitry Vyukov.
+type ring[T any] struct {
+	_    cacheLine
+	tail atomic.Uint64
+	_    cacheLine
+	head atomic.Uint64
+	_    cacheLine
+
+	size  uint64
+	cells []cell[T]
+	next  atomic.Pointer[ring[T]]
+}
+
+type cell[T any] struct {
+	seq   atomic.Uint64
+	value T
+}
+
+func newRing[T any](size uint) *ring[T] {
+	n := max(uint64(size), 2)
+
+	r := &ring[T]{size: n, cells: make([]cell[T], n)}
+	for i := range r.cells {
+		r.cells[i].seq.Store(uint64(i))
+	}
+	return r
+}
+
+// push adds v, it reports whether v was added or the ring is sealed.
+func (r *ring[T]) push(v T) (ok bool, sealed bool) {
+	for {
+		pos := r.tail.Load()
+		if pos&sealedBit != 0 {
+			return false, true
+		}
+
+		c := &r.cells[pos%r.size]
+		switch dif := int64(c.seq.Load() - pos); {
+		case dif == 0:
+			if r.tail.CompareAndSwap(pos, pos+1) {
+				c.value = v
+				c.seq.Store(pos + 1)
+				return true, false
+			}
+		case dif < 0:
+			return false, false // full
+		}
+	}
+}
+
+// pop removes the oldest value, it reports false when there is none ready.
+func (r *ring[T]) pop() (T, bool) {
+	var zero T
+	for {
+		pos := r.head.Load()
+
+		c := &r.cells[pos%r.size]
+		switch dif := int64(c.seq.Load() - (pos + 1)); {
+		case dif == 0:
+			if r.head.CompareAndSwap(pos, pos+1) {
+				v := c.value
+				c.value = zero
+				c.seq.Store(pos + r.size)
+				return v, true
+			}
+		case dif < 0:
+			return zero, false // empty, or the value is being pushed
+		}
+	}
+}
+
+// drained reports whether the ring is sealed and every value pushed was popped.
+func (r *ring[T]) drained() bool {
+	tail := r.tail.Load()
+	return tail&sealedBit != 0 && r.head.Load() == tail&^sealedBit
+}
+
+// len returns the number of values pushed and not popped yet, the head is loaded first:
+// a tail loaded first would be stale after values were pushed and popped, and a full ring would look empty.
+func (r *ring[T]) len() int {
+	head := r.head.Load()
+	tail := r.tail.Load() &^ sealedBit
+	if head >= tail {
+		return 0
+	}
+	return int(tail - head)
+}
@@ -0,0 +1,215 @@
+package ringbuffer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewRingBuffer(t *testing.T) {
+	sizes := map[uint]int{0: 2, 1: 2, 2: 2, 3: 3, 100: 100, 1024: 1024}
+	for size, want := range sizes {
+		if got := NewRingBuffer[int](size).Cap(); got != want {
+			t.Errorf("expected the capacity of %d to be %d, but got %d", size, want, got)
+		}
+	}
+}
+
+func TestPushPop(t *testing.T) {
+	b := NewRingBuffer[int](4)
+
+	for i := range 4 {
+		if !b.Push(i) {
+			t.Fatalf("expected to push %d", i)
+		}
+	}
+	if b.Push(4) {
+		t.Error("expected the push to fail when the buffer is full")
+	}
+	if b.Len() != 4 {
+		t.Errorf("expected 4 values, but got %d", b.Len())
+	}
+
+	for i := range 4 {
+		if v, ok := b.Pop(); !ok || v != i {
+			t.Errorf("expected to pop %d, but got %d, %v", i, v, ok)
+		}
+	}
+	if _, ok := b.Pop(); ok {
+		t.Error("expected the pop to fail when the buffer is empty")
+	}
+
+	// the positions wrap around the ring
+	for i := range 10 {
+		b.Push(i)
+		if v, _ := b.Pop(); v != i {
+			t.Errorf("expected to pop %d, but got %d", i, v)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	t.Run("should keep the pending values in order", func(t *testing.T) {
+		b := NewRingBuffer[int](2)
+		b.Push(0)
+		b.Push(1)
+
+		b.Resize(4)
+		b.Push(2)
+		b.Resize(8)
+		b.Push(3)
+
+		if b.Cap() != 8 {
+			t.Errorf("expected the capacity to be 8, but got %d", b.Cap())
+		}
+		if b.Len() != 4 {
+			t.Errorf("expected 4 values, but got %d", b.Len())
+		}
+		for i := range 4 {
+			if v, ok := b.Pop(); !ok || v != i {
+				t.Errorf("expected to pop %d, but got %d, %v", i, v, ok)
+			}
+		}
+	})
+
+	t.Run("should not lose values pushed while resizing", func(t *testing.T) {
+		b := NewRingBuffer[int](1024)
+		const producers, count = 4, 10000
+
+		var wg sync.WaitGroup
+		for p := range producers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range count {
+					b.PushWait(context.Background(), p*count+i)
+				}
+			}()
+		}
+
+		done := make(chan struct{})
+		go func() {
+			for i := range 20 {
+				b.Resize(uint(512 + i*64))
+			}
+			close(done)
+		}()
+
+		seen := make(map[int]bool)
+		last := make([]int, producers)
+		for i := range last {
+			last[i] = -1
+		}
+		for len(seen) < producers*count {
+			v, ok := b.Pop()
+			if !ok {
+				b.Wait(context.Background())
+				continue
+			}
+			if seen[v] {
+				t.Fatalf("value %d popped twice", v)
+			}
+			seen[v] = true
+
+			// the values of a producer keep its order
+			p, i := v/count, v%count
+			if i <= last[p] {
+				t.Fatalf("value %d of producer %d popped after %d", i, p, last[p])
+			}
+			last[p] = i
+		}
+		wg.Wait()
+		<-done
+	})
+}
+
+func TestWait(t *testing.T) {
+	t.Run("should wake up when a value is pushed", func(t *testing.T) {
+		b := NewRingBuffer[int](4)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			b.Push(1)
+		}()
+
+		if !b.Wait(context.Background()) {
+			t.Error("expected the wait to succeed")
+		}
+		if v, ok := b.Pop(); !ok || v != 1 {
+			t.Errorf("expected to pop 1, but got %d, %v", v, ok)
+		}
+	})
+
+	t.Run("should return when the context is done", func(t *testing.T) {
+		b := NewRingBuffer[int](4)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		if b.Wait(ctx) {
+			t.Error("expected the wait to fail")
+		}
+	})
+}
+
+func TestPushWait(t *testing.T) {
+	t.Run("should wait for room in the buffer", func(t *testing.T) {
+		b := NewRingBuffer[int](2)
+		b.Push(0)
+		b.Push(1)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			b.Pop()
+		}()
+
+		if !b.PushWait(context.Background(), 2) {
+			t.Fatal("expected the push to succeed")
+		}
+		for _, want := range []int{1, 2} {
+			if v, ok := b.Pop(); !ok || v != want {
+				t.Errorf("expected to pop %d, but got %d, %v", want, v, ok)
+			}
+		}
+	})
+
+	t.Run("should return when the context is done", func(t *testing.T) {
+		b := NewRingBuffer[int](2)
+		b.Push(0)
+		b.Push(1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		if b.PushWait(ctx, 2) {
+			t.Error("expected the push to fail")
+		}
+		if b.Len() != 2 {
+			t.Errorf("expected 2 values, but got %d", b.Len())
+		}
+	})
+}
+
+func TestWaitConsumers(t *testing.T) {
+	b := NewRingBuffer[int](4)
+
+	woken := make(chan bool)
+	go func() {
+		woken <- b.Wait(context.Background())
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// another consumer gives up waiting while the first one still waits
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	b.Wait(ctx)
+
+	b.Push(1)
+	select {
+	case ok := <-woken:
+		if !ok {
+			t.Error("expected the wait to succeed")
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the waiting consumer to be notified")
+	}
+}
@@ -0,0 +1,35 @@
+package runtimeinfo
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// helpers holds the names of the functions marked as helpers,
+// they are skipped when resolving the caller.
+var (
+	helpers     sync.Map
+	helperCount atomic.Int64
+)
+
+// MarkHelper marks the function skip frames above the caller of MarkHelper as a helper,
+// skip is counted as in GetCallerInfo.
+func MarkHelper(skip int) {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return
+	}
+	if _, loaded := helpers.LoadOrStore(runtime.FuncForPC(pc).Name(), struct{}{}); !loaded {
+		helperCount.Add(1)
+	}
+}
+
+// IsHelper reports whether the function was marked as a helper.
+func IsHelper(function string) bool {
+	if helperCount.Load() == 0 {
+		return false
+	}
+	_, ok := helpers.Load(function)
+	return ok
+}
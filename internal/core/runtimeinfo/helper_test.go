@@ -0,0 +1,48 @@
+package runtimeinfo
+
+import (
+	"strings"
+	"testing"
+)
+
+// logFromHelper is marked as a helper, it resolves the caller as a logger would.
+func logFromHelper() CallerInfo {
+	MarkHelper(1)
+	return GetCallerInfo(1)
+}
+
+// logFromNestedHelper is a helper that calls another helper.
+func logFromNestedHelper() CallerInfo {
+	MarkHelper(1)
+	return logFromHelper()
+}
+
+func TestMarkHelper(t *testing.T) {
+	info := logFromHelper()
+	if info.Function != "TestMarkHelper" {
+		t.Errorf("expected the helper to be skipped, got %q", info.Function)
+	}
+
+	info = logFromNestedHelper()
+	if info.Function != "TestMarkHelper" {
+		t.Errorf("expected the nested helpers to be skipped, got %q", info.Function)
+	}
+
+	if !IsHelper("github.com/IonicHealthUsa/ionlog/internal/core/runtimeinfo.logFromHelper") {
+		t.Error("expected the function to be marked as a helper")
+	}
+	if IsHelper("github.com/IonicHealthUsa/ionlog/internal/core/runtimeinfo.TestMarkHelper") {
+		t.Error("expected the test to not be a helper")
+	}
+}
+
+func TestTrimHelpers(t *testing.T) {
+	stack := func() []Frame {
+		MarkHelper(1)
+		return TrimInternal(Stack(1))
+	}()
+
+	if len(stack) == 0 || !strings.HasSuffix(stack[0].Function, "TestTrimHelpers") {
+		t.Errorf("expected the stack to start at the caller of the helper, got %+v", stack)
+	}
+}
@@ -0,0 +1,178 @@
+package runtimeinfo
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// Format selects how the caller is written, the zero value writes
+// the base name of the file, the last element of the package and the raw function name.
+type Format int
+
+const (
+	// FullPackage writes the import path of the package, e.g. "example.com/app/internal/db".
+	FullPackage Format = 1 << iota
+	// ModuleFile writes the file relative to the root of its module, e.g. "internal/db/db.go".
+	ModuleFile
+	// CleanFunction writes methods as "Type.Method", without the type parameters
+	// of generic instantiations and with the closures as "Func.closure".
+	CleanFunction
+)
+
+// Format returns the file, package and function of the caller as selected by format.
+func (c CallerInfo) Format(format Format) (file string, pkg string, function string) {
+	file, pkg, function = c.File, c.Package, c.Function
+	if format&ModuleFile != 0 && c.ModuleFile != "" {
+		file = c.ModuleFile
+	}
+	if format&FullPackage != 0 && c.PackagePath != "" {
+		pkg = c.PackagePath
+	}
+	if format&CleanFunction != 0 && c.CleanFunction != "" {
+		function = c.CleanFunction
+	}
+	return file, pkg, function
+}
+
+// splitFunction splits the full name of a function, as the runtime reports it,
+// in the import path of its package and the function name.
+// The runtime escapes the dots of the last element of the import path as "%2e".
+func splitFunction(fullFuncName string) (pkgPath string, function string) {
+	lastSlashIndex := strings.LastIndexByte(fullFuncName, '/')
+
+	firstDotIndex := strings.IndexByte(fullFuncName[lastSlashIndex+1:], '.')
+	if firstDotIndex < 0 {
+		return "", fullFuncName
+	}
+	pkgEnd := lastSlashIndex + 1 + firstDotIndex
+
+	return strings.ReplaceAll(fullFuncName[:pkgEnd], "%2e", "."), fullFuncName[pkgEnd+1:]
+}
+
+// cleanFunction turns the function name reported by the runtime into the name in the source:
+// "(*Server[...]).Handle.func1.2" becomes "Server.Handle.closure",
+// "glob..func1", a closure assigned to a package variable, becomes "init.closure".
+func cleanFunction(function string) string {
+	function = strings.ReplaceAll(function, "[...]", "")
+	if rest, ok := strings.CutPrefix(function, "glob."); ok {
+		function = "init" + rest
+	}
+
+	var parts []string
+	closure := false
+	for _, part := range strings.Split(function, ".") {
+		if part == "" || isClosure(part) {
+			closure = true
+			continue
+		}
+		part = strings.TrimPrefix(part, "(*")
+		part = strings.TrimPrefix(part, "(")
+		part = strings.TrimSuffix(part, ")")
+		parts = append(parts, part)
+	}
+
+	name := strings.Join(parts, ".")
+	if closure {
+		name += ".closure"
+	}
+	return name
+}
+
+// isClosure reports whether part names an anonymous function: "func1", "gowrap1",
+// "deferwrap1", or a number for the closures nested in another closure.
+func isClosure(part string) bool {
+	for _, prefix := range []string{"func", "gowrap", "deferwrap"} {
+		if rest, ok := strings.CutPrefix(part, prefix); ok && rest != "" && isDigits(rest) {
+			return true
+		}
+	}
+	return isDigits(part)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// modules holds the paths of the modules of the program, the main module first.
+var modules = sync.OnceValue(func() []string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+
+	paths := []string{info.Main.Path}
+	for _, dep := range info.Deps {
+		paths = append(paths, dep.Path)
+	}
+	return paths
+})
+
+// moduleOf returns the path of the module that holds the package, the longest that matches.
+func moduleOf(pkgPath string) string {
+	var found string
+	for _, mod := range modules() {
+		if mod == "" || len(mod) <= len(found) {
+			continue
+		}
+		if pkgPath == mod || strings.HasPrefix(pkgPath, mod+"/") {
+			found = mod
+		}
+	}
+	return found
+}
+
+// moduleFile returns the path of file relative to the root of the module of its package.
+func moduleFile(pkgPath string, file string) string {
+	base := path.Base(file)
+
+	if mod := moduleOf(pkgPath); mod != "" {
+		return path.Join(strings.TrimPrefix(pkgPath[len(mod):], "/"), base)
+	}
+
+	// the package main is not named by its import path
+	if mods := modules(); len(mods) > 0 && mods[0] != "" {
+		if rel, ok := strings.CutPrefix(file, mods[0]+"/"); ok {
+			return rel // built with -trimpath
+		}
+	}
+	if root := moduleRoot(path.Dir(file)); root != "" {
+		return strings.TrimPrefix(file, root+"/")
+	}
+	return base
+}
+
+// roots caches the module root of the directories, "" when none was found.
+var roots sync.Map
+
+// moduleRoot returns the closest directory above dir with a go.mod file.
+func moduleRoot(dir string) string {
+	if root, ok := roots.Load(dir); ok {
+		return root.(string)
+	}
+
+	root := ""
+	for d := dir; d != "" && d != "/" && d != "."; d = path.Dir(d) {
+		if _, err := os.Stat(filepath.Join(filepath.FromSlash(d), "go.mod")); err == nil {
+			root = d
+			break
+		}
+		if parent := path.Dir(d); parent == d {
+			break
+		}
+	}
+
+	roots.Store(dir, root)
+	return root
+}
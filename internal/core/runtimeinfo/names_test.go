@@ -0,0 +1,172 @@
+package runtimeinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type box[T any] struct{ v T }
+
+func (b *box[T]) caller() CallerInfo {
+	return GetCallerInfo(1)
+}
+
+func (b box[T]) valueCaller() CallerInfo {
+	return GetCallerInfo(1)
+}
+
+func genericCaller[T any](T) CallerInfo {
+	return GetCallerInfo(1)
+}
+
+func TestSplitFunction(t *testing.T) {
+	tests := []struct {
+		full     string
+		pkgPath  string
+		function string
+	}{
+		{"github.com/app/internal/db.Open", "github.com/app/internal/db", "Open"},
+		{"github.com/app/internal/cache/db.(*Conn).Close", "github.com/app/internal/cache/db", "(*Conn).Close"},
+		{"gopkg.in/yaml%2ev3.(*Decoder).Decode", "gopkg.in/yaml.v3", "(*Decoder).Decode"},
+		{"main.main.func1", "main", "main.func1"},
+		{"noDot", "", "noDot"},
+	}
+	for _, tt := range tests {
+		pkgPath, function := splitFunction(tt.full)
+		if pkgPath != tt.pkgPath || function != tt.function {
+			t.Errorf("splitFunction(%q) = %q, %q, want %q, %q", tt.full, pkgPath, function, tt.pkgPath, tt.function)
+		}
+	}
+}
+
+func TestCleanFunction(t *testing.T) {
+	tests := []struct {
+		function string
+		want     string
+	}{
+		{"Open", "Open"},
+		{"(*Conn).Close", "Conn.Close"},
+		{"Conn.Close", "Conn.Close"},
+		{"Open.func1", "Open.closure"},
+		{"Open.func1.2", "Open.closure"},
+		{"(*Conn).Close.func3", "Conn.Close.closure"},
+		{"Map[...]", "Map"},
+		{"Map[...].func1", "Map.closure"},
+		{"(*Set[...]).Add", "Set.Add"},
+		{"main.gowrap1", "main.closure"},
+		{"run.deferwrap2", "run.closure"},
+		{"glob..func1", "init.closure"},
+		{"init.0", "init.closure"},
+		{"function2", "function2"},
+	}
+	for _, tt := range tests {
+		if got := cleanFunction(tt.function); got != tt.want {
+			t.Errorf("cleanFunction(%q) = %q, want %q", tt.function, got, tt.want)
+		}
+	}
+}
+
+func TestCallerNames(t *testing.T) {
+	t.Run("should keep the full package path", func(t *testing.T) {
+		info := GetCallerInfo(1)
+		if info.Package != "runtimeinfo" {
+			t.Errorf("expected package %q, got %q", "runtimeinfo", info.Package)
+		}
+		if info.PackagePath != "github.com/IonicHealthUsa/ionlog/internal/core/runtimeinfo" {
+			t.Errorf("unexpected package path %q", info.PackagePath)
+		}
+	})
+
+	t.Run("should have the file relative to the module", func(t *testing.T) {
+		info := GetCallerInfo(1)
+		if info.ModuleFile != "internal/core/runtimeinfo/names_test.go" {
+			t.Errorf("unexpected module file %q", info.ModuleFile)
+		}
+	})
+
+	t.Run("should clean the closures", func(t *testing.T) {
+		info := GetCallerInfo(1)
+		if info.Function != "TestCallerNames.func3" || info.CleanFunction != "TestCallerNames.closure" {
+			t.Errorf("unexpected function %q, clean %q", info.Function, info.CleanFunction)
+		}
+	})
+
+	t.Run("should clean the generic methods and functions", func(t *testing.T) {
+		b := &box[int]{}
+
+		if info := b.caller(); info.CleanFunction != "box.caller" {
+			t.Errorf("expected %q, got %q from %q", "box.caller", info.CleanFunction, info.Function)
+		}
+		if info := b.valueCaller(); info.CleanFunction != "box.valueCaller" {
+			t.Errorf("expected %q, got %q from %q", "box.valueCaller", info.CleanFunction, info.Function)
+		}
+		if info := genericCaller("x"); info.CleanFunction != "genericCaller" {
+			t.Errorf("expected %q, got %q from %q", "genericCaller", info.CleanFunction, info.Function)
+		}
+	})
+}
+
+func TestFormat(t *testing.T) {
+	info := CallerInfo{
+		File:          "db.go",
+		Package:       "db",
+		Function:      "(*Conn).Close.func1",
+		PackagePath:   "github.com/app/internal/cache/db",
+		ModuleFile:    "internal/cache/db/db.go",
+		CleanFunction: "Conn.Close.closure",
+	}
+
+	file, pkg, function := info.Format(0)
+	if file != "db.go" || pkg != "db" || function != "(*Conn).Close.func1" {
+		t.Errorf("unexpected default format %q %q %q", file, pkg, function)
+	}
+
+	file, pkg, function = info.Format(FullPackage | ModuleFile | CleanFunction)
+	if file != "internal/cache/db/db.go" || pkg != "github.com/app/internal/cache/db" || function != "Conn.Close.closure" {
+		t.Errorf("unexpected full format %q %q %q", file, pkg, function)
+	}
+
+	file, pkg, _ = CallerInfo{File: "x.go", Package: "x"}.Format(FullPackage | ModuleFile)
+	if file != "x.go" || pkg != "x" {
+		t.Errorf("expected the short names when the full ones are unknown, got %q %q", file, pkg)
+	}
+}
+
+func TestModuleFile(t *testing.T) {
+	defer func(m func() []string) { modules = m }(modules)
+	modules = func() []string {
+		return []string{"example.com/app", "github.com/dep/lib", "github.com/dep/lib/v2"}
+	}
+
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "cmd", "server"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rootSlash := filepath.ToSlash(root)
+
+	tests := []struct {
+		name    string
+		pkgPath string
+		file    string
+		want    string
+	}{
+		{"main module package", "example.com/app/internal/db", "/src/app/internal/db/db.go", "internal/db/db.go"},
+		{"main module root", "example.com/app", "/src/app/app.go", "app.go"},
+		{"dependency", "github.com/dep/lib/codec", "/go/pkg/mod/github.com/dep/lib@v1.0.0/codec/json.go", "codec/json.go"},
+		{"longest module", "github.com/dep/lib/v2/codec", "/go/pkg/mod/github.com/dep/lib/v2@v2.0.0/codec/json.go", "codec/json.go"},
+		{"main built with trimpath", "main", "example.com/app/cmd/server/main.go", "cmd/server/main.go"},
+		{"main with go.mod", "main", rootSlash + "/cmd/server/main.go", "cmd/server/main.go"},
+		{"unknown", "other.org/x", "/nowhere/x/x.go", "x.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := moduleFile(tt.pkgPath, tt.file); got != tt.want {
+				t.Errorf("moduleFile(%q, %q) = %q, want %q", tt.pkgPath, tt.file, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,8 +3,9 @@ package runtimeinfo
 import (
 	"fmt"
 	"os"
+	"path"
 	"runtime"
-	"strings"
+	"sync"
 )
 
 type CallerInfo struct {
@@ -12,29 +13,101 @@ type CallerInfo struct {
 	Package  string
 	Function string
 	Line     int
+
+	// PackagePath is the import path of the package.
+	PackagePath string
+	// ModuleFile is the path of the file relative to the root of its module.
+	ModuleFile string
+	// CleanFunction is the function name as written in the source.
+	CleanFunction string
+}
+
+// resolved is a symbolized program counter.
+type resolved struct {
+	info     CallerInfo
+	function string // the full name, to find the helpers
 }
 
+// cache holds the resolved program counters, a program has a bounded number of call sites.
+var cache sync.Map // uintptr -> resolved
+
+// GetCallerInfo returns the information of the function skip frames above its caller,
+// skip 1 is the caller of GetCallerInfo.
 func GetCallerInfo(skip int) CallerInfo {
-	pc, file, line, ok := runtime.Caller(skip)
-	if !ok {
+	pc := CallerPC(skip + 1)
+	if pc == 0 {
 		fmt.Fprint(os.Stderr, "Failed to get caller information\n")
 		return CallerInfo{}
 	}
+	return Resolve(pc)
+}
+
+// CallerPC returns the program counter of the function skip frames above its caller,
+// or 0 when there is no such frame. It is cheaper than GetCallerInfo,
+// the symbolization is left to Resolve.
+func CallerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+1, pcs[:]) == 0 {
+		return 0
+	}
+	pc := pcs[0]
+
+	// the functions marked as helpers are reported as their callers
+	for depth := 0; helperCount.Load() > 0 && depth < maxFrames; depth++ {
+		if !IsHelper(resolve(pc).function) {
+			break
+		}
+		skip++
+		if runtime.Callers(skip+1, pcs[:]) == 0 {
+			break
+		}
+		pc = pcs[0]
+	}
+
+	return pc
+}
 
-	fileLastSlashIndex := strings.LastIndexByte(file, '/')
+// Resolve returns the information of the call site of pc, a program counter returned by CallerPC.
+func Resolve(pc uintptr) CallerInfo {
+	if pc == 0 {
+		return CallerInfo{}
+	}
+	return resolve(pc).info
+}
 
-	// Get function name
-	fullFuncName := runtime.FuncForPC(pc).Name()
+func resolve(pc uintptr) resolved {
+	if r, ok := cache.Load(pc); ok {
+		return r.(resolved)
+	}
 
-	lastSlashIndex := strings.LastIndexByte(fullFuncName, '/')
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	r := resolved{
+		info:     parseFrame(frame.Function, frame.File, frame.Line),
+		function: frame.Function,
+	}
+	cache.Store(pc, r)
+	return r
+}
 
-	fistDotIndex := strings.IndexByte(fullFuncName[lastSlashIndex+1:], '.')
-	pkgEnd := lastSlashIndex + 1 + fistDotIndex
+// parseFrame splits the full function name in its package and function.
+func parseFrame(fullFuncName string, file string, line int) CallerInfo {
+	pkgPath, function := splitFunction(fullFuncName)
 
 	return CallerInfo{
-		File:     file[fileLastSlashIndex+1:],
-		Package:  fullFuncName[lastSlashIndex+1 : pkgEnd],
-		Function: fullFuncName[pkgEnd+1:],
-		Line:     line,
+		File:          baseName(file),
+		Package:       baseName(pkgPath),
+		Function:      function,
+		Line:          line,
+		PackagePath:   pkgPath,
+		ModuleFile:    moduleFile(pkgPath, file),
+		CleanFunction: cleanFunction(function),
+	}
+}
+
+// baseName returns the last element of p, or "" when p is empty.
+func baseName(p string) string {
+	if p == "" {
+		return ""
 	}
+	return path.Base(p)
 }
@@ -3,6 +3,7 @@ package runtimeinfo
 import (
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -124,3 +125,71 @@ func TestGetCallerInfoInDifferentFile(t *testing.T) {
 		}
 	})
 }
+
+func BenchmarkCallerPC(b *testing.B) {
+	b.Run("CallerPC", func(b *testing.B) {
+		for range b.N {
+			CallerPC(1)
+		}
+	})
+
+	b.Run("CallerPC and Resolve", func(b *testing.B) {
+		for range b.N {
+			Resolve(CallerPC(1))
+		}
+	})
+}
+
+func TestCallerPC(t *testing.T) {
+	t.Run("should resolve to the caller", func(t *testing.T) {
+		pc := CallerPC(1)
+		if pc == 0 {
+			t.Fatal("expected a program counter")
+		}
+
+		info := Resolve(pc)
+		if info.Function != "TestCallerPC.func1" || info.Package != "runtimeinfo" || info.File != "runtimeinfo_test.go" {
+			t.Errorf("unexpected caller info %+v", info)
+		}
+		if info.Line == 0 {
+			t.Errorf("expected a line, got %+v", info)
+		}
+	})
+
+	t.Run("should cache the resolved program counters", func(t *testing.T) {
+		pc := CallerPC(1)
+		first := Resolve(pc)
+
+		if _, ok := cache.Load(pc); !ok {
+			t.Fatal("expected the program counter to be cached")
+		}
+		if Resolve(pc) != first {
+			t.Error("expected the cached caller info to match")
+		}
+	})
+
+	t.Run("should return zero past the stack", func(t *testing.T) {
+		if pc := CallerPC(100); pc != 0 {
+			t.Errorf("expected 0, got %v", pc)
+		}
+		if info := Resolve(0); info != (CallerInfo{}) {
+			t.Errorf("expected empty caller info, got %+v", info)
+		}
+	})
+
+	t.Run("should resolve concurrently", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 100 {
+					if Resolve(CallerPC(1)).Function == "" {
+						t.Error("expected a function")
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
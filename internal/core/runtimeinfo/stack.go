@@ -0,0 +1,90 @@
+package runtimeinfo
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxFrames bounds the depth of the captured stacks.
+const maxFrames = 64
+
+// Frame is a function call of a stack trace.
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// modulePath is the import path of ionlog, found from the name of this package.
+var modulePath = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	if i := strings.Index(name, "/internal/"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}()
+
+// Stack returns the stack of the calling goroutine, skip is the number of frames
+// to skip as in GetCallerInfo. The frame of runtime.goexit is left out.
+func Stack(skip int) []Frame {
+	pcs := make([]uintptr, maxFrames)
+	n := runtime.Callers(skip+1, pcs)
+
+	var stack []Frame
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if f.Function != "runtime.goexit" {
+			stack = append(stack, Frame{Function: f.Function, File: f.File, Line: f.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// TrimInternal removes the frames of ionlog and of the helpers from the top of the stack,
+// so it starts at the code that called the logger.
+func TrimInternal(stack []Frame) []Frame {
+	for len(stack) > 0 && (isInternal(stack[0]) || IsHelper(stack[0].Function)) {
+		stack = stack[1:]
+	}
+	return stack
+}
+
+// isInternal reports whether the frame belongs to ionlog, but its tests.
+func isInternal(f Frame) bool {
+	if strings.HasSuffix(f.File, "_test.go") {
+		return false
+	}
+	return strings.HasPrefix(f.Function, modulePath+".") || strings.HasPrefix(f.Function, modulePath+"/internal/")
+}
+
+// FormatStack formats the stack as the runtime does in a panic.
+func FormatStack(stack []Frame) string {
+	var b strings.Builder
+	for _, f := range stack {
+		b.WriteString(f.Function)
+		b.WriteString("\n\t")
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(f.Line))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// GoroutineID returns the ID of the calling goroutine, read from the header of its stack.
+func GoroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	header := strings.TrimPrefix(string(buf[:n]), "goroutine ")
+	if i := strings.IndexByte(header, ' '); i >= 0 {
+		header = header[:i]
+	}
+	id, _ := strconv.ParseUint(header, 10, 64)
+	return id
+}
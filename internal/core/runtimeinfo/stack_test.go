@@ -0,0 +1,77 @@
+package runtimeinfo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestModulePath(t *testing.T) {
+	if modulePath != "github.com/IonicHealthUsa/ionlog" {
+		t.Errorf("expected the module path of ionlog, got %q", modulePath)
+	}
+}
+
+func TestStack(t *testing.T) {
+	stack := Stack(1)
+	if len(stack) == 0 {
+		t.Fatal("expected a stack")
+	}
+	if !strings.HasSuffix(stack[0].Function, "runtimeinfo.TestStack") {
+		t.Errorf("expected the stack to start at the caller, got %q", stack[0].Function)
+	}
+	if !strings.HasSuffix(stack[0].File, "stack_test.go") || stack[0].Line == 0 {
+		t.Errorf("expected the file and line of the caller, got %+v", stack[0])
+	}
+	for _, f := range stack {
+		if f.Function == "runtime.goexit" {
+			t.Error("expected runtime.goexit to be left out")
+		}
+	}
+}
+
+func TestTrimInternal(t *testing.T) {
+	stack := []Frame{
+		{Function: "github.com/IonicHealthUsa/ionlog/internal/core/logengine.(*logger).AsyncReport", File: "/src/ionlog/internal/core/logengine/logger.go"},
+		{Function: "github.com/IonicHealthUsa/ionlog.Error", File: "/src/ionlog/logger.go"},
+		{Function: "main.handle", File: "/app/main.go"},
+		{Function: "github.com/IonicHealthUsa/ionlog.Error", File: "/src/ionlog/logger.go"},
+	}
+
+	trimmed := TrimInternal(stack)
+	if len(trimmed) != 2 || trimmed[0].Function != "main.handle" {
+		t.Errorf("expected the stack to start at main.handle, got %+v", trimmed)
+	}
+
+	test := []Frame{{Function: "github.com/IonicHealthUsa/ionlog/internal/core/logengine.TestX", File: "/src/ionlog/internal/core/logengine/logger_test.go"}}
+	if len(TrimInternal(test)) != 1 {
+		t.Error("expected the test frames to be kept")
+	}
+
+	other := []Frame{{Function: "github.com/IonicHealthUsa/ionlogger.Run", File: "/src/ionlogger/run.go"}}
+	if len(TrimInternal(other)) != 1 {
+		t.Error("expected a module with the same prefix to be kept")
+	}
+}
+
+func TestFormatStack(t *testing.T) {
+	got := FormatStack([]Frame{{Function: "main.main", File: "/app/main.go", Line: 12}})
+	if got != "main.main\n\t/app/main.go:12\n" {
+		t.Errorf("unexpected format %q", got)
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	id := GoroutineID()
+	if id == 0 {
+		t.Fatal("expected the ID of the goroutine")
+	}
+	if again := GoroutineID(); again != id {
+		t.Errorf("expected the same ID on the same goroutine, got %d and %d", id, again)
+	}
+
+	other := make(chan uint64)
+	go func() { other <- GoroutineID() }()
+	if got := <-other; got == id || got == 0 {
+		t.Errorf("expected another ID on another goroutine, got %d", got)
+	}
+}
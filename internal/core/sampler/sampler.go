@@ -0,0 +1,97 @@
+// Package sampler limits how many entries a call site emits.
+package sampler
+
+import (
+	"encoding/binary"
+	"strconv"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/infrastructure/memory"
+	"github.com/cespare/xxhash"
+)
+
+// idPrefix separates the sampling state of a call site from its LogOnce record.
+const idPrefix = "sample\x00"
+
+type ISampler interface {
+	// Allow reports whether the entry is emitted, updating the state of its call site.
+	Allow(state *memory.SampleState, now time.Time) bool
+}
+
+type burst struct {
+	n   uint64
+	per time.Duration
+}
+
+type firstThenEvery struct {
+	first uint64
+	every uint64
+}
+
+// NewEvery emits at most one entry per interval.
+func NewEvery(interval time.Duration) ISampler {
+	return NewBurst(1, interval)
+}
+
+// NewBurst emits at most n entries per period, the period starts with its first entry.
+func NewBurst(n uint, per time.Duration) ISampler {
+	return &burst{n: uint64(n), per: per}
+}
+
+// NewFirstThenEvery emits the first entries, then one of every entries.
+func NewFirstThenEvery(first uint, every uint) ISampler {
+	return &firstThenEvery{first: uint64(first), every: uint64(every)}
+}
+
+func (b *burst) Allow(state *memory.SampleState, now time.Time) bool {
+	if state.WindowStart.IsZero() || now.Sub(state.WindowStart) >= b.per {
+		state.WindowStart = now
+		state.InWindow = 0
+	}
+
+	if state.InWindow >= b.n {
+		return false
+	}
+	state.InWindow++
+	return true
+}
+
+func (f *firstThenEvery) Allow(state *memory.SampleState, now time.Time) bool {
+	state.Total++
+	if state.Total <= f.first {
+		return true
+	}
+	return f.every > 0 && (state.Total-f.first)%f.every == 0
+}
+
+// CallSiteID returns the ID of the sampling state of a call site.
+func CallSiteID(file string, pkg string, function string, line int) uint64 {
+	return memory.GenHash(idPrefix + file + pkg + function + strconv.Itoa(line))
+}
+
+// CallSitePCID returns the ID of the sampling state of the call site of pc,
+// for the entries whose caller is not resolved.
+func CallSitePCID(pc uintptr) uint64 {
+	var b [len(idPrefix) + 8]byte
+	copy(b[:], idPrefix)
+	binary.LittleEndian.PutUint64(b[len(idPrefix):], uint64(pc))
+	return xxhash.Sum64(b[:])
+}
+
+// Sample reports whether the entry of the call site id is emitted, and when it is,
+// how many entries of the call site were suppressed since the last one emitted.
+func Sample(logsMemory memory.IRecordMemory, id uint64, s ISampler, now time.Time) (bool, uint64) {
+	var suppressed uint64
+
+	emit := logsMemory.GetOrAddRecord(id).Sample(func(state *memory.SampleState) bool {
+		if !s.Allow(state, now) {
+			state.Suppressed++
+			return false
+		}
+		suppressed = state.Suppressed
+		state.Suppressed = 0
+		return true
+	})
+
+	return emit, suppressed
+}
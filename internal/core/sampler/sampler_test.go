@@ -0,0 +1,131 @@
+package sampler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/infrastructure/memory"
+)
+
+func allowed(s ISampler, state *memory.SampleState, now time.Time, n int) int {
+	count := 0
+	for range n {
+		if s.Allow(state, now) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestBurst(t *testing.T) {
+	s := NewBurst(3, time.Second)
+	state := &memory.SampleState{}
+	now := time.Unix(100, 0)
+
+	if got := allowed(s, state, now, 10); got != 3 {
+		t.Errorf("expected 3 entries in the first period, got %d", got)
+	}
+	if got := allowed(s, state, now.Add(999*time.Millisecond), 10); got != 0 {
+		t.Errorf("expected no entries before the period ends, got %d", got)
+	}
+	if got := allowed(s, state, now.Add(time.Second), 10); got != 3 {
+		t.Errorf("expected 3 entries in the next period, got %d", got)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	s := NewEvery(time.Minute)
+	state := &memory.SampleState{}
+	now := time.Unix(100, 0)
+
+	if got := allowed(s, state, now, 5); got != 1 {
+		t.Errorf("expected 1 entry, got %d", got)
+	}
+	if got := allowed(s, state, now.Add(time.Minute), 5); got != 1 {
+		t.Errorf("expected 1 entry after the interval, got %d", got)
+	}
+}
+
+func TestFirstThenEvery(t *testing.T) {
+	s := NewFirstThenEvery(2, 5)
+	state := &memory.SampleState{}
+
+	var emitted []int
+	for i := 1; i <= 20; i++ {
+		if s.Allow(state, time.Time{}) {
+			emitted = append(emitted, i)
+		}
+	}
+
+	want := []int{1, 2, 7, 12, 17}
+	if len(emitted) != len(want) {
+		t.Fatalf("expected %v, got %v", want, emitted)
+	}
+	for i := range want {
+		if emitted[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, emitted)
+		}
+	}
+
+	if got := allowed(NewFirstThenEvery(1, 0), &memory.SampleState{}, time.Time{}, 10); got != 1 {
+		t.Errorf("expected only the first entry when every is zero, got %d", got)
+	}
+}
+
+func TestSample(t *testing.T) {
+	mem := memory.NewRecordMemory()
+	s := NewBurst(1, time.Hour)
+	id := CallSiteID("main.go", "main", "main", 10)
+	now := time.Now()
+
+	if emit, suppressed := Sample(mem, id, s, now); !emit || suppressed != 0 {
+		t.Fatalf("expected the first entry emitted, got %v %d", emit, suppressed)
+	}
+	for range 37 {
+		if emit, _ := Sample(mem, id, s, now); emit {
+			t.Fatal("expected the entries to be suppressed")
+		}
+	}
+	emit, suppressed := Sample(mem, id, s, now.Add(time.Hour))
+	if !emit || suppressed != 37 {
+		t.Errorf("expected 37 suppressed on the next entry, got %v %d", emit, suppressed)
+	}
+
+	if other := CallSiteID("main.go", "main", "main", 11); other == id {
+		t.Error("expected the call sites to have different IDs")
+	}
+	if CallSitePCID(1) == CallSitePCID(2) {
+		t.Error("expected the program counters to have different IDs")
+	}
+}
+
+func TestSampleConcurrent(t *testing.T) {
+	mem := memory.NewRecordMemory()
+	s := NewBurst(10, time.Hour)
+	id := CallSiteID("main.go", "main", "main", 10)
+	now := time.Now()
+
+	var mu sync.Mutex
+	emitted := 0
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if emit, _ := Sample(mem, id, s, now); emit {
+					mu.Lock()
+					emitted++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if emitted != 10 {
+		t.Errorf("expected 10 entries emitted, got %d", emitted)
+	}
+}
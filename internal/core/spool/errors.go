@@ -0,0 +1,12 @@
+package spool
+
+import "errors"
+
+var (
+	ErrEmpty         = errors.New("spool is empty")
+	ErrSpoolFull     = errors.New("spool is full")
+	ErrSpoolClosed   = errors.New("spool is closed")
+	ErrEntryTooLarge = errors.New("entry is larger than a segment")
+	ErrCorrupted     = errors.New("corrupted record")
+	ErrNotPeeked     = errors.New("entry was not peeked or was dropped")
+)
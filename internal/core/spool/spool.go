@@ -0,0 +1,564 @@
+// Package spool is a durable FIFO queue of entries kept in segment files on disk.
+package spool
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/IonicHealthUsa/ionlog/internal/infrastructure/filesystem"
+)
+
+const (
+	DefaultMaxSize     uint = 256 * 1024 * 1024 // 256 MB
+	DefaultSegmentSize uint = 8 * 1024 * 1024   // 8 MB
+	NoMaxSize          uint = 0
+
+	segmentPattern = "segment-%020d.spool"
+	cursorFile     = "cursor"
+
+	// recordHeader is the length and the CRC-32 of the entry, before the entry itself.
+	recordHeader = 8
+)
+
+var segmentName = regexp.MustCompile(`^segment-(\d{20})\.spool$`)
+
+// segment is a file of the spool, entries counts the records it holds.
+type segment struct {
+	id      uint64
+	size    int64
+	entries int
+}
+
+// spool appends the entries to the newest segment and reads them from the oldest one.
+// The read position is kept in the cursor file, so the entries survive a restart.
+type spool struct {
+	filesystem.Filesystem
+
+	folder      string
+	maxSize     uint
+	segmentSize uint
+	sync        bool
+
+	lock     sync.Mutex
+	segments []segment
+	size     uint
+
+	writeFile *os.File
+	readFile  *os.File
+	readOff   int64
+	readCount int  // records of the oldest segment already read
+	peeked    bool // the entry at the read offset was returned by Peek and not acked yet
+	cursor    *os.File
+
+	pending int
+	dropped uint64
+	closed  bool
+}
+
+type ISpool interface {
+	Append(entry []byte) error
+	Peek() ([]byte, error)
+	Ack() error
+	Len() int
+	Size() uint
+	Dropped() uint64
+	Close() error
+}
+
+type Option func(s *spool)
+
+// NewSpool opens the spool in folder, creating it when it does not exist,
+// the entries left by a previous process are kept.
+func NewSpool(folder string, opts ...Option) (ISpool, error) {
+	s := &spool{
+		folder:      folder,
+		maxSize:     DefaultMaxSize,
+		segmentSize: DefaultSegmentSize,
+	}
+	s.Filesystem = filesystem.NewFileSystem(
+		os.Stat,
+		os.Mkdir,
+		os.ReadDir,
+		os.IsNotExist,
+		os.OpenFile,
+		os.Remove,
+	)
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.maxSize != NoMaxSize {
+		s.segmentSize = min(s.segmentSize, s.maxSize/2)
+	}
+
+	if err := s.open(); err != nil {
+		s.closeFiles()
+		return nil, err
+	}
+	return s, nil
+}
+
+// WithMaxSize bounds the size of the folder, the oldest segment is dropped when it is exceeded,
+// NoMaxSize does not bound it.
+func WithMaxSize(size uint) Option {
+	return func(s *spool) {
+		s.maxSize = size
+	}
+}
+
+// WithSegmentSize sets the size of every segment file, at most half of the max size.
+func WithSegmentSize(size uint) Option {
+	return func(s *spool) {
+		s.segmentSize = size
+	}
+}
+
+// WithSync syncs every entry to the disk before Append returns.
+func WithSync(enabled bool) Option {
+	return func(s *spool) {
+		s.sync = enabled
+	}
+}
+
+// open loads the segments and the cursor.
+func (s *spool) open() error {
+	if err := s.assertFolder(); err != nil {
+		return err
+	}
+
+	ids, err := s.segmentIDs()
+	if err != nil {
+		return err
+	}
+
+	s.cursor, err = s.OpenFile(filepath.Join(s.folder, cursorFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	readID, readOff := s.readCursor()
+
+	for _, id := range ids {
+		if id < readID {
+			s.RemoveFile(s.segmentPath(id)) // already delivered
+			continue
+		}
+
+		seg, err := s.scan(id)
+		if err != nil {
+			return err
+		}
+		s.segments = append(s.segments, seg)
+		s.size += uint(seg.size)
+		s.pending += seg.entries
+	}
+
+	if len(s.segments) == 0 {
+		next := readID + 1
+		if len(ids) > 0 {
+			next = max(next, ids[len(ids)-1]+1)
+		}
+		if err := s.newSegment(next); err != nil {
+			return err
+		}
+		return s.writeCursor()
+	}
+
+	if s.segments[0].id == readID {
+		s.readOff, s.readCount = s.skip(s.segments[0], readOff)
+		s.pending -= s.readCount
+	}
+
+	last := s.segments[len(s.segments)-1]
+	s.writeFile, err = s.OpenFile(s.segmentPath(last.id), os.O_WRONLY|os.O_APPEND, 0644)
+	return err
+}
+
+// assertFolder creates the folder when it does not exist.
+func (s *spool) assertFolder() error {
+	_, err := s.Stat(s.folder)
+	if err == nil {
+		return nil
+	}
+	if s.IsNotExist(err) {
+		return s.Mkdir(s.folder, 0755)
+	}
+	return err
+}
+
+// segmentIDs returns the IDs of the segment files, from the oldest to the newest.
+func (s *spool) segmentIDs() ([]uint64, error) {
+	files, err := s.ReadDir(s.folder)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	for _, file := range files {
+		m := segmentName.FindStringSubmatch(file.Name())
+		if file.IsDir() || m == nil {
+			continue
+		}
+		id, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (s *spool) segmentPath(id uint64) string {
+	return filepath.Join(s.folder, fmt.Sprintf(segmentPattern, id))
+}
+
+// scan counts the valid records of a segment, a partial or corrupted tail,
+// e.g. from a crash during a write, is truncated.
+func (s *spool) scan(id uint64) (segment, error) {
+	f, err := s.OpenFile(s.segmentPath(id), os.O_RDWR, 0644)
+	if err != nil {
+		return segment{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return segment{}, err
+	}
+
+	seg := segment{id: id}
+	for {
+		entry, err := readRecord(f, seg.size, info.Size())
+		if err != nil {
+			break
+		}
+		seg.size += int64(recordHeader + len(entry))
+		seg.entries++
+	}
+
+	if err := f.Truncate(seg.size); err != nil {
+		return segment{}, err
+	}
+	return seg, nil
+}
+
+// skip returns the offset and the count of the records of seg before off.
+func (s *spool) skip(seg segment, off int64) (int64, int) {
+	f, err := s.OpenFile(s.segmentPath(seg.id), os.O_RDONLY, 0)
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+
+	var pos int64
+	count := 0
+	for pos < off {
+		entry, err := readRecord(f, pos, seg.size)
+		if err != nil {
+			break
+		}
+		pos += int64(recordHeader + len(entry))
+		count++
+	}
+	return pos, count
+}
+
+// readRecord reads the record at off, verifying its checksum. The record must end
+// before end, the size of the segment, so a corrupted length is not allocated.
+func readRecord(r io.ReaderAt, off int64, end int64) ([]byte, error) {
+	var header [recordHeader]byte
+	if _, err := r.ReadAt(header[:], off); err != nil {
+		return nil, err
+	}
+
+	size := binary.LittleEndian.Uint32(header[:4])
+	if int64(size) > end-off-recordHeader {
+		return nil, ErrCorrupted
+	}
+
+	entry := make([]byte, size)
+	if _, err := r.ReadAt(entry, off+recordHeader); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrCorrupted
+		}
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(entry) != binary.LittleEndian.Uint32(header[4:]) {
+		return nil, ErrCorrupted
+	}
+	return entry, nil
+}
+
+func (s *spool) readCursor() (uint64, int64) {
+	var buf [16]byte
+	if _, err := s.cursor.ReadAt(buf[:], 0); err != nil {
+		return 0, 0
+	}
+	return binary.LittleEndian.Uint64(buf[:8]), int64(binary.LittleEndian.Uint64(buf[8:]))
+}
+
+func (s *spool) writeCursor() error {
+	var buf [16]byte
+	binary.LittleEndian.PutUint64(buf[:8], s.segments[0].id)
+	binary.LittleEndian.PutUint64(buf[8:], uint64(s.readOff))
+	_, err := s.cursor.WriteAt(buf[:], 0)
+	return err
+}
+
+// newSegment creates the segment id and makes it the one appended to.
+func (s *spool) newSegment(id uint64) error {
+	f, err := s.OpenFile(s.segmentPath(id), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	if s.writeFile != nil {
+		s.writeFile.Close()
+	}
+	s.writeFile = f
+	s.segments = append(s.segments, segment{id: id})
+	return nil
+}
+
+// Append adds the entry after the others, dropping the oldest segment when the
+// spool would exceed its max size.
+func (s *spool) Append(entry []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return ErrSpoolClosed
+	}
+
+	size := uint(recordHeader + len(entry))
+	if size > s.segmentSize {
+		return ErrEntryTooLarge
+	}
+
+	last := &s.segments[len(s.segments)-1]
+	if uint(last.size)+size > s.segmentSize {
+		if err := s.newSegment(last.id + 1); err != nil {
+			return err
+		}
+		last = &s.segments[len(s.segments)-1]
+	}
+
+	for s.maxSize != NoMaxSize && s.size+size > s.maxSize {
+		if !s.dropOldest() {
+			return ErrSpoolFull
+		}
+	}
+
+	record := make([]byte, recordHeader, size)
+	binary.LittleEndian.PutUint32(record[:4], uint32(len(entry)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(entry))
+	record = append(record, entry...)
+
+	if _, err := s.writeFile.Write(record); err != nil {
+		return err
+	}
+	if s.sync {
+		if err := s.writeFile.Sync(); err != nil {
+			return err
+		}
+	}
+
+	last.size += int64(size)
+	last.entries++
+	s.size += size
+	s.pending++
+	return nil
+}
+
+// dropOldest removes the oldest segment and its unread entries,
+// the segment being appended to is never dropped. The entry peeked, if any,
+// is dropped with it and can not be acked anymore.
+func (s *spool) dropOldest() bool {
+	if len(s.segments) < 2 {
+		return false
+	}
+
+	oldest := s.segments[0]
+	lost := oldest.entries - s.readCount
+
+	s.removeOldest()
+	s.pending -= lost
+	s.dropped += uint64(lost)
+
+	fmt.Fprintf(os.Stderr, "spool %q is full, %d entries were dropped\n", s.folder, lost)
+	return true
+}
+
+// quarantine gives up the unread entries of the oldest segment after a corrupted record,
+// its length can not be trusted to find the next one.
+func (s *spool) quarantine() {
+	oldest := s.segments[0]
+	lost := oldest.entries - s.readCount
+
+	s.pending -= lost
+	s.dropped += uint64(lost)
+	fmt.Fprintf(os.Stderr, "spool %q has a corrupted record, %d entries were dropped\n", s.folder, lost)
+
+	if len(s.segments) > 1 {
+		s.removeOldest()
+		return
+	}
+
+	// the segment is still appended to, the next entries are read after the ones lost
+	s.readOff = oldest.size
+	s.readCount = oldest.entries
+	s.peeked = false
+	s.writeCursor()
+}
+
+func (s *spool) removeOldest() {
+	oldest := s.segments[0]
+
+	if s.readFile != nil {
+		s.readFile.Close()
+		s.readFile = nil
+	}
+	s.RemoveFile(s.segmentPath(oldest.id))
+
+	s.segments = s.segments[1:]
+	s.size -= uint(oldest.size)
+	s.readOff = 0
+	s.readCount = 0
+	s.peeked = false
+	s.writeCursor()
+}
+
+// Peek returns the oldest entry without removing it, ErrEmpty when there is none.
+// A corrupted record is skipped with the rest of its segment.
+func (s *spool) Peek() ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return nil, ErrSpoolClosed
+	}
+
+	for {
+		if s.pending == 0 {
+			return nil, ErrEmpty
+		}
+
+		oldest := s.segments[0]
+		if s.readCount >= oldest.entries {
+			s.removeOldest() // fully read, the entries are in the next segment
+			continue
+		}
+
+		if s.readFile == nil {
+			f, err := s.OpenFile(s.segmentPath(oldest.id), os.O_RDONLY, 0)
+			if err != nil {
+				return nil, err
+			}
+			s.readFile = f
+		}
+
+		entry, err := readRecord(s.readFile, s.readOff, oldest.size)
+		if errors.Is(err, ErrCorrupted) {
+			s.quarantine()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.peeked = true
+		return entry, nil
+	}
+}
+
+// Ack removes the oldest entry, the one returned by Peek. It returns ErrNotPeeked when
+// that entry was dropped meanwhile, so the entry after it is not removed undelivered.
+func (s *spool) Ack() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return ErrSpoolClosed
+	}
+	if s.pending == 0 {
+		return ErrEmpty
+	}
+	if !s.peeked {
+		return ErrNotPeeked
+	}
+	s.peeked = false
+
+	var header [4]byte
+	if _, err := s.readFile.ReadAt(header[:], s.readOff); err != nil {
+		return err
+	}
+
+	s.readOff += int64(recordHeader + binary.LittleEndian.Uint32(header[:]))
+	s.readCount++
+	s.pending--
+
+	if s.readCount == s.segments[0].entries && len(s.segments) > 1 {
+		s.removeOldest()
+		return nil
+	}
+	return s.writeCursor()
+}
+
+// Len returns how many entries wait in the spool.
+func (s *spool) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.pending
+}
+
+// Size returns the bytes used by the segment files.
+func (s *spool) Size() uint {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.size
+}
+
+// Dropped returns how many entries were lost because the spool was full.
+func (s *spool) Dropped() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.dropped
+}
+
+// Close closes the files, the entries stay on disk for the next process.
+func (s *spool) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	return s.closeFiles()
+}
+
+func (s *spool) closeFiles() error {
+	var errs []error
+	for _, f := range []*os.File{s.writeFile, s.readFile, s.cursor} {
+		if f == nil {
+			continue
+		}
+		if s.sync {
+			f.Sync()
+		}
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
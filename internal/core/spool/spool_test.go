@@ -0,0 +1,298 @@
+package spool
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func drain(t *testing.T, s ISpool) []string {
+	t.Helper()
+
+	var got []string
+	for {
+		entry, err := s.Peek()
+		if errors.Is(err, ErrEmpty) {
+			return got
+		}
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		got = append(got, string(entry))
+		if err := s.Ack(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+	}
+}
+
+func segments(t *testing.T, folder string) int {
+	t.Helper()
+	files, _ := filepath.Glob(filepath.Join(folder, "segment-*.spool"))
+	return len(files)
+}
+
+func TestSpool(t *testing.T) {
+	t.Run("should return the entries in order", func(t *testing.T) {
+		s, err := NewSpool(t.TempDir())
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer s.Close()
+
+		for i := range 5 {
+			s.Append(fmt.Appendf(nil, "entry %d", i))
+		}
+		if s.Len() != 5 {
+			t.Errorf("expected 5 entries, but got %d", s.Len())
+		}
+
+		got := drain(t, s)
+		if len(got) != 5 || got[0] != "entry 0" || got[4] != "entry 4" {
+			t.Errorf("expected the entries in order, but got %v", got)
+		}
+		if s.Len() != 0 {
+			t.Errorf("expected no entries, but got %d", s.Len())
+		}
+	})
+
+	t.Run("should peek the same entry until it is acked", func(t *testing.T) {
+		s, _ := NewSpool(t.TempDir())
+		defer s.Close()
+
+		s.Append([]byte("a"))
+		s.Append([]byte("b"))
+
+		first, _ := s.Peek()
+		again, _ := s.Peek()
+		if string(first) != "a" || string(again) != "a" {
+			t.Errorf("expected to peek %q twice, but got %q and %q", "a", first, again)
+		}
+	})
+
+	t.Run("should roll the segments and remove the read ones", func(t *testing.T) {
+		folder := t.TempDir()
+		s, _ := NewSpool(folder, WithSegmentSize(64), WithMaxSize(1024))
+		defer s.Close()
+
+		for i := range 10 {
+			s.Append(fmt.Appendf(nil, "entry number %02d", i)) // 24 bytes with the header
+		}
+		if n := segments(t, folder); n != 5 {
+			t.Errorf("expected 5 segments, but got %d", n)
+		}
+
+		got := drain(t, s)
+		if len(got) != 10 || got[9] != "entry number 09" {
+			t.Errorf("expected the entries in order, but got %v", got)
+		}
+		if n := segments(t, folder); n != 1 {
+			t.Errorf("expected the read segments to be removed, but got %d", n)
+		}
+	})
+
+	t.Run("should drop the oldest segment when full", func(t *testing.T) {
+		s, _ := NewSpool(t.TempDir(), WithSegmentSize(48), WithMaxSize(96))
+		defer s.Close()
+
+		for i := range 6 {
+			if err := s.Append(fmt.Appendf(nil, "entry number %02d", i)); err != nil {
+				t.Fatalf("expected no error, but got %v", err)
+			}
+		}
+
+		if s.Dropped() != 2 {
+			t.Errorf("expected 2 dropped entries, but got %d", s.Dropped())
+		}
+		if s.Size() > 96 {
+			t.Errorf("expected the size to be at most 96, but got %d", s.Size())
+		}
+
+		got := drain(t, s)
+		if len(got) != 4 || got[0] != "entry number 02" {
+			t.Errorf("expected the newest entries, but got %v", got)
+		}
+	})
+
+	t.Run("should not bound the size without a max size", func(t *testing.T) {
+		s, err := NewSpool(t.TempDir(), WithSegmentSize(48), WithMaxSize(NoMaxSize))
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer s.Close()
+
+		for i := range 6 {
+			if err := s.Append(fmt.Appendf(nil, "entry number %02d", i)); err != nil {
+				t.Fatalf("expected no error, but got %v", err)
+			}
+		}
+
+		if s.Dropped() != 0 {
+			t.Errorf("expected no dropped entries, but got %d", s.Dropped())
+		}
+		if got := drain(t, s); len(got) != 6 {
+			t.Errorf("expected every entry, but got %v", got)
+		}
+	})
+
+	t.Run("should not ack the entry after a peeked one that was dropped", func(t *testing.T) {
+		s, _ := NewSpool(t.TempDir(), WithSegmentSize(48), WithMaxSize(96))
+		defer s.Close()
+
+		for i := range 4 {
+			s.Append(fmt.Appendf(nil, "entry number %02d", i))
+		}
+		s.Peek()
+		s.Append([]byte("entry number 04")) // drops the segment being read
+
+		if err := s.Ack(); !errors.Is(err, ErrNotPeeked) {
+			t.Errorf("expected error to be %v, but got %v", ErrNotPeeked, err)
+		}
+		if got := drain(t, s); len(got) != 3 || got[0] != "entry number 02" {
+			t.Errorf("expected the entries after the dropped segment, but got %v", got)
+		}
+	})
+
+	t.Run("should skip the segment of a corrupted record", func(t *testing.T) {
+		folder := t.TempDir()
+		s, _ := NewSpool(folder, WithSegmentSize(32), WithMaxSize(1024))
+		defer s.Close()
+
+		s.Append([]byte("entry-a"))
+		s.Append([]byte("entry-b"))
+		s.Append([]byte("entry-c")) // in the next segment
+
+		path := filepath.Join(folder, fmt.Sprintf(segmentPattern, 1))
+		f, _ := os.OpenFile(path, os.O_WRONLY, 0644)
+		f.WriteAt([]byte("x"), recordHeader)
+		f.Close()
+
+		if got := drain(t, s); len(got) != 1 || got[0] != "entry-c" {
+			t.Errorf("expected the entries after the corrupted segment, but got %v", got)
+		}
+		if s.Dropped() != 2 {
+			t.Errorf("expected 2 dropped entries, but got %d", s.Dropped())
+		}
+	})
+
+	t.Run("should reject a length beyond the segment", func(t *testing.T) {
+		folder := t.TempDir()
+		s, _ := NewSpool(folder)
+		defer s.Close()
+
+		s.Append([]byte("entry-a"))
+
+		path := filepath.Join(folder, fmt.Sprintf(segmentPattern, 1))
+		f, _ := os.OpenFile(path, os.O_WRONLY, 0644)
+		f.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, 0)
+		f.Close()
+
+		if _, err := s.Peek(); !errors.Is(err, ErrEmpty) {
+			t.Errorf("expected error to be %v, but got %v", ErrEmpty, err)
+		}
+
+		s.Append([]byte("entry-b")) // in the same segment, after the lost one
+		if got := drain(t, s); len(got) != 1 || got[0] != "entry-b" {
+			t.Errorf("expected only the entry after the corrupted one, but got %v", got)
+		}
+	})
+
+	t.Run("should reject entries larger than a segment", func(t *testing.T) {
+		s, _ := NewSpool(t.TempDir(), WithSegmentSize(16), WithMaxSize(64))
+		defer s.Close()
+
+		if err := s.Append(make([]byte, 32)); !errors.Is(err, ErrEntryTooLarge) {
+			t.Errorf("expected error to be %v, but got %v", ErrEntryTooLarge, err)
+		}
+	})
+
+	t.Run("should refuse operations after close", func(t *testing.T) {
+		s, _ := NewSpool(t.TempDir())
+		s.Close()
+
+		if err := s.Append([]byte("a")); !errors.Is(err, ErrSpoolClosed) {
+			t.Errorf("expected error to be %v, but got %v", ErrSpoolClosed, err)
+		}
+		if _, err := s.Peek(); !errors.Is(err, ErrSpoolClosed) {
+			t.Errorf("expected error to be %v, but got %v", ErrSpoolClosed, err)
+		}
+	})
+}
+
+func TestSpoolRestart(t *testing.T) {
+	t.Run("should keep the unread entries across restarts", func(t *testing.T) {
+		folder := t.TempDir()
+
+		s, _ := NewSpool(folder, WithSegmentSize(64), WithMaxSize(1024))
+		for i := range 6 {
+			s.Append(fmt.Appendf(nil, "entry number %02d", i))
+		}
+		for range 3 {
+			s.Peek()
+			s.Ack()
+		}
+		s.Close()
+
+		s, err := NewSpool(folder, WithSegmentSize(64), WithMaxSize(1024))
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer s.Close()
+
+		if s.Len() != 3 {
+			t.Errorf("expected 3 entries, but got %d", s.Len())
+		}
+
+		s.Append([]byte("after restart"))
+		got := drain(t, s)
+		if len(got) != 4 || got[0] != "entry number 03" || got[3] != "after restart" {
+			t.Errorf("expected the unread entries in order, but got %v", got)
+		}
+	})
+
+	t.Run("should truncate a partial record", func(t *testing.T) {
+		folder := t.TempDir()
+
+		s, _ := NewSpool(folder)
+		s.Append([]byte("complete"))
+		s.Close()
+
+		path := filepath.Join(folder, fmt.Sprintf(segmentPattern, 1))
+		f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		f.Write([]byte{50, 0, 0, 0, 1, 2}) // a header without its entry
+		f.Close()
+
+		s, err := NewSpool(folder)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer s.Close()
+
+		s.Append([]byte("next"))
+		got := drain(t, s)
+		if len(got) != 2 || got[0] != "complete" || got[1] != "next" {
+			t.Errorf("expected the valid entries, but got %v", got)
+		}
+	})
+
+	t.Run("should continue after every entry was read", func(t *testing.T) {
+		folder := t.TempDir()
+
+		s, _ := NewSpool(folder)
+		s.Append([]byte("a"))
+		drain(t, s)
+		s.Close()
+
+		s, _ = NewSpool(folder)
+		defer s.Close()
+
+		if s.Len() != 0 {
+			t.Errorf("expected no entries, but got %d", s.Len())
+		}
+		s.Append([]byte("b"))
+		if got := drain(t, s); len(got) != 1 || got[0] != "b" {
+			t.Errorf("expected only the new entry, but got %v", got)
+		}
+	})
+}
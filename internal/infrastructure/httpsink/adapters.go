@@ -0,0 +1,185 @@
+package httpsink
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+)
+
+// IAdapter turns a batch of entries into the body of a request for a specific endpoint.
+type IAdapter interface {
+	Encode(entries [][]byte) ([]byte, error)
+	ContentType() string
+}
+
+// responseChecker is implemented by the adapters of endpoints that report
+// failures in the body of a successful response.
+type responseChecker interface {
+	CheckResponse(body []byte) error
+}
+
+type ndjsonAdapter struct{}
+
+// NewNDJSONAdapter sends the entries as newline delimited JSON, one entry per line.
+func NewNDJSONAdapter() IAdapter {
+	return ndjsonAdapter{}
+}
+
+func (ndjsonAdapter) Encode(entries [][]byte) ([]byte, error) {
+	var b bytes.Buffer
+	for _, e := range entries {
+		b.Write(bytes.TrimRight(e, "\n"))
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+func (ndjsonAdapter) ContentType() string {
+	return "application/x-ndjson"
+}
+
+type lokiAdapter struct {
+	labels []string
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+// NewLokiAdapter sends the entries to the Loki push API (/loki/api/v1/push).
+// The given fields become the labels of the streams, "level" when none is given;
+// keep them few and with low cardinality, as Loki recommends.
+func NewLokiAdapter(labels ...string) IAdapter {
+	if len(labels) == 0 {
+		labels = []string{"level"}
+	}
+	return &lokiAdapter{labels: slices.Clone(labels)}
+}
+
+func (l *lokiAdapter) Encode(entries [][]byte) ([]byte, error) {
+	var push lokiPush
+	streams := map[string]int{}
+
+	for _, line := range entries {
+		e, err := logengine.ParseEntry(line)
+		if err != nil {
+			return nil, err
+		}
+
+		labels := make(map[string]string, len(l.labels))
+		for _, key := range l.labels {
+			if value, ok := entryField(e, key); ok && value != "" {
+				labels[lokiLabel(key)] = value
+			}
+		}
+
+		id := labelsID(labels)
+		index, ok := streams[id]
+		if !ok {
+			index = len(push.Streams)
+			streams[id] = index
+			push.Streams = append(push.Streams, lokiStream{Stream: labels})
+		}
+
+		ts := e.Time
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		push.Streams[index].Values = append(push.Streams[index].Values, [2]string{
+			strconv.FormatInt(ts.UnixNano(), 10),
+			string(bytes.TrimRight(line, "\n")),
+		})
+	}
+
+	return json.Marshal(push)
+}
+
+func (l *lokiAdapter) ContentType() string {
+	return "application/json"
+}
+
+// lokiLabel keeps only the characters allowed in a label name.
+func lokiLabel(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+func labelsID(labels map[string]string) string {
+	var b strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		b.WriteString(key)
+		b.WriteByte(0)
+		b.WriteString(labels[key])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// entryField returns a field of the entry by its JSON key.
+func entryField(e logengine.Entry, key string) (string, bool) {
+	switch key {
+	case "level":
+		return e.Level.String(), true
+	case "package":
+		return e.Package, true
+	case "function":
+		return e.Function, true
+	case "file":
+		return e.File, true
+	}
+	value, ok := e.Fields[key]
+	return value, ok
+}
+
+type elasticsearchAdapter struct {
+	action []byte
+}
+
+// NewElasticsearchAdapter sends the entries to the Elasticsearch bulk API (/_bulk),
+// indexing every entry as a document of index.
+func NewElasticsearchAdapter(index string) IAdapter {
+	action, _ := json.Marshal(map[string]map[string]string{"create": {"_index": index}})
+	return &elasticsearchAdapter{action: action}
+}
+
+func (es *elasticsearchAdapter) Encode(entries [][]byte) ([]byte, error) {
+	var b bytes.Buffer
+	for _, e := range entries {
+		b.Write(es.action)
+		b.WriteByte('\n')
+		b.Write(bytes.TrimRight(e, "\n"))
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+func (es *elasticsearchAdapter) ContentType() string {
+	return "application/x-ndjson"
+}
+
+// CheckResponse fails when any document of the bulk request was rejected,
+// Elasticsearch answers 200 even in that case.
+func (es *elasticsearchAdapter) CheckResponse(body []byte) error {
+	var resp struct {
+		Errors bool `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || !resp.Errors {
+		return nil
+	}
+	return ErrBulkRejected
+}
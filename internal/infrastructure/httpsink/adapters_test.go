@@ -0,0 +1,90 @@
+package httpsink
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testEntries = [][]byte{
+	[]byte(`{"app":"api","time":"2025-06-17T10:00:00Z","level":"INFO","msg":"one"}` + "\n"),
+	[]byte(`{"app":"api","time":"2025-06-17T10:00:01Z","level":"ERROR","msg":"two"}` + "\n"),
+	[]byte(`{"app":"api","time":"2025-06-17T10:00:02Z","level":"INFO","msg":"three"}` + "\n"),
+}
+
+func TestNDJSONAdapter(t *testing.T) {
+	body, err := NewNDJSONAdapter().Encode(testEntries)
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], `"msg":"two"`) {
+		t.Errorf("expected one entry per line, but got %q", body)
+	}
+}
+
+func TestLokiAdapter(t *testing.T) {
+	t.Run("should group the entries in streams by their labels", func(t *testing.T) {
+		body, err := NewLokiAdapter("level", "app", "service-id").Encode(testEntries)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		var push lokiPush
+		if err := json.Unmarshal(body, &push); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		if len(push.Streams) != 2 {
+			t.Fatalf("expected 2 streams, but got %d", len(push.Streams))
+		}
+
+		info := push.Streams[0]
+		if info.Stream["level"] != "INFO" || info.Stream["app"] != "api" || len(info.Stream) != 2 {
+			t.Errorf("unexpected labels %v", info.Stream)
+		}
+		if len(info.Values) != 2 || info.Values[0][0] != "1750154400000000000" || info.Values[1][0] != "1750154402000000000" {
+			t.Errorf("unexpected values %v", info.Values)
+		}
+		if !strings.Contains(info.Values[0][1], `"msg":"one"`) || strings.HasSuffix(info.Values[0][1], "\n") {
+			t.Errorf("expected the line without the line feed, but got %q", info.Values[0][1])
+		}
+
+		if push.Streams[1].Stream["level"] != "ERROR" {
+			t.Errorf("unexpected labels %v", push.Streams[1].Stream)
+		}
+	})
+
+	t.Run("should sanitize the label names", func(t *testing.T) {
+		if got := lokiLabel("service-id.v2"); got != "service_id_v2" {
+			t.Errorf("unexpected label %q", got)
+		}
+	})
+}
+
+func TestElasticsearchAdapter(t *testing.T) {
+	t.Run("should encode the bulk request", func(t *testing.T) {
+		body, err := NewElasticsearchAdapter("logs").Encode(testEntries[:1])
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		expected := `{"create":{"_index":"logs"}}` + "\n" + strings.TrimSuffix(string(testEntries[0]), "\n") + "\n"
+		if string(body) != expected {
+			t.Errorf("expected body to be %q, but got %q", expected, body)
+		}
+	})
+
+	t.Run("should fail when an item is rejected", func(t *testing.T) {
+		checker := NewElasticsearchAdapter("logs").(responseChecker)
+
+		if err := checker.CheckResponse([]byte(`{"took":3,"errors":true,"items":[]}`)); !errors.Is(err, ErrBulkRejected) {
+			t.Errorf("expected error to be %v, but got %v", ErrBulkRejected, err)
+		}
+		if err := checker.CheckResponse([]byte(`{"took":3,"errors":false,"items":[]}`)); err != nil {
+			t.Errorf("expected no error, but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,10 @@
+package httpsink
+
+import "errors"
+
+var (
+	ErrInvalidEndpoint = errors.New("invalid HTTP endpoint")
+	ErrInvalidEntry    = errors.New("entry is not a JSON log")
+	ErrPostFailed      = errors.New("HTTP post failed")
+	ErrBulkRejected    = errors.New("bulk request has rejected items")
+)
@@ -0,0 +1,167 @@
+// Package httpsink ships the logs in batches to HTTP endpoints, such as Loki and Elasticsearch.
+package httpsink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/batchwriter"
+)
+
+const (
+	defaultTimeout     = 10 * time.Second
+	maxResponseBody    = 64 * 1024
+	maxErrorBodyLength = 512
+)
+
+type httpWriter struct {
+	batchwriter.IBatchWriter
+
+	endpoint  string
+	adapter   IAdapter
+	client    *http.Client
+	timeout   time.Duration
+	headers   map[string]string
+	gzip      bool
+	batchOpts []batchwriter.Option
+}
+
+type IHTTPWriter interface {
+	io.WriteCloser
+	Flush() error
+	Dropped() uint64
+}
+
+type Option func(h *httpWriter)
+
+// NewHTTPWriter creates a writer that posts the entries in batches to endpoint,
+// encoded by adapter.
+func NewHTTPWriter(endpoint string, adapter IAdapter, opts ...Option) (IHTTPWriter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidEndpoint, endpoint)
+	}
+
+	h := &httpWriter{
+		endpoint: endpoint,
+		adapter:  adapter,
+		client:   http.DefaultClient,
+		timeout:  defaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.IBatchWriter = batchwriter.NewBatchWriter(h.post, h.batchOpts...)
+
+	return h, nil
+}
+
+// WithGzip compresses the body of the requests.
+func WithGzip(enabled bool) Option {
+	return func(h *httpWriter) {
+		h.gzip = enabled
+	}
+}
+
+// WithHeaders adds headers to every request, e.g. the authorization or the Loki tenant.
+func WithHeaders(headers map[string]string) Option {
+	return func(h *httpWriter) {
+		h.headers = maps.Clone(headers)
+	}
+}
+
+// WithHTTPClient sets the client used to send the requests, http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) Option {
+	return func(h *httpWriter) {
+		h.client = client
+	}
+}
+
+// WithTimeout bounds every request.
+func WithTimeout(timeout time.Duration) Option {
+	return func(h *httpWriter) {
+		h.timeout = timeout
+	}
+}
+
+// WithBatching sets the options of the batches, see the batchwriter options.
+func WithBatching(opts ...batchwriter.Option) Option {
+	return func(h *httpWriter) {
+		h.batchOpts = append(h.batchOpts, opts...)
+	}
+}
+
+// Write adds the entry in p to the current batch.
+func (h *httpWriter) Write(p []byte) (int, error) {
+	if !json.Valid(p) {
+		return 0, ErrInvalidEntry
+	}
+	return h.IBatchWriter.Write(p)
+}
+
+// post sends a batch in a single request.
+func (h *httpWriter) post(entries [][]byte) error {
+	body, err := h.adapter.Encode(entries)
+	if err != nil {
+		return batchwriter.Permanent(err)
+	}
+
+	if h.gzip {
+		var b bytes.Buffer
+		zw := gzip.NewWriter(&b)
+		zw.Write(body)
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = b.Bytes()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return batchwriter.Permanent(err)
+	}
+	req.Header.Set("Content-Type", h.adapter.ContentType())
+	if h.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for key, value := range h.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if len(respBody) > maxErrorBodyLength {
+			respBody = respBody[:maxErrorBodyLength]
+		}
+		err := fmt.Errorf("%w: %s: %s", ErrPostFailed, resp.Status, bytes.TrimSpace(respBody))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return err
+		}
+		return batchwriter.Permanent(err)
+	}
+
+	if c, ok := h.adapter.(responseChecker); ok {
+		return batchwriter.Permanent(c.CheckResponse(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package httpsink
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/batchwriter"
+)
+
+// fakeEndpoint records the requests received and answers with the given statuses
+type fakeEndpoint struct {
+	lock     sync.Mutex
+	bodies   []string
+	headers  []http.Header
+	statuses []int
+	response string
+}
+
+func (f *fakeEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var reader io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reader = zr
+	}
+	body, _ := io.ReadAll(reader)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.bodies = append(f.bodies, string(body))
+	f.headers = append(f.headers, r.Header.Clone())
+
+	if len(f.statuses) > 0 {
+		status := f.statuses[0]
+		f.statuses = f.statuses[1:]
+		w.WriteHeader(status)
+	}
+	w.Write([]byte(f.response))
+}
+
+func newEndpoint(t *testing.T, statuses ...int) (*fakeEndpoint, string) {
+	t.Helper()
+	f := &fakeEndpoint{statuses: statuses}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv.URL
+}
+
+func TestHTTPWriter(t *testing.T) {
+	t.Run("should post the batch compressed with the headers", func(t *testing.T) {
+		f, endpoint := newEndpoint(t)
+
+		w, err := NewHTTPWriter(endpoint, NewNDJSONAdapter(),
+			WithGzip(true),
+			WithHeaders(map[string]string{"X-Scope-OrgID": "tenant"}),
+			WithBatching(batchwriter.WithInterval(time.Hour)),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer w.Close()
+
+		for _, e := range testEntries {
+			w.Write(e)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		if len(f.bodies) != 1 || strings.Count(f.bodies[0], "\n") != 3 {
+			t.Fatalf("expected one request with 3 entries, but got %q", f.bodies)
+		}
+		h := f.headers[0]
+		if h.Get("Content-Encoding") != "gzip" || h.Get("Content-Type") != "application/x-ndjson" || h.Get("X-Scope-OrgID") != "tenant" {
+			t.Errorf("unexpected headers %v", h)
+		}
+	})
+
+	t.Run("should split the entries in batches", func(t *testing.T) {
+		f, endpoint := newEndpoint(t)
+
+		w, _ := NewHTTPWriter(endpoint, NewNDJSONAdapter(), WithBatching(
+			batchwriter.WithBatchSize(2, 1024),
+			batchwriter.WithInterval(time.Hour),
+		))
+		defer w.Close()
+
+		for _, e := range testEntries {
+			w.Write(e)
+		}
+		w.Flush()
+
+		f.lock.Lock()
+		defer f.lock.Unlock()
+		if len(f.bodies) != 2 {
+			t.Errorf("expected 2 requests, but got %d", len(f.bodies))
+		}
+	})
+
+	t.Run("should retry the server errors", func(t *testing.T) {
+		f, endpoint := newEndpoint(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+		w, _ := NewHTTPWriter(endpoint, NewNDJSONAdapter(), WithBatching(
+			batchwriter.WithRetries(3, time.Millisecond),
+			batchwriter.WithInterval(time.Hour),
+		))
+		defer w.Close()
+
+		w.Write(testEntries[0])
+		if err := w.Flush(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		f.lock.Lock()
+		defer f.lock.Unlock()
+		if len(f.bodies) != 3 {
+			t.Errorf("expected 3 attempts, but got %d", len(f.bodies))
+		}
+	})
+
+	t.Run("should not retry the rejected requests", func(t *testing.T) {
+		f, endpoint := newEndpoint(t, http.StatusBadRequest)
+
+		w, _ := NewHTTPWriter(endpoint, NewNDJSONAdapter(), WithBatching(
+			batchwriter.WithRetries(3, time.Millisecond),
+			batchwriter.WithInterval(time.Hour),
+		))
+		defer w.Close()
+
+		w.Write(testEntries[0])
+		if err := w.Flush(); !errors.Is(err, ErrPostFailed) {
+			t.Errorf("expected error to be %v, but got %v", ErrPostFailed, err)
+		}
+
+		f.lock.Lock()
+		defer f.lock.Unlock()
+		if len(f.bodies) != 1 {
+			t.Errorf("expected a single attempt, but got %d", len(f.bodies))
+		}
+	})
+
+	t.Run("should fail when the bulk items are rejected", func(t *testing.T) {
+		f, endpoint := newEndpoint(t)
+		f.response = `{"errors":true,"items":[]}`
+
+		w, _ := NewHTTPWriter(endpoint+"/_bulk", NewElasticsearchAdapter("logs"), WithBatching(batchwriter.WithInterval(time.Hour)))
+		defer w.Close()
+
+		w.Write(testEntries[0])
+		if err := w.Flush(); !errors.Is(err, ErrBulkRejected) {
+			t.Errorf("expected error to be %v, but got %v", ErrBulkRejected, err)
+		}
+	})
+
+	t.Run("should deliver the last batch on close", func(t *testing.T) {
+		f, endpoint := newEndpoint(t)
+
+		w, _ := NewHTTPWriter(endpoint, NewLokiAdapter(), WithBatching(batchwriter.WithInterval(time.Hour)))
+		w.Write(testEntries[0])
+
+		if err := w.Close(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		f.lock.Lock()
+		defer f.lock.Unlock()
+		if len(f.bodies) != 1 || !strings.Contains(f.bodies[0], `"streams"`) {
+			t.Errorf("expected the Loki push, but got %q", f.bodies)
+		}
+	})
+
+	t.Run("should reject invalid endpoints and entries", func(t *testing.T) {
+		if _, err := NewHTTPWriter("localhost:3100", NewNDJSONAdapter()); !errors.Is(err, ErrInvalidEndpoint) {
+			t.Errorf("expected error to be %v, but got %v", ErrInvalidEndpoint, err)
+		}
+
+		_, endpoint := newEndpoint(t)
+		w, _ := NewHTTPWriter(endpoint, NewNDJSONAdapter())
+		defer w.Close()
+
+		if _, err := w.Write([]byte("not a log")); !errors.Is(err, ErrInvalidEntry) {
+			t.Errorf("expected error to be %v, but got %v", ErrInvalidEntry, err)
+		}
+	})
+}
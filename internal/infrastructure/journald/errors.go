@@ -0,0 +1,8 @@
+package journald
+
+import "errors"
+
+var (
+	ErrUnsupported  = errors.New("journald is only available on linux")
+	ErrWriterClosed = errors.New("journald writer is closed")
+)
@@ -0,0 +1,108 @@
+// Package journald sends the logs to the systemd journal using its native protocol.
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+	"github.com/IonicHealthUsa/ionlog/internal/infrastructure/syslog"
+)
+
+// DefaultSocket is where journald listens for native messages.
+const DefaultSocket = "/run/systemd/journal/socket"
+
+// maxFieldName is the longest field name journald accepts.
+const maxFieldName = 64
+
+// reservedPrefix is put before the static fields named like the fields the writer sets itself.
+const reservedPrefix = "FIELD_"
+
+// encode serializes the entry in the journal native format, one field per line.
+func encode(identifier string, e logengine.Entry) []byte {
+	var b bytes.Buffer
+
+	writeField(&b, "MESSAGE", e.Msg)
+	writeField(&b, "PRIORITY", strconv.Itoa(syslog.Severity(e.Level)))
+	if identifier != "" {
+		writeField(&b, "SYSLOG_IDENTIFIER", identifier)
+	}
+	if e.File != "" {
+		writeField(&b, "CODE_FILE", e.File)
+		writeField(&b, "CODE_LINE", strconv.Itoa(e.Line))
+		writeField(&b, "CODE_FUNC", codeFunc(e))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(e.Fields)) {
+		name := FieldName(key)
+		if name == "" {
+			continue
+		}
+		writeField(&b, name, e.Fields[key])
+	}
+
+	return b.Bytes()
+}
+
+// writeField writes NAME=value, or the binary form when the value has a line feed:
+// NAME, a line feed, the value length as a little endian uint64, the value and a line feed.
+func writeField(b *bytes.Buffer, name string, value string) {
+	b.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+
+	b.WriteByte('\n')
+	b.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(value))))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+func codeFunc(e logengine.Entry) string {
+	if e.Package == "" {
+		return e.Function
+	}
+	return e.Package + "." + e.Function
+}
+
+// FieldName converts a key into a valid journal field name: uppercase letters, digits and
+// underscores, not starting with an underscore (reserved for trusted fields) or a digit.
+// The names of the fields set by the writer, such as MESSAGE or CODE_FILE, get the FIELD_ prefix.
+// It returns an empty string when nothing is left.
+func FieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	name = strings.TrimLeft(name, "_0123456789")
+	if reserved(name) {
+		name = reservedPrefix + name
+	}
+	if len(name) > maxFieldName {
+		name = name[:maxFieldName]
+	}
+	return name
+}
+
+// reserved reports whether name is one of the fields the writer sets itself.
+func reserved(name string) bool {
+	switch name {
+	case "MESSAGE", "PRIORITY", "SYSLOG_IDENTIFIER":
+		return true
+	}
+	return strings.HasPrefix(name, "CODE_")
+}
@@ -0,0 +1,76 @@
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+)
+
+func TestFieldName(t *testing.T) {
+	cases := map[string]string{
+		"service-id":  "SERVICE_ID",
+		"Env":         "ENV",
+		"_hidden":     "HIDDEN",
+		"1st.attempt": "ST_ATTEMPT",
+		"ção":         "O",
+		"___":         "",
+		"message":     "FIELD_MESSAGE",
+		"Priority":    "FIELD_PRIORITY",
+		"code_file":   "FIELD_CODE_FILE",
+		"codec":       "CODEC",
+	}
+	for key, expected := range cases {
+		if got := FieldName(key); got != expected {
+			t.Errorf("expected field name of %q to be %q, but got %q", key, expected, got)
+		}
+	}
+
+	long := FieldName(string(bytes.Repeat([]byte("a"), 100)))
+	if len(long) != maxFieldName {
+		t.Errorf("expected the name to be truncated to %d characters, but got %d", maxFieldName, len(long))
+	}
+}
+
+func TestEncode(t *testing.T) {
+	t.Run("should encode the entry as journal fields", func(t *testing.T) {
+		e := logengine.Entry{
+			Level:    logengine.Error,
+			Msg:      "boom",
+			File:     "main.go",
+			Package:  "main",
+			Function: "run",
+			Line:     7,
+			Fields:   map[string]string{"service-id": "42", "env": "prod"},
+		}
+
+		got := string(encode("api", e))
+		expected := "MESSAGE=boom\n" +
+			"PRIORITY=3\n" +
+			"SYSLOG_IDENTIFIER=api\n" +
+			"CODE_FILE=main.go\n" +
+			"CODE_LINE=7\n" +
+			"CODE_FUNC=main.run\n" +
+			"ENV=prod\n" +
+			"SERVICE_ID=42\n"
+
+		if got != expected {
+			t.Errorf("expected message to be\n%q\nbut got\n%q", expected, got)
+		}
+	})
+
+	t.Run("should use the binary form for values with line feeds", func(t *testing.T) {
+		e := logengine.Entry{Level: logengine.Info, Msg: "line 1\nline 2"}
+
+		var expected bytes.Buffer
+		expected.WriteString("MESSAGE\n")
+		binary.Write(&expected, binary.LittleEndian, uint64(13))
+		expected.WriteString("line 1\nline 2\n")
+		expected.WriteString("PRIORITY=6\n")
+
+		if got := encode("", e); !bytes.Equal(got, expected.Bytes()) {
+			t.Errorf("expected message to be %q, but got %q", expected.Bytes(), got)
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package journald
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+)
+
+type journaldWriter struct {
+	identifier string
+	socket     string
+
+	conn      *net.UnixConn
+	addr      *net.UnixAddr
+	closed    bool
+	writeLock sync.Mutex
+}
+
+type IJournaldWriter interface {
+	io.WriteCloser
+}
+
+type Option func(j *journaldWriter)
+
+// NewJournaldWriter opens a datagram socket to journald.
+func NewJournaldWriter(opts ...Option) (IJournaldWriter, error) {
+	j := &journaldWriter{
+		identifier: filepath.Base(os.Args[0]),
+		socket:     DefaultSocket,
+	}
+
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	conn, err := unboundSocket()
+	if err != nil {
+		return nil, err
+	}
+
+	j.conn = conn
+	j.addr = &net.UnixAddr{Name: j.socket, Net: "unixgram"}
+
+	return j, nil
+}
+
+// WithIdentifier sets SYSLOG_IDENTIFIER, the program name by default.
+func WithIdentifier(identifier string) Option {
+	return func(j *journaldWriter) {
+		j.identifier = identifier
+	}
+}
+
+// WithSocket sets the journald socket path, DefaultSocket by default.
+func WithSocket(path string) Option {
+	return func(j *journaldWriter) {
+		j.socket = path
+	}
+}
+
+// Write sends the entry in p to the journal. An entry too large for a datagram is
+// written to a memory file whose descriptor is sent instead, as journald expects.
+func (j *journaldWriter) Write(p []byte) (int, error) {
+	e, err := logengine.ParseEntry(p)
+	if err != nil {
+		return 0, err
+	}
+	msg := encode(j.identifier, e)
+
+	j.writeLock.Lock()
+	defer j.writeLock.Unlock()
+
+	if j.closed {
+		return 0, ErrWriterClosed
+	}
+
+	_, _, err = j.conn.WriteMsgUnix(msg, nil, j.addr)
+	if err == nil {
+		return len(p), nil
+	}
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return 0, err
+	}
+
+	if err := j.writeFd(msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeFd sends msg through a file descriptor.
+func (j *journaldWriter) writeFd(msg []byte) error {
+	f, err := tempFile(msg)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, _, err = j.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), j.addr)
+	return err
+}
+
+func (j *journaldWriter) Close() error {
+	j.writeLock.Lock()
+	defer j.writeLock.Unlock()
+
+	if j.closed {
+		return nil
+	}
+	j.closed = true
+
+	return j.conn.Close()
+}
+
+// tempFile returns a sealed memfd with the content of msg, or an unlinked file in /dev/shm
+// when memfd is not available. Other folders may be on disk, so there is no further fallback.
+func tempFile(msg []byte) (*os.File, error) {
+	if f, err := memfd(msg); err == nil {
+		return f, nil
+	}
+
+	f, err := os.CreateTemp("/dev/shm", "ionlog-journal-")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(f.Name())
+
+	if _, err := f.Write(msg); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// unboundSocket creates a datagram socket that is not bound nor connected,
+// so it keeps working when journald restarts.
+func unboundSocket() (*net.UnixConn, error) {
+	fd, err := syscall.Socket(syscall.AF_UNIX, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	f := os.NewFile(uintptr(fd), "journald")
+	defer f.Close()
+
+	conn, err := net.FileConn(f)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*net.UnixConn), nil
+}
+
+const (
+	mfdCloexec      = 0x1
+	mfdAllowSealing = 0x2
+	fAddSeals       = 1033
+	sealAll         = 0x1 | 0x2 | 0x4 | 0x8 // F_SEAL_SEAL, SHRINK, GROW and WRITE
+)
+
+func memfd(msg []byte) (*os.File, error) {
+	if sysMemfdCreate == 0 {
+		return nil, syscall.ENOSYS
+	}
+
+	name, err := syscall.BytePtrFromString("ionlog-journal")
+	if err != nil {
+		return nil, err
+	}
+
+	fd, _, errno := syscall.Syscall(uintptr(sysMemfdCreate), uintptr(unsafe.Pointer(name)), mfdCloexec|mfdAllowSealing, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	f := os.NewFile(fd, "ionlog-journal")
+	if _, err := f.Write(msg); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, fAddSeals, sealAll); errno != 0 {
+		f.Close()
+		return nil, errno
+	}
+	return f, nil
+}
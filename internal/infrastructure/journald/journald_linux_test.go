@@ -0,0 +1,142 @@
+package journald
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const testLine = `{"service-id":"42","time":"2025-06-17T10:00:00Z","level":"WARN","msg":"disk almost full","file":"main.go","package":"main","function":"run","line":"7"}` + "\n"
+
+func listen(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, path
+}
+
+func TestJournaldWriter(t *testing.T) {
+	t.Run("should send the entry as a datagram", func(t *testing.T) {
+		conn, path := listen(t)
+
+		w, err := NewJournaldWriter(WithSocket(path), WithIdentifier("api"))
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer w.Close()
+
+		if n, err := w.Write([]byte(testLine)); err != nil || n != len(testLine) {
+			t.Fatalf("expected %d bytes and no error, but got %d and %v", len(testLine), n, err)
+		}
+
+		buf := make([]byte, 4096)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		got := string(buf[:n])
+		for _, field := range []string{"MESSAGE=disk almost full\n", "PRIORITY=4\n", "SYSLOG_IDENTIFIER=api\n", "CODE_LINE=7\n", "SERVICE_ID=42\n"} {
+			if !strings.Contains(got, field) {
+				t.Errorf("expected message to contain %q, but got %q", field, got)
+			}
+		}
+	})
+
+	t.Run("should send large entries through a file descriptor", func(t *testing.T) {
+		conn, path := listen(t)
+
+		w, err := NewJournaldWriter(WithSocket(path))
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer w.Close()
+
+		msg := strings.Repeat("x", 1<<20)
+		line := `{"level":"INFO","msg":"` + msg + `"}`
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		oob := make([]byte, syscall.CmsgSpace(4))
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, oobn, _, _, err := conn.ReadMsgUnix(make([]byte, 16), oob)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil || len(msgs) != 1 {
+			t.Fatalf("expected one control message, but got %d and %v", len(msgs), err)
+		}
+		fds, err := syscall.ParseUnixRights(&msgs[0])
+		if err != nil || len(fds) != 1 {
+			t.Fatalf("expected one file descriptor, but got %d and %v", len(fds), err)
+		}
+
+		f := os.NewFile(uintptr(fds[0]), "journal")
+		defer f.Close()
+
+		f.Seek(0, io.SeekStart)
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if !strings.HasPrefix(string(data), "MESSAGE="+msg+"\n") {
+			t.Errorf("expected the file to hold the message, but got %d bytes", len(data))
+		}
+	})
+
+	t.Run("should refuse writes after close", func(t *testing.T) {
+		_, path := listen(t)
+
+		w, err := NewJournaldWriter(WithSocket(path))
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		w.Close()
+
+		if _, err := w.Write([]byte(testLine)); !errors.Is(err, ErrWriterClosed) {
+			t.Errorf("expected error to be %v, but got %v", ErrWriterClosed, err)
+		}
+	})
+
+	t.Run("should fail when journald is not listening", func(t *testing.T) {
+		w, err := NewJournaldWriter(WithSocket(filepath.Join(t.TempDir(), "missing.sock")))
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer w.Close()
+
+		if _, err := w.Write([]byte(testLine)); err == nil {
+			t.Error("expected an error, but got nil")
+		}
+	})
+}
+
+func TestTempFile(t *testing.T) {
+	f, err := tempFile([]byte("MESSAGE=hi\n"))
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	defer f.Close()
+
+	f.Seek(0, io.SeekStart)
+	data, _ := io.ReadAll(f)
+	if string(data) != "MESSAGE=hi\n" {
+		t.Errorf("unexpected content %q", data)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package journald
+
+import "io"
+
+type IJournaldWriter interface {
+	io.WriteCloser
+}
+
+type Option func(j *journaldWriter)
+
+type journaldWriter struct{}
+
+// NewJournaldWriter always fails, journald exists only on linux.
+func NewJournaldWriter(opts ...Option) (IJournaldWriter, error) {
+	return nil, ErrUnsupported
+}
+
+func WithIdentifier(identifier string) Option {
+	return func(j *journaldWriter) {}
+}
+
+func WithSocket(path string) Option {
+	return func(j *journaldWriter) {}
+}
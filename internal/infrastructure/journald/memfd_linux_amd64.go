@@ -0,0 +1,3 @@
+package journald
+
+const sysMemfdCreate = 319
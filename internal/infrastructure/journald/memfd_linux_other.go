@@ -0,0 +1,6 @@
+//go:build linux && !amd64 && !arm64
+
+package journald
+
+// sysMemfdCreate is unknown on this architecture, the writer falls back to a temporary file.
+const sysMemfdCreate = 0
@@ -2,44 +2,124 @@
 package memory
 
 import (
+	"container/list"
 	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/cespare/xxhash"
 )
 
+// DefaultCapacity is how many records are kept before the least recently used is evicted.
+const DefaultCapacity = 10000
+
 type recordUnity struct {
 	MsgHash uint64
+
+	lastSeen time.Time
+	elem     *list.Element
+
+	sampleLock sync.Mutex
+	sample     SampleState
+}
+
+// SampleState is the sampling state of a call site.
+type SampleState struct {
+	WindowStart time.Time
+	InWindow    uint64
+	Total       uint64
+	Suppressed  uint64
 }
 
+// recordMemory keeps the records in a map, ordered from the most to the least
+// recently used in a list. When the capacity is reached the least recently used
+// record is evicted, and a record not used for the TTL expires.
 type recordMemory struct {
 	records map[uint64]*recordUnity
+	lru     *list.List
 	mu      sync.Mutex
+
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+
+	hits        uint64
+	misses      uint64
+	evictions   uint64
+	expirations uint64
+}
+
+// Stats is a snapshot of the counters of a record memory.
+type Stats struct {
+	Records     int
+	Capacity    int
+	TTL         time.Duration
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
 }
 
 type IRecordUnity interface {
 	GetMsgHash() uint64
 	SetMsgHash(msg uint64)
+	Sample(decide func(state *SampleState) bool) bool
 }
 
 type IRecordMemory interface {
 	AddRecord(id uint64, msg string) error
 	RemoveRecord(id uint64)
 	GetRecord(id uint64) IRecordUnity
+	GetOrAddRecord(id uint64) IRecordUnity
+	SetLimits(capacity int, ttl time.Duration)
+	Stats() Stats
+}
+
+type Option func(r *recordMemory)
+
+func NewRecordMemory(opts ...Option) IRecordMemory {
+	r := &recordMemory{
+		records:  make(map[uint64]*recordUnity),
+		lru:      list.New(),
+		capacity: DefaultCapacity,
+		now:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
 }
 
-func NewRecordMemory() IRecordMemory {
-	return &recordMemory{
-		records: make(map[uint64]*recordUnity),
+// WithCapacity bounds the number of records, zero means no bound.
+func WithCapacity(capacity int) Option {
+	return func(r *recordMemory) {
+		r.capacity = capacity
 	}
 }
 
-func (r recordUnity) GetMsgHash() uint64 {
-	return r.MsgHash
+// WithTTL makes a record expire when it is not used for ttl, zero means never.
+func WithTTL(ttl time.Duration) Option {
+	return func(r *recordMemory) {
+		r.ttl = ttl
+	}
+}
+
+func (r *recordUnity) GetMsgHash() uint64 {
+	return atomic.LoadUint64(&r.MsgHash)
 }
 
 func (r *recordUnity) SetMsgHash(msg uint64) {
-	r.MsgHash = msg
+	atomic.StoreUint64(&r.MsgHash, msg)
+}
+
+// Sample runs decide with the sampling state of the record locked, and returns its result.
+func (r *recordUnity) Sample(decide func(state *SampleState) bool) bool {
+	r.sampleLock.Lock()
+	defer r.sampleLock.Unlock()
+	return decide(&r.sample)
 }
 
 func GenHash(s string) uint64 {
@@ -47,7 +127,7 @@ func GenHash(s string) uint64 {
 }
 
 func (r *recordMemory) AddRecord(id uint64, msg string) error {
-	if r.readRecord(id) != nil {
+	if r.peekRecord(id) != nil {
 		return ErrRecordIDCollision
 	}
 	r.writeRecord(
@@ -60,13 +140,14 @@ func (r *recordMemory) AddRecord(id uint64, msg string) error {
 }
 
 func (r *recordMemory) RemoveRecord(id uint64) {
-	if r.GetRecord(id) == nil {
+	if r.peekRecord(id) == nil {
 		slog.Debug("Trying to remove non-existing record")
 		return
 	}
 	r.deleteRecord(id)
 }
 
+// GetRecord returns the record and marks it as used, so its TTL starts again.
 func (r *recordMemory) GetRecord(id uint64) IRecordUnity {
 	record := r.readRecord(id)
 	if record == nil {
@@ -77,20 +158,152 @@ func (r *recordMemory) GetRecord(id uint64) IRecordUnity {
 	return record
 }
 
+// GetOrAddRecord returns the record, adding an empty one when it does not exist.
+func (r *recordMemory) GetOrAddRecord(id uint64) IRecordUnity {
+	if record := r.readRecord(id); record != nil {
+		return record
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// another goroutine may have added it meanwhile
+	if record := r.records[id]; record != nil {
+		r.touch(id, record, r.now())
+		return record
+	}
+
+	record := &recordUnity{}
+	r.records[id] = record
+	r.touch(id, record, r.now())
+	r.evict()
+	return record
+}
+
+// SetLimits changes the capacity and the TTL, evicting the records over the new capacity.
+func (r *recordMemory) SetLimits(capacity int, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.capacity = capacity
+	r.ttl = ttl
+	r.evict()
+}
+
+func (r *recordMemory) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return Stats{
+		Records:     len(r.records),
+		Capacity:    r.capacity,
+		TTL:         r.ttl,
+		Hits:        r.hits,
+		Misses:      r.misses,
+		Evictions:   r.evictions,
+		Expirations: r.expirations,
+	}
+}
+
 func (r *recordMemory) readRecord(id uint64) *recordUnity {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	return r.records[id]
+
+	now := r.now()
+	record := r.lookup(id, now)
+	if record == nil {
+		r.misses++
+		return nil
+	}
+
+	r.hits++
+	r.touch(id, record, now)
+	return record
+}
+
+// peekRecord returns the record without counting a hit or a miss and without marking it as used.
+func (r *recordMemory) peekRecord(id uint64) *recordUnity {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.lookup(id, r.now())
+}
+
+// lookup returns the record, removing it when it expired, it must be called with the lock held.
+func (r *recordMemory) lookup(id uint64, now time.Time) *recordUnity {
+	record := r.records[id]
+	if record == nil {
+		return nil
+	}
+	if r.expired(record, now) {
+		r.remove(id, record)
+		r.expirations++
+		return nil
+	}
+	return record
 }
 
 func (r *recordMemory) writeRecord(id uint64, req *recordUnity) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+
+	if old := r.records[id]; old != nil {
+		r.remove(id, old)
+	}
+
 	r.records[id] = req
+	r.touch(id, req, r.now())
+	r.evict()
 }
 
 func (r *recordMemory) deleteRecord(id uint64) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+
+	if record := r.records[id]; record != nil {
+		r.remove(id, record)
+	}
+}
+
+// touch moves the record to the front of the list, it must be called with the lock held.
+func (r *recordMemory) touch(id uint64, record *recordUnity, now time.Time) {
+	record.lastSeen = now
+	if record.elem == nil {
+		record.elem = r.lru.PushFront(id)
+		return
+	}
+	r.lru.MoveToFront(record.elem)
+}
+
+func (r *recordMemory) remove(id uint64, record *recordUnity) {
+	if record.elem != nil {
+		r.lru.Remove(record.elem)
+		record.elem = nil
+	}
 	delete(r.records, id)
 }
+
+func (r *recordMemory) expired(record *recordUnity, now time.Time) bool {
+	return r.ttl > 0 && !record.lastSeen.IsZero() && now.Sub(record.lastSeen) > r.ttl
+}
+
+// evict removes the expired records from the back of the list and
+// the least recently used ones over the capacity.
+func (r *recordMemory) evict() {
+	now := r.now()
+
+	for back := r.lru.Back(); back != nil; back = r.lru.Back() {
+		id := back.Value.(uint64)
+		record := r.records[id]
+
+		switch {
+		case r.expired(record, now):
+			r.expirations++
+		case r.capacity > 0 && len(r.records) > r.capacity:
+			r.evictions++
+		default:
+			return
+		}
+		r.remove(id, record)
+	}
+}
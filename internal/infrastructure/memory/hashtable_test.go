@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"sync"
 	"testing"
 	"time"
 )
@@ -327,3 +328,123 @@ func TestDeleteRecord(t *testing.T) {
 		r.mu.Unlock()
 	})
 }
+
+func TestCapacity(t *testing.T) {
+	r := NewRecordMemory(WithCapacity(2))
+
+	r.AddRecord(1, "a")
+	r.AddRecord(2, "b")
+	r.GetRecord(1) // 2 becomes the least recently used
+	r.AddRecord(3, "c")
+
+	if r.GetRecord(2) != nil {
+		t.Error("expected the least recently used record to be evicted")
+	}
+	if r.GetRecord(1) == nil || r.GetRecord(3) == nil {
+		t.Error("expected the recently used records to be kept")
+	}
+
+	st := r.Stats()
+	if st.Records != 2 || st.Evictions != 1 {
+		t.Errorf("expected 2 records and 1 eviction, got %+v", st)
+	}
+
+	r.SetLimits(1, 0)
+	if st := r.Stats(); st.Records != 1 || st.Evictions != 2 {
+		t.Errorf("expected SetLimits to evict down to the new capacity, got %+v", st)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	r := NewRecordMemory(WithTTL(time.Minute)).(*recordMemory)
+	r.now = func() time.Time { return now }
+
+	r.AddRecord(1, "a")
+	r.AddRecord(2, "b")
+
+	now = now.Add(50 * time.Second)
+	if r.GetRecord(1) == nil {
+		t.Fatal("expected the record to be alive before the TTL")
+	}
+
+	now = now.Add(50 * time.Second)
+	if r.GetRecord(1) == nil {
+		t.Error("expected the access to refresh the TTL")
+	}
+	if r.GetRecord(2) != nil {
+		t.Error("expected the unused record to expire")
+	}
+
+	now = now.Add(2 * time.Minute)
+	r.AddRecord(3, "c")
+	if _, ok := r.records[1]; ok {
+		t.Error("expected the expired records to be swept on insert")
+	}
+
+	st := r.Stats()
+	if st.Records != 1 || st.Expirations != 2 {
+		t.Errorf("expected 1 record and 2 expirations, got %+v", st)
+	}
+}
+
+func TestStatsOfAddAndRemove(t *testing.T) {
+	now := time.Unix(0, 0)
+	r := NewRecordMemory(WithTTL(time.Minute)).(*recordMemory)
+	r.now = func() time.Time { return now }
+
+	r.AddRecord(1, "a")
+	if err := r.AddRecord(1, "b"); err != ErrRecordIDCollision {
+		t.Errorf("expected a collision, got %v", err)
+	}
+
+	now = now.Add(50 * time.Second)
+	r.RemoveRecord(2)
+	r.AddRecord(2, "c")
+	r.RemoveRecord(2)
+
+	if st := r.Stats(); st.Hits != 0 || st.Misses != 0 {
+		t.Errorf("expected no hits nor misses, got %+v", st)
+	}
+
+	now = now.Add(20 * time.Second)
+	if r.GetRecord(1) != nil {
+		t.Error("expected the collision check not to refresh the TTL")
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	r := NewRecordMemory(WithCapacity(64), WithTTL(time.Hour))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				id := uint64(i % 100)
+				if rec := r.GetRecord(id); rec != nil {
+					rec.SetMsgHash(uint64(g))
+					rec.GetMsgHash()
+				} else {
+					r.AddRecord(id, "msg")
+				}
+				if i%10 == 0 {
+					r.RemoveRecord(id)
+				}
+				r.Stats()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	st := r.Stats()
+	if st.Records > 64 {
+		t.Errorf("expected at most 64 records, got %d", st.Records)
+	}
+
+	_r := r.(*recordMemory)
+	if _r.lru.Len() != len(_r.records) {
+		t.Errorf("expected the LRU list and the map to match, got %d and %d", _r.lru.Len(), len(_r.records))
+	}
+}
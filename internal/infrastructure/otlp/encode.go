@@ -0,0 +1,220 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+)
+
+// The OTLP JSON encoding follows the protobuf JSON mapping: lowerCamelCase names,
+// 64 bit integers as strings and the trace and span IDs in hexadecimal.
+
+type jsonAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    string  `json:"intValue,omitempty"`
+}
+
+type jsonKeyValue struct {
+	Key   string       `json:"key"`
+	Value jsonAnyValue `json:"value"`
+}
+
+type jsonLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano,omitempty"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano,omitempty"`
+	SeverityNumber       int            `json:"severityNumber,omitempty"`
+	SeverityText         string         `json:"severityText,omitempty"`
+	Body                 jsonAnyValue   `json:"body"`
+	Attributes           []jsonKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type jsonScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type jsonScopeLogs struct {
+	Scope      jsonScope       `json:"scope"`
+	LogRecords []jsonLogRecord `json:"logRecords"`
+}
+
+type jsonResource struct {
+	Attributes []jsonKeyValue `json:"attributes,omitempty"`
+}
+
+type jsonResourceLogs struct {
+	Resource  jsonResource    `json:"resource"`
+	ScopeLogs []jsonScopeLogs `json:"scopeLogs"`
+}
+
+type jsonRequest struct {
+	ResourceLogs []jsonResourceLogs `json:"resourceLogs"`
+}
+
+func jsonAttributes(attrs []attribute) []jsonKeyValue {
+	kvs := make([]jsonKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		kv := jsonKeyValue{Key: a.key}
+		if a.isInt {
+			kv.Value.IntValue = a.value
+		} else {
+			kv.Value.StringValue = &a.value
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs
+}
+
+func encodeJSON(req exportRequest) ([]byte, error) {
+	records := make([]jsonLogRecord, 0, len(req.records))
+	for _, r := range req.records {
+		body := r.body
+		jr := jsonLogRecord{
+			ObservedTimeUnixNano: strconv.FormatUint(r.observedUnixNano, 10),
+			SeverityNumber:       r.severityNumber,
+			SeverityText:         r.severityText,
+			Body:                 jsonAnyValue{StringValue: &body},
+			Attributes:           jsonAttributes(r.attributes),
+			TraceID:              hex.EncodeToString(r.traceID),
+			SpanID:               hex.EncodeToString(r.spanID),
+		}
+		if r.timeUnixNano != 0 {
+			jr.TimeUnixNano = strconv.FormatUint(r.timeUnixNano, 10)
+		}
+		records = append(records, jr)
+	}
+
+	return json.Marshal(jsonRequest{
+		ResourceLogs: []jsonResourceLogs{{
+			Resource: jsonResource{Attributes: jsonAttributes(req.resource)},
+			ScopeLogs: []jsonScopeLogs{{
+				Scope:      jsonScope{Name: req.scope, Version: req.version},
+				LogRecords: records,
+			}},
+		}},
+	})
+}
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// The field numbers of opentelemetry/proto/collector/logs/v1 and its dependencies.
+const (
+	fieldRequestResourceLogs = 1
+
+	fieldResourceLogsResource  = 1
+	fieldResourceLogsScopeLogs = 2
+
+	fieldResourceAttributes = 1
+
+	fieldScopeLogsScope      = 1
+	fieldScopeLogsLogRecords = 2
+
+	fieldScopeName    = 1
+	fieldScopeVersion = 2
+
+	fieldRecordTime           = 1
+	fieldRecordSeverityNumber = 2
+	fieldRecordSeverityText   = 3
+	fieldRecordBody           = 5
+	fieldRecordAttributes     = 6
+	fieldRecordTraceID        = 9
+	fieldRecordSpanID         = 10
+	fieldRecordObservedTime   = 11
+
+	fieldKeyValueKey   = 1
+	fieldKeyValueValue = 2
+
+	fieldAnyValueString = 1
+	fieldAnyValueInt    = 3
+)
+
+// pb appends protobuf fields to a buffer, the nested messages are encoded
+// into their own buffer and appended as length delimited fields.
+type pb []byte
+
+func (b pb) tag(field int, wire int) pb {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wire))
+}
+
+func (b pb) varint(field int, v uint64) pb {
+	return binary.AppendUvarint(b.tag(field, wireVarint), v)
+}
+
+func (b pb) fixed64(field int, v uint64) pb {
+	return binary.LittleEndian.AppendUint64(b.tag(field, wireFixed64), v)
+}
+
+func (b pb) bytes(field int, v []byte) pb {
+	b = binary.AppendUvarint(b.tag(field, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+func (b pb) string(field int, v string) pb {
+	b = binary.AppendUvarint(b.tag(field, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+func pbAnyValue(a attribute) pb {
+	if a.isInt {
+		n, _ := strconv.ParseInt(a.value, 10, 64)
+		return pb(nil).varint(fieldAnyValueInt, uint64(n))
+	}
+	return pb(nil).string(fieldAnyValueString, a.value)
+}
+
+func pbKeyValue(a attribute) pb {
+	return pb(nil).
+		string(fieldKeyValueKey, a.key).
+		bytes(fieldKeyValueValue, pbAnyValue(a))
+}
+
+func pbLogRecord(r logRecord) pb {
+	var b pb
+	if r.timeUnixNano != 0 {
+		b = b.fixed64(fieldRecordTime, r.timeUnixNano)
+	}
+	b = b.varint(fieldRecordSeverityNumber, uint64(r.severityNumber))
+	b = b.string(fieldRecordSeverityText, r.severityText)
+	b = b.bytes(fieldRecordBody, pb(nil).string(fieldAnyValueString, r.body))
+	for _, a := range r.attributes {
+		b = b.bytes(fieldRecordAttributes, pbKeyValue(a))
+	}
+	if len(r.traceID) > 0 {
+		b = b.bytes(fieldRecordTraceID, r.traceID)
+	}
+	if len(r.spanID) > 0 {
+		b = b.bytes(fieldRecordSpanID, r.spanID)
+	}
+	return b.fixed64(fieldRecordObservedTime, r.observedUnixNano)
+}
+
+func encodeProtobuf(req exportRequest) []byte {
+	var resource pb
+	for _, a := range req.resource {
+		resource = resource.bytes(fieldResourceAttributes, pbKeyValue(a))
+	}
+
+	scope := pb(nil).string(fieldScopeName, req.scope)
+	if req.version != "" {
+		scope = scope.string(fieldScopeVersion, req.version)
+	}
+
+	scopeLogs := pb(nil).bytes(fieldScopeLogsScope, scope)
+	for _, r := range req.records {
+		scopeLogs = scopeLogs.bytes(fieldScopeLogsLogRecords, pbLogRecord(r))
+	}
+
+	resourceLogs := pb(nil).
+		bytes(fieldResourceLogsResource, resource).
+		bytes(fieldResourceLogsScopeLogs, scopeLogs)
+
+	return pb(nil).bytes(fieldRequestResourceLogs, resourceLogs)
+}
@@ -0,0 +1,9 @@
+package otlp
+
+import "errors"
+
+var (
+	ErrInvalidEndpoint = errors.New("invalid OTLP endpoint")
+	ErrInvalidEntry    = errors.New("entry is not a JSON log")
+	ErrExportFailed    = errors.New("OTLP export failed")
+)
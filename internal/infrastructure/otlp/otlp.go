@@ -0,0 +1,247 @@
+// Package otlp exports the logs as OpenTelemetry log records to a collector over HTTP.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/batchwriter"
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+)
+
+// Encoding is the payload format of the export requests.
+type Encoding int
+
+const (
+	// Protobuf sends application/x-protobuf payloads, the default of OTLP/HTTP.
+	Protobuf Encoding = iota
+	// JSON sends application/json payloads.
+	JSON
+)
+
+// DefaultEndpoint is the logs endpoint of a collector running on the same host.
+const DefaultEndpoint = "http://localhost:4318/v1/logs"
+
+const defaultTimeout = 10 * time.Second
+
+type otlpWriter struct {
+	batchwriter.IBatchWriter
+
+	endpoint    string
+	encoding    Encoding
+	headers     map[string]string
+	client      *http.Client
+	timeout     time.Duration
+	serviceName string
+	traceKey    string
+	spanKey     string
+	batchOpts   []batchwriter.Option
+
+	lock   sync.Mutex
+	static map[string]string
+}
+
+type IOTLPWriter interface {
+	io.WriteCloser
+	Flush() error
+	Dropped() uint64
+}
+
+type Option func(o *otlpWriter)
+
+// NewOTLPWriter creates a writer that exports the entries in batches to the collector
+// at endpoint, e.g. DefaultEndpoint. The static fields become resource attributes.
+func NewOTLPWriter(endpoint string, opts ...Option) (IOTLPWriter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidEndpoint, endpoint)
+	}
+
+	o := &otlpWriter{
+		endpoint: endpoint,
+		client:   http.DefaultClient,
+		timeout:  defaultTimeout,
+		traceKey: "trace_id",
+		spanKey:  "span_id",
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	o.IBatchWriter = batchwriter.NewBatchWriter(o.export, o.batchOpts...)
+
+	return o, nil
+}
+
+// WithEncoding sets the payload format, Protobuf by default.
+func WithEncoding(encoding Encoding) Option {
+	return func(o *otlpWriter) {
+		o.encoding = encoding
+	}
+}
+
+// WithHeaders adds headers to every request, e.g. the authorization of the collector.
+func WithHeaders(headers map[string]string) Option {
+	return func(o *otlpWriter) {
+		o.headers = maps.Clone(headers)
+	}
+}
+
+// WithHTTPClient sets the client used to send the requests, http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *otlpWriter) {
+		o.client = client
+	}
+}
+
+// WithTimeout bounds every export request.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *otlpWriter) {
+		o.timeout = timeout
+	}
+}
+
+// WithServiceName sets the service.name resource attribute.
+func WithServiceName(name string) Option {
+	return func(o *otlpWriter) {
+		o.serviceName = name
+	}
+}
+
+// WithTraceKeys sets the fields that hold the trace and span IDs, "trace_id" and "span_id" by default.
+func WithTraceKeys(traceKey string, spanKey string) Option {
+	return func(o *otlpWriter) {
+		o.traceKey = traceKey
+		o.spanKey = spanKey
+	}
+}
+
+// WithBatching sets the options of the batches, see the batchwriter options.
+func WithBatching(opts ...batchwriter.Option) Option {
+	return func(o *otlpWriter) {
+		o.batchOpts = append(o.batchOpts, opts...)
+	}
+}
+
+// Write adds the entry in p to the current batch.
+func (o *otlpWriter) Write(p []byte) (int, error) {
+	if !json.Valid(p) {
+		return 0, ErrInvalidEntry
+	}
+	return o.IBatchWriter.Write(p)
+}
+
+// SetStaticFields sets the fields exported as resource attributes.
+func (o *otlpWriter) SetStaticFields(fields map[string]string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.static = fields
+}
+
+// SetReporter sets the function called with the result of every delivery.
+func (o *otlpWriter) SetReporter(report func(n int, err error)) {
+	o.IBatchWriter.SetReporter(report)
+}
+
+// export sends a batch of entries in a single request.
+func (o *otlpWriter) export(entries [][]byte) error {
+	o.lock.Lock()
+	static := o.static
+	o.lock.Unlock()
+
+	req := exportRequest{
+		resource: o.resource(static),
+		scope:    "github.com/IonicHealthUsa/ionlog",
+		records:  make([]logRecord, 0, len(entries)),
+	}
+
+	now := time.Now()
+	for _, line := range entries {
+		e, err := logengine.ParseEntry(line)
+		if err != nil {
+			continue // Write accepts only valid JSON, it can not happen
+		}
+		req.records = append(req.records, o.newRecord(e, static, now))
+	}
+
+	var body []byte
+	var contentType string
+
+	switch o.encoding {
+	case JSON:
+		var err error
+		if body, err = encodeJSON(req); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	default:
+		body = encodeProtobuf(req)
+		contentType = "application/x-protobuf"
+	}
+
+	return o.post(body, contentType)
+}
+
+func (o *otlpWriter) resource(static map[string]string) []attribute {
+	attrs := make([]attribute, 0, len(static)+1)
+	if o.serviceName != "" {
+		attrs = append(attrs, attribute{key: "service.name", value: o.serviceName})
+	}
+	for _, key := range slices.Sorted(maps.Keys(static)) {
+		if key == "service.name" && o.serviceName != "" {
+			continue
+		}
+		attrs = append(attrs, attribute{key: key, value: static[key]})
+	}
+	return attrs
+}
+
+func (o *otlpWriter) post(body []byte, contentType string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range o.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+
+	err = fmt.Errorf("%w: %s: %s", ErrExportFailed, resp.Status, bytes.TrimSpace(msg))
+	if retryable(resp.StatusCode) {
+		return err
+	}
+	return batchwriter.Permanent(err)
+}
+
+// retryable reports whether the collector may accept the request later, as OTLP/HTTP defines.
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/batchwriter"
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+)
+
+const testLine = `{"service":"api","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","user":"42","time":"2025-06-17T10:00:00Z","level":"ERROR","msg":"boom","file":"main.go","package":"main","function":"run","line":"7"}`
+
+// fakeCollector records the requests received
+type fakeCollector struct {
+	lock     sync.Mutex
+	bodies   [][]byte
+	types    []string
+	headers  []http.Header
+	status   int
+	requests int
+}
+
+func (f *fakeCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.requests++
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		w.Write([]byte("unavailable"))
+		return
+	}
+	f.bodies = append(f.bodies, body)
+	f.types = append(f.types, r.Header.Get("Content-Type"))
+	f.headers = append(f.headers, r.Header.Clone())
+}
+
+func newCollector(t *testing.T) (*fakeCollector, string) {
+	t.Helper()
+	c := &fakeCollector{}
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+	return c, srv.URL + "/v1/logs"
+}
+
+func TestSeverityNumber(t *testing.T) {
+	cases := map[logengine.Level]int{
+		logengine.Trace: 1,
+		logengine.Debug: 5,
+		logengine.Info:  9,
+		logengine.Warn:  13,
+		logengine.Error: 17,
+		logengine.Panic: 21,
+		logengine.Fatal: 24,
+	}
+	for level, expected := range cases {
+		if got := SeverityNumber(level); got != expected {
+			t.Errorf("expected severity number of %v to be %d, but got %d", level, expected, got)
+		}
+	}
+}
+
+func TestOTLPWriterJSON(t *testing.T) {
+	t.Run("should export the batch as OTLP JSON", func(t *testing.T) {
+		c, endpoint := newCollector(t)
+
+		w, err := NewOTLPWriter(endpoint,
+			WithEncoding(JSON),
+			WithServiceName("checkout"),
+			WithHeaders(map[string]string{"Authorization": "Bearer token"}),
+			WithBatching(batchwriter.WithInterval(time.Hour)),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer w.Close()
+
+		w.(interface{ SetStaticFields(map[string]string) }).SetStaticFields(map[string]string{"service": "api"})
+
+		w.Write([]byte(testLine))
+		w.Write([]byte(testLine))
+		if err := w.Flush(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		if len(c.bodies) != 1 {
+			t.Fatalf("expected one request, but got %d", len(c.bodies))
+		}
+		if c.types[0] != "application/json" {
+			t.Errorf("expected the JSON content type, but got %q", c.types[0])
+		}
+		if c.headers[0].Get("Authorization") != "Bearer token" {
+			t.Errorf("expected the authorization header, but got %q", c.headers[0].Get("Authorization"))
+		}
+
+		var req jsonRequest
+		if err := json.Unmarshal(c.bodies[0], &req); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		resource := req.ResourceLogs[0].Resource.Attributes
+		if len(resource) != 2 || resource[0].Key != "service.name" || *resource[0].Value.StringValue != "checkout" || resource[1].Key != "service" {
+			t.Errorf("unexpected resource attributes %+v", resource)
+		}
+
+		records := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+		if len(records) != 2 {
+			t.Fatalf("expected 2 records, but got %d", len(records))
+		}
+
+		r := records[0]
+		if r.SeverityNumber != SeverityError || r.SeverityText != "ERROR" || *r.Body.StringValue != "boom" {
+			t.Errorf("unexpected record %+v", r)
+		}
+		if r.TimeUnixNano != "1750154400000000000" {
+			t.Errorf("unexpected time %q", r.TimeUnixNano)
+		}
+		if r.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || r.SpanID != "00f067aa0ba902b7" {
+			t.Errorf("unexpected trace context %q %q", r.TraceID, r.SpanID)
+		}
+
+		attrs := map[string]jsonAnyValue{}
+		for _, kv := range r.Attributes {
+			attrs[kv.Key] = kv.Value
+		}
+		if _, ok := attrs["service"]; ok {
+			t.Error("expected the static fields not to be record attributes")
+		}
+		if _, ok := attrs["trace_id"]; ok {
+			t.Error("expected the trace ID not to be a record attribute")
+		}
+		if v := attrs["user"]; v.StringValue == nil || *v.StringValue != "42" {
+			t.Errorf("expected the user attribute, but got %+v", v)
+		}
+		if v := attrs["code.lineno"]; v.IntValue != "7" {
+			t.Errorf("expected the line as an integer, but got %+v", v)
+		}
+	})
+
+	t.Run("should keep invalid trace IDs as attributes", func(t *testing.T) {
+		o := &otlpWriter{traceKey: "trace_id", spanKey: "span_id"}
+		e := logengine.Entry{Fields: map[string]string{"trace_id": "not-hex", "span_id": "0000000000000000"}}
+
+		r := o.newRecord(e, nil, time.Now())
+		if r.traceID != nil || r.spanID != nil || len(r.attributes) != 2 {
+			t.Errorf("expected the invalid IDs to stay as attributes, but got %+v", r)
+		}
+	})
+}
+
+// pbFields decodes a protobuf message into its fields, the length delimited
+// values are kept as bytes and the others as numbers.
+func pbFields(t *testing.T, b []byte) map[int][]any {
+	t.Helper()
+
+	fields := map[int][]any{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		b = b[n:]
+		field, wire := int(key>>3), int(key&7)
+
+		switch wire {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			b = b[n:]
+			fields[field] = append(fields[field], v)
+		case wireFixed64:
+			fields[field] = append(fields[field], binary.LittleEndian.Uint64(b))
+			b = b[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			b = b[n:]
+			fields[field] = append(fields[field], b[:size])
+			b = b[size:]
+		default:
+			t.Fatalf("unexpected wire type %d", wire)
+		}
+	}
+	return fields
+}
+
+func TestOTLPWriterProtobuf(t *testing.T) {
+	t.Run("should export the batch as OTLP protobuf", func(t *testing.T) {
+		c, endpoint := newCollector(t)
+
+		w, err := NewOTLPWriter(endpoint, WithBatching(batchwriter.WithInterval(time.Hour)))
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer w.Close()
+
+		w.(interface{ SetStaticFields(map[string]string) }).SetStaticFields(map[string]string{"service": "api"})
+		w.Write([]byte(testLine))
+		if err := w.Flush(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		if c.types[0] != "application/x-protobuf" {
+			t.Errorf("expected the protobuf content type, but got %q", c.types[0])
+		}
+
+		request := pbFields(t, c.bodies[0])
+		resourceLogs := pbFields(t, request[fieldRequestResourceLogs][0].([]byte))
+
+		resource := pbFields(t, resourceLogs[fieldResourceLogsResource][0].([]byte))
+		kv := pbFields(t, resource[fieldResourceAttributes][0].([]byte))
+		if string(kv[fieldKeyValueKey][0].([]byte)) != "service" {
+			t.Errorf("unexpected resource attribute %v", kv)
+		}
+
+		scopeLogs := pbFields(t, resourceLogs[fieldResourceLogsScopeLogs][0].([]byte))
+		record := pbFields(t, scopeLogs[fieldScopeLogsLogRecords][0].([]byte))
+
+		if record[fieldRecordTime][0].(uint64) != 1750154400000000000 {
+			t.Errorf("unexpected time %v", record[fieldRecordTime])
+		}
+		if record[fieldRecordSeverityNumber][0].(uint64) != SeverityError {
+			t.Errorf("unexpected severity %v", record[fieldRecordSeverityNumber])
+		}
+		body := pbFields(t, record[fieldRecordBody][0].([]byte))
+		if string(body[fieldAnyValueString][0].([]byte)) != "boom" {
+			t.Errorf("unexpected body %v", body)
+		}
+		if len(record[fieldRecordTraceID][0].([]byte)) != 16 || len(record[fieldRecordSpanID][0].([]byte)) != 8 {
+			t.Errorf("unexpected trace context %v %v", record[fieldRecordTraceID], record[fieldRecordSpanID])
+		}
+		if len(record[fieldRecordAttributes]) != 5 { // code.* and user
+			t.Errorf("expected 5 attributes, but got %d", len(record[fieldRecordAttributes]))
+		}
+	})
+}
+
+func TestOTLPWriterErrors(t *testing.T) {
+	t.Run("should reject invalid endpoints", func(t *testing.T) {
+		for _, endpoint := range []string{"", "localhost:4318", "ftp://collector/v1/logs"} {
+			if _, err := NewOTLPWriter(endpoint); !errors.Is(err, ErrInvalidEndpoint) {
+				t.Errorf("expected error to be %v for %q, but got %v", ErrInvalidEndpoint, endpoint, err)
+			}
+		}
+	})
+
+	t.Run("should reject lines that are not logs", func(t *testing.T) {
+		_, endpoint := newCollector(t)
+		w, _ := NewOTLPWriter(endpoint)
+		defer w.Close()
+
+		if _, err := w.Write([]byte("not a log")); !errors.Is(err, ErrInvalidEntry) {
+			t.Errorf("expected error to be %v, but got %v", ErrInvalidEntry, err)
+		}
+	})
+
+	t.Run("should report the rejected exports", func(t *testing.T) {
+		c, endpoint := newCollector(t)
+		c.status = http.StatusServiceUnavailable
+
+		w, _ := NewOTLPWriter(endpoint, WithBatching(
+			batchwriter.WithInterval(time.Hour),
+			batchwriter.WithRetries(1, time.Millisecond),
+		))
+		defer w.Close()
+
+		w.Write([]byte(testLine))
+		err := w.Flush()
+		if !errors.Is(err, ErrExportFailed) || !errors.Is(err, batchwriter.ErrUndelivered) {
+			t.Errorf("expected the export to fail, but got %v", err)
+		}
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		if c.requests != 2 {
+			t.Errorf("expected 2 attempts, but got %d", c.requests)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package otlp
+
+import (
+	"encoding/hex"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+)
+
+// Severity numbers of the OpenTelemetry log data model.
+const (
+	SeverityTrace = 1
+	SeverityDebug = 5
+	SeverityInfo  = 9
+	SeverityWarn  = 13
+	SeverityError = 17
+	SeverityFatal = 21
+)
+
+// SeverityNumber maps a log level to an OpenTelemetry severity number.
+func SeverityNumber(level logengine.Level) int {
+	switch level {
+	case logengine.Trace:
+		return SeverityTrace
+	case logengine.Debug:
+		return SeverityDebug
+	case logengine.Info:
+		return SeverityInfo
+	case logengine.Warn:
+		return SeverityWarn
+	case logengine.Error:
+		return SeverityError
+	case logengine.Panic:
+		return SeverityFatal
+	default:
+		return SeverityFatal + 3 // FATAL4, the most severe
+	}
+}
+
+// attribute is a key value pair, the value is an integer when isInt is set.
+type attribute struct {
+	key   string
+	value string
+	isInt bool
+}
+
+type logRecord struct {
+	timeUnixNano     uint64
+	observedUnixNano uint64
+	severityNumber   int
+	severityText     string
+	body             string
+	attributes       []attribute
+	traceID          []byte
+	spanID           []byte
+}
+
+// exportRequest is an ExportLogsServiceRequest with a single resource and scope.
+type exportRequest struct {
+	resource []attribute
+	scope    string
+	version  string
+	records  []logRecord
+}
+
+// newRecord converts an entry to a log record. The static fields belong to the resource,
+// so they are left out, and the trace context fields become the trace and span IDs.
+func (o *otlpWriter) newRecord(e logengine.Entry, static map[string]string, observed time.Time) logRecord {
+	r := logRecord{
+		observedUnixNano: uint64(observed.UnixNano()),
+		severityNumber:   SeverityNumber(e.Level),
+		severityText:     e.Level.String(),
+		body:             e.Msg,
+	}
+	if !e.Time.IsZero() {
+		r.timeUnixNano = uint64(e.Time.UnixNano())
+	}
+
+	if e.File != "" {
+		r.attributes = append(r.attributes,
+			attribute{key: "code.filepath", value: e.File},
+			attribute{key: "code.lineno", value: strconv.Itoa(e.Line), isInt: true},
+			attribute{key: "code.function", value: e.Function},
+			attribute{key: "code.namespace", value: e.Package},
+		)
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(e.Fields)) {
+		value := e.Fields[key]
+
+		switch key {
+		case o.traceKey:
+			if id, ok := decodeID(value, 16); ok {
+				r.traceID = id
+				continue
+			}
+		case o.spanKey:
+			if id, ok := decodeID(value, 8); ok {
+				r.spanID = id
+				continue
+			}
+		}
+
+		if _, ok := static[key]; ok {
+			continue
+		}
+		r.attributes = append(r.attributes, attribute{key: key, value: value})
+	}
+
+	return r
+}
+
+// decodeID decodes a hexadecimal trace or span ID of size bytes, an all zero ID is invalid.
+func decodeID(s string, size int) ([]byte, bool) {
+	id, err := hex.DecodeString(s)
+	if err != nil || len(id) != size {
+		return nil, false
+	}
+	for _, b := range id {
+		if b != 0 {
+			return id, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,9 @@
+package syslog
+
+import "errors"
+
+var (
+	ErrUnknownNetwork = errors.New("unknown syslog network")
+	ErrNoLocalSyslog  = errors.New("no local syslog socket found")
+	ErrWriterClosed   = errors.New("syslog writer is closed")
+)
@@ -0,0 +1,263 @@
+package syslog
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+)
+
+// Format is the syslog message format.
+type Format int
+
+const (
+	RFC5424 Format = iota
+	RFC3164
+)
+
+// Framing is how messages are delimited on stream transports (TCP, TLS and unix streams).
+type Framing int
+
+const (
+	// DefaultFraming uses octet counting on stream transports and no framing on datagrams.
+	DefaultFraming Framing = iota
+	// OctetCounting prefixes every message with its length (RFC 6587 3.4.1).
+	OctetCounting
+	// NonTransparent terminates every message with a line feed (RFC 6587 3.4.2).
+	NonTransparent
+	// NoFraming sends the message as is, one message per datagram.
+	NoFraming
+)
+
+// Facility is the syslog facility of the messages.
+type Facility int
+
+const (
+	Kern Facility = iota
+	User
+	Mail
+	Daemon
+	Auth
+	Syslog
+	Lpr
+	News
+	Uucp
+	Cron
+	Authpriv
+	Ftp
+	Local0 Facility = iota + 4
+	Local1
+	Local2
+	Local3
+	Local4
+	Local5
+	Local6
+	Local7
+)
+
+// Severity values of RFC 5424.
+const (
+	SeverityEmergency = iota
+	SeverityAlert
+	SeverityCritical
+	SeverityError
+	SeverityWarning
+	SeverityNotice
+	SeverityInformational
+	SeverityDebug
+)
+
+const nilValue = "-"
+
+// DefaultEnterpriseID is the private enterprise number reserved for documentation,
+// it makes the structured data IDs valid until the user sets its own number.
+const DefaultEnterpriseID = "32473"
+
+// Severity maps a log level to a syslog severity.
+func Severity(level logengine.Level) int {
+	switch {
+	case level <= logengine.Debug:
+		return SeverityDebug
+	case level == logengine.Info:
+		return SeverityInformational
+	case level == logengine.Warn:
+		return SeverityWarning
+	case level == logengine.Error:
+		return SeverityError
+	case level == logengine.Panic:
+		return SeverityCritical
+	default:
+		return SeverityAlert
+	}
+}
+
+// header holds the values that identify the sender of the messages.
+type header struct {
+	facility     Facility
+	hostname     string
+	appName      string
+	procID       string
+	enterpriseID string
+}
+
+func (h header) priority(level logengine.Level) int {
+	return int(h.facility)*8 + Severity(level)
+}
+
+// formatRFC5424 formats the entry as:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func (h header) formatRFC5424(e logengine.Entry) []byte {
+	var b strings.Builder
+
+	b.WriteString("<")
+	b.WriteString(strconv.Itoa(h.priority(e.Level)))
+	b.WriteString(">1 ")
+	b.WriteString(formatTime5424(e.Time))
+	b.WriteByte(' ')
+	b.WriteString(headerValue(h.hostname, 255))
+	b.WriteByte(' ')
+	b.WriteString(headerValue(h.appName, 48))
+	b.WriteByte(' ')
+	b.WriteString(headerValue(h.procID, 128))
+	b.WriteByte(' ')
+	b.WriteString(nilValue) // MSGID
+	b.WriteByte(' ')
+	b.WriteString(h.structuredData(e))
+
+	if e.Msg != "" {
+		b.WriteByte(' ')
+		b.WriteString(e.Msg)
+	}
+
+	return []byte(b.String())
+}
+
+// structuredData emits the static fields in the "ionlog" element and the caller in the "caller" element.
+func (h header) structuredData(e logengine.Entry) string {
+	var b strings.Builder
+
+	if len(e.Fields) > 0 {
+		b.WriteString("[ionlog@")
+		b.WriteString(h.enterpriseID)
+		for _, key := range slices.Sorted(maps.Keys(e.Fields)) {
+			writeParam(&b, key, e.Fields[key])
+		}
+		b.WriteByte(']')
+	}
+
+	if e.File != "" {
+		b.WriteString("[caller@")
+		b.WriteString(h.enterpriseID)
+		writeParam(&b, "file", e.File)
+		writeParam(&b, "line", strconv.Itoa(e.Line))
+		writeParam(&b, "package", e.Package)
+		writeParam(&b, "function", e.Function)
+		b.WriteByte(']')
+	}
+
+	if b.Len() == 0 {
+		return nilValue
+	}
+	return b.String()
+}
+
+// writeParam writes ` name="value"`, escaping the value as RFC 5424 6.3.3 requires.
+func writeParam(b *strings.Builder, name string, value string) {
+	b.WriteByte(' ')
+	b.WriteString(sdName(name))
+	b.WriteString(`="`)
+	for _, r := range value {
+		if r == '"' || r == '\\' || r == ']' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+}
+
+// sdName keeps only the characters allowed in a SD-NAME: printable ASCII but '=', ' ', ']' and '"'.
+func sdName(name string) string {
+	clean := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+
+	if len(clean) > 32 {
+		clean = clean[:32]
+	}
+	if clean == "" {
+		return "_"
+	}
+	return clean
+}
+
+// headerValue keeps only printable ASCII, as the header fields require.
+func headerValue(value string, maxLen int) string {
+	clean := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, value)
+
+	if len(clean) > maxLen {
+		clean = clean[:maxLen]
+	}
+	if clean == "" {
+		return nilValue
+	}
+	return clean
+}
+
+func formatTime5424(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.Format("2006-01-02T15:04:05.000000Z07:00")
+}
+
+// formatRFC3164 formats the entry as:
+// <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+func (h header) formatRFC3164(e logengine.Entry) []byte {
+	t := e.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	tag := strings.Map(func(r rune) rune {
+		if r > 127 || !(r == '-' || r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return -1
+		}
+		return r
+	}, h.appName)
+	if len(tag) > 32 {
+		tag = tag[:32]
+	}
+
+	return fmt.Appendf(nil, "<%d>%s %s %s[%s]: %s",
+		h.priority(e.Level),
+		t.Format(time.Stamp),
+		headerValue(h.hostname, 255),
+		tag,
+		h.procID,
+		e.Msg,
+	)
+}
+
+// frame delimits msg for the transport.
+func frame(framing Framing, msg []byte) []byte {
+	switch framing {
+	case OctetCounting:
+		return append(strconv.AppendInt(nil, int64(len(msg)), 10), append([]byte{' '}, msg...)...)
+	case NonTransparent:
+		return append(msg, '\n')
+	default:
+		return msg
+	}
+}
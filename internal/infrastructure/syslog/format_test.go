@@ -0,0 +1,115 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+)
+
+func testHeader() header {
+	return header{
+		facility:     Local0,
+		hostname:     "host-1",
+		appName:      "api",
+		procID:       "123",
+		enterpriseID: DefaultEnterpriseID,
+	}
+}
+
+func testEntry() logengine.Entry {
+	return logengine.Entry{
+		Time:     time.Date(2025, 6, 17, 10, 0, 0, 500_000_000, time.UTC),
+		Level:    logengine.Warn,
+		Msg:      "disk almost full",
+		File:     "main.go",
+		Package:  "main",
+		Function: "run",
+		Line:     42,
+		Fields:   map[string]string{"env": "prod", "app": `a"b]c\d`},
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	cases := map[logengine.Level]int{
+		logengine.Trace: SeverityDebug,
+		logengine.Debug: SeverityDebug,
+		logengine.Info:  SeverityInformational,
+		logengine.Warn:  SeverityWarning,
+		logengine.Error: SeverityError,
+		logengine.Panic: SeverityCritical,
+		logengine.Fatal: SeverityAlert,
+	}
+	for level, expected := range cases {
+		if got := Severity(level); got != expected {
+			t.Errorf("expected severity of %v to be %d, but got %d", level, expected, got)
+		}
+	}
+}
+
+func TestFacility(t *testing.T) {
+	if Local0 != 16 || Local7 != 23 || Ftp != 11 {
+		t.Errorf("unexpected facility values: local0=%d local7=%d ftp=%d", Local0, Local7, Ftp)
+	}
+}
+
+func TestFormatRFC5424(t *testing.T) {
+	t.Run("should format the entry with structured data", func(t *testing.T) {
+		got := string(testHeader().formatRFC5424(testEntry()))
+		expected := `<132>1 2025-06-17T10:00:00.500000Z host-1 api 123 - ` +
+			`[ionlog@32473 app="a\"b\]c\\d" env="prod"]` +
+			`[caller@32473 file="main.go" line="42" package="main" function="run"] disk almost full`
+
+		if got != expected {
+			t.Errorf("expected message to be\n%q\nbut got\n%q", expected, got)
+		}
+	})
+
+	t.Run("should use the nil value when there is no structured data", func(t *testing.T) {
+		h := testHeader()
+		h.hostname = ""
+		e := logengine.Entry{Time: testEntry().Time, Level: logengine.Info, Msg: "hi"}
+
+		got := string(h.formatRFC5424(e))
+		expected := `<134>1 2025-06-17T10:00:00.500000Z - api 123 - - hi`
+		if got != expected {
+			t.Errorf("expected message to be %q, but got %q", expected, got)
+		}
+	})
+
+	t.Run("should sanitize the names of the parameters", func(t *testing.T) {
+		if got := sdName(`a b="c]`); got != "a_b__c_" {
+			t.Errorf("unexpected name %q", got)
+		}
+		if got := sdName("abcdefghijklmnopqrstuvwxyz0123456789"); len(got) != 32 {
+			t.Errorf("expected the name to be truncated to 32 characters, but got %d", len(got))
+		}
+	})
+}
+
+func TestFormatRFC3164(t *testing.T) {
+	t.Run("should format the entry with the BSD format", func(t *testing.T) {
+		h := testHeader()
+		h.appName = "my api/v1"
+
+		got := string(h.formatRFC3164(testEntry()))
+		expected := `<132>Jun 17 10:00:00 host-1 myapiv1[123]: disk almost full`
+		if got != expected {
+			t.Errorf("expected message to be %q, but got %q", expected, got)
+		}
+	})
+}
+
+func TestFrame(t *testing.T) {
+	msg := []byte("<134>1 hello")
+
+	if got := string(frame(OctetCounting, msg)); got != "12 <134>1 hello" {
+		t.Errorf("unexpected octet counting frame %q", got)
+	}
+	if got := string(frame(NonTransparent, msg)); got != "<134>1 hello\n" {
+		t.Errorf("unexpected non transparent frame %q", got)
+	}
+	if got := string(frame(NoFraming, msg)); got != "<134>1 hello" {
+		t.Errorf("unexpected frame %q", got)
+	}
+}
@@ -0,0 +1,243 @@
+// Package syslog sends the logs to a syslog server following RFC 5424 or RFC 3164.
+package syslog
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+)
+
+// localSockets are the usual paths of the local syslog socket.
+var localSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+type syslogWriter struct {
+	header
+
+	network     string
+	address     string
+	format      Format
+	framing     Framing
+	connFraming Framing
+	tlsConfig   *tls.Config
+	dialTimeout time.Duration
+
+	conn      net.Conn
+	closed    bool
+	writeLock sync.Mutex
+}
+
+type ISyslogWriter interface {
+	io.WriteCloser
+}
+
+type Option func(s *syslogWriter)
+
+// NewSyslogWriter connects to the syslog server at address.
+// The network is one of "udp", "tcp", "tls", "unix" or "unixgram",
+// an empty network and address connects to the local syslog socket.
+func NewSyslogWriter(network string, address string, opts ...Option) (ISyslogWriter, error) {
+	hostname, _ := os.Hostname()
+
+	s := &syslogWriter{
+		header: header{
+			facility:     User,
+			hostname:     hostname,
+			appName:      filepath.Base(os.Args[0]),
+			procID:       strconv.Itoa(os.Getpid()),
+			enterpriseID: DefaultEnterpriseID,
+		},
+		network:     network,
+		address:     address,
+		format:      RFC5424,
+		dialTimeout: 5 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// WithFormat sets the message format, RFC5424 by default.
+func WithFormat(format Format) Option {
+	return func(s *syslogWriter) {
+		s.format = format
+	}
+}
+
+// WithFraming sets how the messages are delimited on stream transports.
+func WithFraming(framing Framing) Option {
+	return func(s *syslogWriter) {
+		s.framing = framing
+	}
+}
+
+// WithFacility sets the facility of the messages, User by default.
+func WithFacility(facility Facility) Option {
+	return func(s *syslogWriter) {
+		s.facility = facility
+	}
+}
+
+// WithAppName sets the APP-NAME (or TAG) of the messages, the program name by default.
+func WithAppName(appName string) Option {
+	return func(s *syslogWriter) {
+		s.appName = appName
+	}
+}
+
+// WithHostname sets the HOSTNAME of the messages, os.Hostname by default.
+func WithHostname(hostname string) Option {
+	return func(s *syslogWriter) {
+		s.hostname = hostname
+	}
+}
+
+// WithEnterpriseID sets the private enterprise number used in the structured data IDs.
+func WithEnterpriseID(id string) Option {
+	return func(s *syslogWriter) {
+		s.enterpriseID = id
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the "tls" network.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(s *syslogWriter) {
+		s.tlsConfig = config
+	}
+}
+
+// WithDialTimeout bounds the time to connect to the server.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(s *syslogWriter) {
+		s.dialTimeout = timeout
+	}
+}
+
+// Write sends the entry in p as a syslog message,
+// reconnecting once when the connection was lost.
+func (s *syslogWriter) Write(p []byte) (int, error) {
+	e, err := logengine.ParseEntry(p)
+	if err != nil {
+		return 0, err
+	}
+
+	var msg []byte
+	switch s.format {
+	case RFC3164:
+		msg = s.formatRFC3164(e)
+	default:
+		msg = s.formatRFC5424(e)
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	if s.closed {
+		return 0, ErrWriterClosed
+	}
+
+	if s.conn != nil {
+		if _, err = s.conn.Write(frame(s.connFraming, msg)); err == nil {
+			return len(p), nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	if err := s.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := s.conn.Write(frame(s.connFraming, msg)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// connect dials the server, it must be called with the write lock held or before the writer is shared.
+func (s *syslogWriter) connect() error {
+	if s.network == "" && s.address == "" {
+		return s.connectLocal()
+	}
+
+	var conn net.Conn
+	var err error
+
+	switch s.network {
+	case "tls":
+		dialer := &net.Dialer{Timeout: s.dialTimeout}
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.address, s.tlsConfig)
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6", "unix", "unixgram":
+		conn, err = net.DialTimeout(s.network, s.address, s.dialTimeout)
+	default:
+		return ErrUnknownNetwork
+	}
+	if err != nil {
+		return err
+	}
+
+	s.setConn(conn)
+	return nil
+}
+
+// connectLocal connects to the first local syslog socket available.
+func (s *syslogWriter) connectLocal() error {
+	for _, path := range localSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := net.DialTimeout(network, path, s.dialTimeout)
+			if err != nil {
+				continue
+			}
+			s.setConn(conn)
+			return nil
+		}
+	}
+	return ErrNoLocalSyslog
+}
+
+// setConn sets the connection and resolves the framing used on it,
+// by default octet counting on streams and no framing on datagrams.
+func (s *syslogWriter) setConn(conn net.Conn) {
+	s.conn = conn
+	s.connFraming = s.framing
+	if s.framing != DefaultFraming {
+		return
+	}
+
+	s.connFraming = OctetCounting
+	switch conn.(type) {
+	case *net.UDPConn:
+		s.connFraming = NoFraming
+	case *net.UnixConn:
+		if addr := conn.RemoteAddr(); addr != nil && addr.Network() == "unixgram" {
+			s.connFraming = NoFraming
+		}
+	}
+}
+
+func (s *syslogWriter) Close() error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
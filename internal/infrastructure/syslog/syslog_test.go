@@ -0,0 +1,336 @@
+package syslog
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testLine = `{"env":"prod","time":"2025-06-17T10:00:00Z","level":"ERROR","msg":"boom","file":"main.go","package":"main","function":"run","line":"7"}` + "\n"
+
+// readOctetFrame reads a message framed with octet counting
+func readOctetFrame(r *bufio.Reader) (string, error) {
+	size, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(size))
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return "", err
+	}
+	return string(msg), nil
+}
+
+func TestSyslogWriterUDP(t *testing.T) {
+	t.Run("should send one message per datagram", func(t *testing.T) {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer pc.Close()
+
+		w, err := NewSyslogWriter("udp", pc.LocalAddr().String(), WithHostname("h"), WithAppName("app"))
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer w.Close()
+
+		if n, err := w.Write([]byte(testLine)); err != nil || n != len(testLine) {
+			t.Fatalf("expected %d bytes and no error, but got %d and %v", len(testLine), n, err)
+		}
+
+		buf := make([]byte, 2048)
+		pc.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		got := string(buf[:n])
+		if !strings.HasPrefix(got, "<11>1 2025-06-17T10:00:00.000000Z h app ") {
+			t.Errorf("unexpected message %q", got)
+		}
+		if !strings.HasSuffix(got, `[ionlog@32473 env="prod"][caller@32473 file="main.go" line="7" package="main" function="run"] boom`) {
+			t.Errorf("unexpected message %q", got)
+		}
+	})
+}
+
+func TestSyslogWriterTCP(t *testing.T) {
+	t.Run("should frame the messages with octet counting", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer ln.Close()
+
+		received := make(chan string, 2)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				msg, err := readOctetFrame(r)
+				if err != nil {
+					return
+				}
+				received <- msg
+			}
+		}()
+
+		w, err := NewSyslogWriter("tcp", ln.Addr().String(), WithFormat(RFC3164), WithHostname("h"), WithAppName("app"))
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer w.Close()
+
+		w.Write([]byte(testLine))
+		w.Write([]byte(testLine))
+
+		for range 2 {
+			select {
+			case msg := <-received:
+				if !strings.HasPrefix(msg, "<11>Jun 17 10:00:00 h app[") || !strings.HasSuffix(msg, "]: boom") {
+					t.Errorf("unexpected message %q", msg)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("expected a message, but timeout")
+			}
+		}
+	})
+
+	t.Run("should use the chosen framing", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer ln.Close()
+
+		received := make(chan string, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			received <- line
+		}()
+
+		w, err := NewSyslogWriter("tcp", ln.Addr().String(), WithFraming(NonTransparent), WithFacility(Local7))
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer w.Close()
+
+		w.Write([]byte(testLine))
+
+		select {
+		case msg := <-received:
+			if !strings.HasPrefix(msg, "<187>1 ") || !strings.HasSuffix(msg, " boom\n") {
+				t.Errorf("unexpected message %q", msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected a message, but timeout")
+		}
+	})
+
+	t.Run("should reconnect when the connection is lost", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer ln.Close()
+
+		received := make(chan string, 1)
+		go func() {
+			conn, _ := ln.Accept()
+			conn.Close() // drop the first connection
+
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			msg, _ := readOctetFrame(bufio.NewReader(conn))
+			received <- msg
+		}()
+
+		w, err := NewSyslogWriter("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer w.Close()
+
+		deadline := time.After(2 * time.Second)
+		for {
+			w.Write([]byte(testLine))
+			select {
+			case msg := <-received:
+				if !strings.HasSuffix(msg, " boom") {
+					t.Errorf("unexpected message %q", msg)
+				}
+				return
+			case <-deadline:
+				t.Fatal("expected a message after reconnecting, but timeout")
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	})
+}
+
+func TestSyslogWriterTLS(t *testing.T) {
+	t.Run("should send the messages over TLS", func(t *testing.T) {
+		cert := selfSignedCert(t)
+		ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer ln.Close()
+
+		received := make(chan string, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			msg, _ := readOctetFrame(bufio.NewReader(conn))
+			received <- msg
+		}()
+
+		pool := x509.NewCertPool()
+		pool.AddCert(cert.Leaf)
+
+		w, err := NewSyslogWriter("tls", ln.Addr().String(), WithTLSConfig(&tls.Config{RootCAs: pool, ServerName: "localhost"}))
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer w.Close()
+
+		w.Write([]byte(testLine))
+
+		select {
+		case msg := <-received:
+			if !strings.HasSuffix(msg, " boom") {
+				t.Errorf("unexpected message %q", msg)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected a message, but timeout")
+		}
+	})
+}
+
+func TestSyslogWriterUnix(t *testing.T) {
+	t.Run("should send the messages to a unix datagram socket", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "log.sock")
+		pc, err := net.ListenPacket("unixgram", path)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer pc.Close()
+
+		w, err := NewSyslogWriter("unixgram", path)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer w.Close()
+
+		w.Write([]byte(testLine))
+
+		buf := make([]byte, 2048)
+		pc.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if got := string(buf[:n]); !strings.HasPrefix(got, "<11>1 ") || !strings.HasSuffix(got, " boom") {
+			t.Errorf("unexpected message %q", got)
+		}
+	})
+}
+
+func TestSyslogWriterErrors(t *testing.T) {
+	t.Run("should reject unknown networks", func(t *testing.T) {
+		if _, err := NewSyslogWriter("sctp", "127.0.0.1:514"); !errors.Is(err, ErrUnknownNetwork) {
+			t.Errorf("expected error to be %v, but got %v", ErrUnknownNetwork, err)
+		}
+	})
+
+	t.Run("should reject lines that are not logs", func(t *testing.T) {
+		pc, _ := net.ListenPacket("udp", "127.0.0.1:0")
+		defer pc.Close()
+
+		w, err := NewSyslogWriter("udp", pc.LocalAddr().String())
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		defer w.Close()
+
+		if _, err := w.Write([]byte("not a log")); err == nil {
+			t.Error("expected an error, but got nil")
+		}
+	})
+
+	t.Run("should refuse writes after close", func(t *testing.T) {
+		pc, _ := net.ListenPacket("udp", "127.0.0.1:0")
+		defer pc.Close()
+
+		w, _ := NewSyslogWriter("udp", pc.LocalAddr().String())
+		w.Close()
+
+		if _, err := w.Write([]byte(testLine)); !errors.Is(err, ErrWriterClosed) {
+			t.Errorf("expected error to be %v, but got %v", ErrWriterClosed, err)
+		}
+	})
+}
+
+func selfSignedCert(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
@@ -12,7 +13,6 @@ import (
 )
 
 type coreService struct {
-	ctx           context.Context
 	cancel        context.CancelFunc
 	serviceWg     sync.WaitGroup
 	serviceStatus ServiceStatus
@@ -23,15 +23,18 @@ type coreService struct {
 	serviceStatusLock sync.Mutex
 }
 
+// ICoreService is the logger service, its status moves from Stopped to Running on Start,
+// then to Draining while Stop or Shutdown write what is pending, and back to Stopped.
+// The writers and the settings are kept across a stop, so the service can be started again.
 type ICoreService interface {
 	IService
 	LogEngine() logengine.ILogger
+	Shutdown(ctx context.Context) error
 	CreateRotationService(folder string, maxFolderSize uint, rotation rotationengine.PeriodicRotation)
 }
 
 func NewCoreService() ICoreService {
 	cs := &coreService{}
-	cs.ctx, cs.cancel = context.WithCancel(context.Background())
 	cs.logEngine = logengine.NewLogger()
 	cs.rotationService = nil // will be set if rotation is enabled by the user
 	return cs
@@ -41,53 +44,188 @@ func (c *coreService) LogEngine() logengine.ILogger {
 	return c.logEngine
 }
 
+// CreateRotationService replaces the rotation service, the new one is started when the logger service runs.
 func (c *coreService) CreateRotationService(folder string, maxFolderSize uint, rotation rotationengine.PeriodicRotation) {
+	c.serviceStatusLock.Lock()
+	defer c.serviceStatusLock.Unlock()
+
 	if c.rotationService != nil {
 		c.LogEngine().Writer().DeleteWriter(c.rotationService.RotationEngine())
-		c.rotationService.Stop()
+		_ = c.rotationService.Stop()
 	}
 
 	c.rotationService = NewRotationService(folder, maxFolderSize, rotation)
 	c.LogEngine().Writer().AddWriter(c.rotationService.RotationEngine())
+
+	if c.serviceStatus == Running {
+		_ = c.rotationService.Start()
+	}
+}
+
+// Start starts the report goroutine and the rotation service, it returns once they run.
+// It does nothing when the service is running, and returns ErrServiceDraining while it stops.
+func (c *coreService) Start() error {
+	c.serviceStatusLock.Lock()
+	defer c.serviceStatusLock.Unlock()
+
+	switch c.serviceStatus {
+	case Running:
+		return nil
+	case Draining:
+		return ErrServiceDraining
+	}
+
+	if c.rotationService != nil {
+		if err := c.rotationService.Start(); err != nil {
+			return err
+		}
+	}
+
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	c.logEngine.OpenReports()
+
+	ready := make(chan struct{})
+	c.serviceWg.Add(1)
+	go c.run(ctx, ready)
+	<-ready // the flushes write the reports themselves until the goroutine takes them
+
+	c.serviceStatus = Running
+	return nil
 }
 
-// Start starts the logger service, it blocks until the service is stopped
-func (c *coreService) Start(startSync *sync.WaitGroup) {
+// run takes the reports until ctx is canceled, it closes ready once it takes them.
+func (c *coreService) run(ctx context.Context, ready chan<- struct{}) {
+	defer c.serviceWg.Done()
 	defer func() {
 		if r := recover(); r != nil {
-			ci := runtimeinfo.GetCallerInfo(3)
-			fmt.Fprintf(os.Stderr, "logger service panic: '%v' [%v](%v) %v:%v\n", r, ci.Package, ci.Function, ci.File, ci.Line)
+			stack := runtimeinfo.Stack(3) // starts at the function that panicked
+			fmt.Fprintf(os.Stderr, "logger service panic: '%v'\n%s", r, runtimeinfo.FormatStack(stack))
+			c.panicked()
 		}
 	}()
 
-	c.serviceWg.Add(1)
-	defer c.serviceWg.Done()
+	c.logEngine.HandleReports(ctx, ready)
+}
 
-	c.setServiceStatus(Running)
-	defer c.setServiceStatus(Stopped)
+// panicked moves a running service to Stopped after its report goroutine panicked, so it can be
+// started again. The entries logged meanwhile are dropped, they would wait for a goroutine that is gone.
+func (c *coreService) panicked() {
+	c.serviceStatusLock.Lock()
+	defer c.serviceStatusLock.Unlock()
 
+	if c.serviceStatus != Running {
+		return // Stop or Shutdown are moving it to Stopped
+	}
+
+	c.logEngine.CloseReports()
 	if c.rotationService != nil {
-		rotateSync := sync.WaitGroup{}
-		rotateSync.Add(1)
-		go c.rotationService.Start(&rotateSync)
-		rotateSync.Wait()
+		_ = c.rotationService.Stop()
 	}
+	c.serviceStatus = Stopped
+}
+
+// drain moves the service to Draining and returns the status it had.
+// A stopped service is moved only when fromStopped is true.
+func (c *coreService) drain(fromStopped bool) (ServiceStatus, IRotationService, error) {
+	c.serviceStatusLock.Lock()
+	defer c.serviceStatusLock.Unlock()
 
-	if startSync != nil {
-		startSync.Done()
+	status := c.serviceStatus
+	switch {
+	case status == Draining:
+		return status, nil, ErrServiceDraining
+	case status == Stopped && !fromStopped:
+		return status, nil, nil
 	}
 
-	c.logEngine.HandleReports(c.ctx)
+	c.serviceStatus = Draining
+	return status, c.rotationService, nil
 }
 
-// Stop stops the logger by canceling the context and waiting for the worker to finish
-func (c *coreService) Stop() {
+// Stop stops the report goroutine once the queued reports are written, flushes the writers
+// and stops the rotation service. The writers are left open, so the service can be started again.
+// It does nothing when the service is stopped, and returns ErrServiceDraining while it stops.
+func (c *coreService) Stop() error {
+	status, rotation, err := c.drain(false)
+	if err != nil || status == Stopped {
+		return err
+	}
+
 	c.cancel()
 	c.serviceWg.Wait()
 	c.logEngine.FlushReports()
 
-	if c.rotationService != nil {
-		c.rotationService.Stop()
+	if rotation != nil {
+		_ = rotation.Stop()
+	}
+
+	c.setServiceStatus(Stopped)
+	return nil
+}
+
+// Shutdown stops accepting entries, writes the queued ones and closes the writers, giving up
+// when ctx is done. The entries still queued then are discarded, and the returned error
+// tells how many entries were lost during the shutdown, by the queue or by the writers, along with the failures.
+// It returns ErrServiceDraining while the service stops.
+func (c *coreService) Shutdown(ctx context.Context) error {
+	status, rotation, err := c.drain(true)
+	if err != nil {
+		return err
+	}
+	defer c.setServiceStatus(Stopped)
+
+	writer := c.logEngine.Writer()
+	lostBefore := c.logEngine.Dropped() + writer.Dropped()
+
+	var errs []error
+
+	c.logEngine.CloseReports()
+	if err := c.logEngine.FlushContext(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to flush the reports: %w", err))
+	}
+
+	if status == Running {
+		c.cancel()
+		if err := waitContext(ctx, func() error { c.serviceWg.Wait(); return nil }); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop the report goroutine: %w", err))
+		}
+	}
+	c.logEngine.Discard()
+
+	if err := waitContext(ctx, writer.Shutdown); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close the writers: %w", err))
+	}
+
+	if rotation != nil {
+		_ = rotation.Stop()
+	}
+
+	if lost := c.logEngine.Dropped() + writer.Dropped() - lostBefore; lost > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d entries", logengine.ErrEntriesLost, lost))
+	}
+
+	return errors.Join(errs...)
+}
+
+// waitContext runs fn and returns its error, or the error of ctx when it is done first,
+// fn is left running then.
+func waitContext(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		select {
+		case err := <-done:
+			return err
+		default:
+			return ctx.Err()
+		}
 	}
 }
 
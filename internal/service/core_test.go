@@ -2,13 +2,17 @@ package service
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/IonicHealthUsa/ionlog/internal/core/batchwriter"
 	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
 	"github.com/IonicHealthUsa/ionlog/internal/core/rotationengine"
 	"github.com/IonicHealthUsa/ionlog/internal/core/runtimeinfo"
@@ -174,12 +178,11 @@ func TestStart_Core(t *testing.T) {
 		buf := newMockBufferWriter()
 		cs.LogEngine().Writer().AddWriter(buf)
 
-		startSync := sync.WaitGroup{}
-		startSync.Add(1)
-		go cs.Start(&startSync)
+		if err := cs.Start(); err != nil {
+			t.Fatal(err)
+		}
 
 		cs.LogEngine().AsyncReport(r)
-		startSync.Wait()
 
 		if buf.String() != reportLog {
 			t.Errorf("expected the report log to be %q, but got %q", reportLog, buf.String())
@@ -195,10 +198,9 @@ func TestStart_Core(t *testing.T) {
 			t.Fatal("NewCoreService() did not return *coreService")
 		}
 
-		startSync := sync.WaitGroup{}
-		startSync.Add(1)
-		go cs.Start(&startSync)
-		startSync.Wait()
+		if err := cs.Start(); err != nil {
+			t.Fatal(err)
+		}
 
 		_cs.serviceStatusLock.Lock()
 		if _cs.serviceStatus != Running {
@@ -226,10 +228,9 @@ func TestStart_Core(t *testing.T) {
 			t.Fatal("NewRotationService() did not return *rotationService")
 		}
 
-		startSync := sync.WaitGroup{}
-		startSync.Add(1)
-		go cs.Start(&startSync)
-		startSync.Wait()
+		if err := cs.Start(); err != nil {
+			t.Fatal(err)
+		}
 
 		_cs.serviceStatusLock.Lock()
 		if _cs.serviceStatus != Running {
@@ -269,10 +270,9 @@ func TestStart_Core(t *testing.T) {
 			t.Errorf("expected the buffer length to be 0, but got %d", buf.Len())
 		}
 
-		startSync := sync.WaitGroup{}
-		startSync.Add(1)
-		go cs.Start(&startSync)
-		startSync.Wait()
+		if err := cs.Start(); err != nil {
+			t.Fatal(err)
+		}
 
 		if buf.String() != reportLog {
 			t.Errorf("expected the report log to be %q, but got %q", reportLog, buf.String())
@@ -282,6 +282,40 @@ func TestStart_Core(t *testing.T) {
 	})
 }
 
+func TestStart_EarlyEntries(t *testing.T) {
+	t.Run("should keep the entries logged before the start without blocking", func(t *testing.T) {
+		cs := NewCoreService()
+		buf := newMockBufferWriter()
+		cs.LogEngine().Writer().AddWriter(buf)
+		cs.LogEngine().SetEarlyBuffer(1000)
+
+		begin := time.Now()
+		for i := range 500 {
+			cs.LogEngine().AsyncReport(logengine.ReportType{Level: logengine.Info, Msg: fmt.Sprint("early ", i)})
+		}
+		if elapsed := time.Since(begin); elapsed > 500*time.Millisecond {
+			t.Errorf("expected the entries to be kept at once, but it took %v", elapsed)
+		}
+
+		if err := cs.Start(); err != nil {
+			t.Fatal(err)
+		}
+		if err := cs.Stop(); err != nil {
+			t.Fatal(err)
+		}
+
+		lines := bytes.Split(bytes.TrimSpace([]byte(buf.String())), []byte("\n"))
+		if len(lines) != 500 {
+			t.Fatalf("expected 500 entries, but got %d", len(lines))
+		}
+		for i, line := range lines {
+			if !bytes.Contains(line, []byte(fmt.Sprintf(`"msg":"early %d"`, i))) {
+				t.Fatalf("expected the entries in order, but got %q at %d", line, i)
+			}
+		}
+	})
+}
+
 func TestStop_Core(t *testing.T) {
 	t.Run("should core service stop", func(t *testing.T) {
 		cs := NewCoreService()
@@ -290,10 +324,9 @@ func TestStop_Core(t *testing.T) {
 			t.Fatal("NreCoreService() did not return *coreService")
 		}
 
-		startSync := sync.WaitGroup{}
-		startSync.Add(1)
-		go cs.Start(&startSync)
-		startSync.Wait()
+		if err := cs.Start(); err != nil {
+			t.Fatal(err)
+		}
 
 		_cs.serviceStatusLock.Lock()
 		if _cs.serviceStatus != Running {
@@ -328,10 +361,9 @@ func TestStop_Core(t *testing.T) {
 			t.Fatal("NewRotationService() did not return *rotationService")
 		}
 
-		startSync := sync.WaitGroup{}
-		startSync.Add(1)
-		go cs.Start(&startSync)
-		startSync.Wait()
+		if err := cs.Start(); err != nil {
+			t.Fatal(err)
+		}
 
 		_cs.serviceStatusLock.Lock()
 		if _cs.serviceStatus != Running {
@@ -364,6 +396,282 @@ func TestStop_Core(t *testing.T) {
 	})
 }
 
+func TestStop_FlushBatches(t *testing.T) {
+	t.Run("should deliver the last batch on stop", func(t *testing.T) {
+		var lock sync.Mutex
+		var delivered []string
+		send := func(entries [][]byte) error {
+			lock.Lock()
+			defer lock.Unlock()
+			for _, e := range entries {
+				delivered = append(delivered, string(e))
+			}
+			return nil
+		}
+
+		cs := NewCoreService()
+		cs.LogEngine().Writer().AddWriter(batchwriter.NewBatchWriter(send, batchwriter.WithInterval(time.Hour)))
+
+		if err := cs.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		cs.LogEngine().AsyncReport(logengine.ReportType{Level: logengine.Info, Msg: "last words"})
+		cs.Stop()
+
+		lock.Lock()
+		defer lock.Unlock()
+		if len(delivered) != 1 || !bytes.Contains([]byte(delivered[0]), []byte("last words")) {
+			t.Errorf("expected the last entry to be delivered, but got %q", delivered)
+		}
+	})
+}
+
+// closerWriter records the entries and whether it was closed
+type closerWriter struct {
+	mockBufferWriter
+	closed bool
+}
+
+func (c *closerWriter) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+	return nil
+}
+
+// stuckWriter blocks every write until release is closed
+type stuckWriter struct {
+	release chan struct{}
+}
+
+func (s *stuckWriter) Write(p []byte) (int, error) {
+	<-s.release
+	return len(p), nil
+}
+
+// panicWriter panics on the first write, then records the entries
+type panicWriter struct {
+	*mockBufferWriter
+	panicked bool
+}
+
+func (p *panicWriter) Write(b []byte) (int, error) {
+	p.lock.Lock()
+	panicked := p.panicked
+	p.panicked = true
+	p.lock.Unlock()
+
+	if !panicked {
+		panic("boom")
+	}
+	return p.mockBufferWriter.Write(b)
+}
+
+func TestShutdown_Core(t *testing.T) {
+	t.Run("should write the queued entries and close the writers", func(t *testing.T) {
+		cs := NewCoreService()
+		w := &closerWriter{}
+		w.cond = sync.NewCond(&w.lock)
+		cs.LogEngine().Writer().AddWriter(w)
+
+		if err := cs.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := range 10 {
+			cs.LogEngine().AsyncReport(logengine.ReportType{Level: logengine.Info, Msg: fmt.Sprint("entry ", i)})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := cs.Shutdown(ctx); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		if n := bytes.Count([]byte(w.String()), []byte("\n")); n != 10 {
+			t.Errorf("expected 10 entries, but got %d", n)
+		}
+		if !w.closed {
+			t.Error("expected the writer to be closed")
+		}
+		if cs.Status() != Stopped {
+			t.Errorf("expected the status of logger service to be %q, but got %q", Stopped, cs.Status())
+		}
+	})
+
+	t.Run("should report the entries lost when the deadline is exceeded", func(t *testing.T) {
+		cs := NewCoreService()
+		w := &stuckWriter{release: make(chan struct{})}
+		defer close(w.release)
+		cs.LogEngine().Writer().AddWriter(w)
+
+		if err := cs.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		for range 10 {
+			cs.LogEngine().AsyncReport(logengine.ReportType{Level: logengine.Info, Msg: "stuck"})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := cs.Shutdown(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the deadline to be exceeded, but got %v", err)
+		}
+		if !errors.Is(err, logengine.ErrEntriesLost) {
+			t.Errorf("expected the entries lost to be reported, but got %v", err)
+		}
+	})
+
+	t.Run("should not report the entries lost before the shutdown", func(t *testing.T) {
+		cs := NewCoreService()
+		cs.LogEngine().Writer().AddWriter(newMockBufferWriter())
+
+		cs.LogEngine().AsyncReport(logengine.ReportType{Level: logengine.Info, Msg: "discarded"})
+		cs.LogEngine().Discard()
+
+		if err := cs.Shutdown(context.Background()); err != nil {
+			t.Errorf("expected no error, but got %v", err)
+		}
+	})
+
+	t.Run("should drop the entries logged after the shutdown", func(t *testing.T) {
+		cs := NewCoreService()
+		w := newMockBufferWriter()
+		cs.LogEngine().Writer().AddWriter(w)
+
+		if err := cs.Shutdown(context.Background()); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		cs.LogEngine().AsyncReport(logengine.ReportType{Level: logengine.Info, Msg: "too late"})
+
+		if w.Len() != 0 {
+			t.Errorf("expected no entry, but got %q", w.buf.String())
+		}
+		if n := cs.LogEngine().Dropped(); n != 1 {
+			t.Errorf("expected 1 dropped entry, but got %d", n)
+		}
+	})
+}
+
+func TestLifecycle_Core(t *testing.T) {
+	t.Run("should start and stop once", func(t *testing.T) {
+		cs := NewCoreService()
+
+		for range 2 {
+			if err := cs.Start(); err != nil {
+				t.Fatalf("expected no error, but got %v", err)
+			}
+		}
+		if cs.Status() != Running {
+			t.Errorf("expected the status to be %v, but got %v", Running, cs.Status())
+		}
+
+		for range 2 {
+			if err := cs.Stop(); err != nil {
+				t.Fatalf("expected no error, but got %v", err)
+			}
+		}
+		if cs.Status() != Stopped {
+			t.Errorf("expected the status to be %v, but got %v", Stopped, cs.Status())
+		}
+	})
+
+	t.Run("should keep the writers and the settings across a restart", func(t *testing.T) {
+		folderName := "rotation_restart"
+		defer os.RemoveAll(folderName)
+
+		cs := NewCoreService()
+		buf := newMockBufferWriter()
+		cs.LogEngine().Writer().AddWriter(buf)
+		cs.LogEngine().AddStaticFields(map[string]string{"service": "api"})
+		cs.CreateRotationService(folderName, rotationengine.GB, rotationengine.Daily)
+
+		for _, msg := range []string{"first run", "second run"} {
+			if err := cs.Start(); err != nil {
+				t.Fatal(err)
+			}
+			cs.LogEngine().AsyncReport(logengine.ReportType{Level: logengine.Info, Msg: msg})
+			if err := cs.Stop(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got := buf.String()
+		if !bytes.Contains([]byte(got), []byte(`"msg":"first run"`)) || !bytes.Contains([]byte(got), []byte(`"msg":"second run"`)) {
+			t.Errorf("expected the entries of both runs, but got %q", got)
+		}
+		if n := bytes.Count([]byte(got), []byte(`"service":"api"`)); n != 2 {
+			t.Errorf("expected the static field on both entries, but got %q", got)
+		}
+
+		files, err := os.ReadDir(folderName)
+		if err != nil || len(files) != 1 {
+			t.Fatalf("expected a log file, but got %v, %v", files, err)
+		}
+		content, err := os.ReadFile(filepath.Join(folderName, files[0].Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := bytes.Count(content, []byte("\n")); n != 2 {
+			t.Errorf("expected the rotation file to get both entries, but got %q", content)
+		}
+	})
+
+	t.Run("should stop when the report goroutine panics and start again", func(t *testing.T) {
+		cs := NewCoreService()
+		w := &panicWriter{mockBufferWriter: newMockBufferWriter()}
+		cs.LogEngine().Writer().AddWriter(w)
+
+		if err := cs.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cs.LogEngine().AsyncReport(logengine.ReportType{Level: logengine.Info, Msg: "boom"})
+
+		deadline := time.Now().Add(2 * time.Second)
+		for cs.Status() != Stopped {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected the status to be %v, but got %v", Stopped, cs.Status())
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		if err := cs.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cs.LogEngine().AsyncReport(logengine.ReportType{Level: logengine.Info, Msg: "restarted"})
+		if err := cs.Stop(); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := w.String(); !bytes.Contains([]byte(got), []byte(`"msg":"restarted"`)) {
+			t.Errorf("expected the entry logged after the restart, but got %q", got)
+		}
+	})
+
+	t.Run("should refuse to start or stop while draining", func(t *testing.T) {
+		cs := NewCoreService()
+		_cs, ok := cs.(*coreService)
+		if !ok {
+			t.Fatal("NewCoreService() did not return *coreService")
+		}
+
+		_cs.setServiceStatus(Draining)
+
+		if err := cs.Start(); !errors.Is(err, ErrServiceDraining) {
+			t.Errorf("expected %v on start, but got %v", ErrServiceDraining, err)
+		}
+		if err := cs.Stop(); !errors.Is(err, ErrServiceDraining) {
+			t.Errorf("expected %v on stop, but got %v", ErrServiceDraining, err)
+		}
+		if err := cs.Shutdown(context.Background()); !errors.Is(err, ErrServiceDraining) {
+			t.Errorf("expected %v on shutdown, but got %v", ErrServiceDraining, err)
+		}
+	})
+}
+
 func TestStatusCore(t *testing.T) {
 	t.Run("should return running status", func(t *testing.T) {
 		cs := NewCoreService()
@@ -0,0 +1,7 @@
+package service
+
+import "errors"
+
+var (
+	ErrServiceDraining = errors.New("service is draining")
+)
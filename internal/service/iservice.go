@@ -1,11 +1,11 @@
 package service
 
-import "sync"
-
+// IService is a service that runs in its own goroutines, its status moves
+// from Stopped to Running on Start, and back to Stopped on Stop.
 type IService interface {
 	Status() ServiceStatus
-	Start(startSync *sync.WaitGroup)
-	Stop()
+	Start() error
+	Stop() error
 }
 
 type ServiceStatus int
@@ -13,4 +13,6 @@ type ServiceStatus int
 const (
 	Stopped ServiceStatus = iota
 	Running
+	// Draining is the status of a service that is stopping, it writes what is pending.
+	Draining
 )
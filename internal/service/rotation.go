@@ -12,7 +12,6 @@ import (
 )
 
 type rotationService struct {
-	ctx           context.Context
 	cancel        context.CancelFunc
 	serviceWg     sync.WaitGroup
 	serviceStatus ServiceStatus
@@ -29,7 +28,6 @@ type IRotationService interface {
 
 func NewRotationService(folder string, maxFolderSize uint, rotation rotationengine.PeriodicRotation) IRotationService {
 	rs := &rotationService{}
-	rs.ctx, rs.cancel = context.WithCancel(context.Background())
 	rs.rotationEngine = rotationengine.NewRotationEngine(folder, maxFolderSize, rotation)
 	return rs
 }
@@ -38,7 +36,29 @@ func (r *rotationService) RotationEngine() rotationengine.IRotationEngine {
 	return r.rotationEngine
 }
 
-func (r *rotationService) Start(startSync *sync.WaitGroup) {
+// Start starts the periodic checks of the rotation, it does nothing when they are running.
+// The log file closed by a stop is opened again.
+func (r *rotationService) Start() error {
+	r.serviceStatusLock.Lock()
+	defer r.serviceStatusLock.Unlock()
+
+	if r.serviceStatus == Running {
+		return nil
+	}
+
+	var ctx context.Context
+	ctx, r.cancel = context.WithCancel(context.Background())
+	r.rotationEngine.AutoChecks()
+
+	r.serviceWg.Add(1)
+	go r.run(ctx)
+
+	r.serviceStatus = Running
+	return nil
+}
+
+func (r *rotationService) run(ctx context.Context) {
+	defer r.serviceWg.Done()
 	defer func() {
 		if rec := recover(); rec != nil {
 			ci := runtimeinfo.GetCallerInfo(3)
@@ -46,22 +66,12 @@ func (r *rotationService) Start(startSync *sync.WaitGroup) {
 		}
 	}()
 
-	r.serviceWg.Add(1)
-	defer r.serviceWg.Done()
-
-	r.setServiceStatus(Running)
-	defer r.setServiceStatus(Stopped)
-
-	if startSync != nil {
-		startSync.Done()
-	}
-
 	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-r.ctx.Done():
+		case <-ctx.Done():
 			return
 
 		case <-ticker.C:
@@ -70,10 +80,19 @@ func (r *rotationService) Start(startSync *sync.WaitGroup) {
 	}
 }
 
-func (r *rotationService) Stop() {
-	r.cancel()
-	r.serviceWg.Wait()
+// Stop stops the periodic checks, when they run, and closes the log file.
+func (r *rotationService) Stop() error {
+	r.serviceStatusLock.Lock()
+	defer r.serviceStatusLock.Unlock()
+
+	if r.serviceStatus == Running {
+		r.cancel()
+		r.serviceWg.Wait()
+	}
 	r.rotationEngine.CloseLogFile()
+
+	r.serviceStatus = Stopped
+	return nil
 }
 
 func (r *rotationService) Status() ServiceStatus {
@@ -3,7 +3,6 @@ package service
 import (
 	"os"
 	"reflect"
-	"sync"
 	"testing"
 	"time"
 
@@ -102,10 +101,9 @@ func TestStart_rotatation(t *testing.T) {
 			t.Error("expected a instance of rotation of rotation service")
 		}
 
-		startSync := &sync.WaitGroup{}
-		startSync.Add(1)
-		go rs.Start(startSync)
-		startSync.Wait()
+		if err := rs.Start(); err != nil {
+			t.Fatal(err)
+		}
 
 		_rs.serviceStatusLock.Lock()
 		if _rs.serviceStatus != Running {
@@ -134,10 +132,9 @@ func TestStop_rotation(t *testing.T) {
 			t.Error("expected a instance of rotation of rotation service")
 		}
 
-		startSync := &sync.WaitGroup{}
-		startSync.Add(1)
-		go rs.Start(startSync)
-		startSync.Wait()
+		if err := rs.Start(); err != nil {
+			t.Fatal(err)
+		}
 
 		_rs.serviceStatusLock.Lock()
 		if _rs.serviceStatus != Running {
@@ -3,16 +3,59 @@ package styles
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"slices"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+)
+
+// ColorMode selects when the console writer emits ANSI escape codes.
+type ColorMode int
+
+const (
+	// ColorAuto colors the output only when the target is a terminal,
+	// honoring the NO_COLOR and FORCE_COLOR environment variables.
+	ColorAuto ColorMode = iota
+	ColorAlways
+	ColorNever
 )
 
-// customWriter type of customs writers
-type customWriter struct{}
+// LayoutElement is one of the parts that compose a console line.
+type LayoutElement int
+
+const (
+	LayoutTime    LayoutElement = iota // 2006-01-02T15:04:05Z07:00
+	LayoutLevel                        // INFO
+	LayoutCaller                       // [package function]
+	LayoutMessage                      // the log message
+	LayoutSource                       // (file:line)
+	LayoutFields                       // key:value pairs of the static fields
+)
+
+// Theme maps a level to the ANSI sequence used to paint it.
+type Theme map[logengine.Level]string
+
+// consoleWriter writes the logs in a human readable format.
+type consoleWriter struct {
+	writeLock sync.Mutex
+	colorOnce sync.Once
+
+	target    io.Writer
+	colorMode ColorMode
+	colored   bool
+	theme     Theme
+	layout    []LayoutElement
+	relative  bool
+	start     time.Time
+}
+
+type ConsoleOption func(c *consoleWriter)
 
-// Logentry logs in JSON format
+// logEntry is a log in JSON format, with the values as text
 type logEntry map[string]string
 
 // ANSI color for terminal
@@ -32,53 +75,229 @@ const (
 	bgBlue   = "\033[44m"
 )
 
-func (c *customWriter) Write(p []byte) (int, error) {
-	log, err := processLogLine(p)
-	if err != nil {
-		return 0, fmt.Errorf("failed to process log line: %w", err)
-	}
+var DefaultTheme = Theme{
+	logengine.Trace: cyan,
+	logengine.Debug: white,
+	logengine.Info:  green,
+	logengine.Warn:  yellow,
+	logengine.Error: red,
+	logengine.Panic: bgRed + bold + white,
+	logengine.Fatal: bgRed + bold + white,
+}
 
-	return os.Stdout.Write(log)
+var DefaultLayout = []LayoutElement{
+	LayoutTime,
+	LayoutLevel,
+	LayoutCaller,
+	LayoutMessage,
+	LayoutSource,
+	LayoutFields,
 }
 
 var (
-	CustomOutput = &customWriter{}
+	CustomOutput = NewConsoleWriter(os.Stdout)
 )
 
 var logEntryKeyDefault = []string{"time", "level", "msg", "file", "package", "function", "line"}
 
-func processLogLine(line []byte) ([]byte, error) {
+// NewConsoleWriter creates a writer that formats every log for humans and
+// writes it to target. By default the color is detected from the target.
+func NewConsoleWriter(target io.Writer, opts ...ConsoleOption) io.Writer {
+	c := &consoleWriter{
+		target: target,
+		theme:  DefaultTheme,
+		layout: DefaultLayout,
+		start:  time.Now(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithColorMode sets when the console writer paints the output.
+func WithColorMode(mode ColorMode) ConsoleOption {
+	return func(c *consoleWriter) {
+		c.colorMode = mode
+	}
+}
+
+// WithTheme overrides the colors of the given levels,
+// the levels not present in theme keep the default colors.
+func WithTheme(theme Theme) ConsoleOption {
+	return func(c *consoleWriter) {
+		merged := make(Theme, len(DefaultTheme)+len(theme))
+		for level, color := range DefaultTheme {
+			merged[level] = color
+		}
+		for level, color := range theme {
+			merged[level] = color
+		}
+		c.theme = merged
+	}
+}
+
+// WithLayout sets which parts compose a line and in which order.
+func WithLayout(elements ...LayoutElement) ConsoleOption {
+	return func(c *consoleWriter) {
+		if len(elements) == 0 {
+			return
+		}
+		c.layout = slices.Clone(elements)
+	}
+}
+
+// WithRelativeTime shows the time elapsed since the writer creation
+// instead of the wall clock time.
+// The entries carry their time to the second, so it is only accurate to the second.
+func WithRelativeTime(enabled bool) ConsoleOption {
+	return func(c *consoleWriter) {
+		c.relative = enabled
+	}
+}
+
+func (c *consoleWriter) Write(p []byte) (int, error) {
+	log, err := c.processLogLine(p)
+	if err != nil {
+		return 0, fmt.Errorf("failed to process log line: %w", err)
+	}
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if _, err := c.target.Write(log); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *consoleWriter) processLogLine(line []byte) ([]byte, error) {
 	if line == nil {
 		return nil, ErrNilLine
 	}
 
-	var entry logEntry
-	err := json.Unmarshal(line, &entry)
+	entry, err := parseLogEntry(line)
 	if err != nil {
 		return nil, err
 	}
 
-	timestamp := formatTimestamp(entry["time"])
-	functionName := formatFunctionName(entry["function"])
-	levelColor := getLevelColor(entry["level"])
-	staticField := formatStaticField(entry)
+	c.colorOnce.Do(func() {
+		c.colored = useColor(c.colorMode, c.target)
+	})
+
+	levelColor := c.getLevelColor(entry["level"])
+
+	parts := make([]string, 0, len(c.layout))
+	for _, element := range c.layout {
+		switch element {
+		case LayoutTime:
+			parts = append(parts, c.paint(bold+white, c.formatTime(entry["time"])))
+		case LayoutLevel:
+			parts = append(parts, c.paint(levelColor, entry["level"]))
+		case LayoutCaller:
+			if _, ok := entry["function"]; !ok {
+				continue // the caller info is disabled
+			}
+			parts = append(parts, "["+c.paint(cyan, entry["package"])+" "+c.paint(blue, formatFunctionName(entry["function"]))+"]")
+		case LayoutMessage:
+			parts = append(parts, c.paint(levelColor, entry["msg"]))
+		case LayoutSource:
+			if _, ok := entry["file"]; !ok {
+				continue
+			}
+			parts = append(parts, "("+c.paint(magenta, entry["file"]+":"+entry["line"])+")")
+		case LayoutFields:
+			parts = append(parts, formatStaticField(entry))
+		}
+	}
+
+	return []byte(strings.Join(parts, " ") + "\n"), nil
+}
+
+// parseLogEntry decodes a JSON log, the string values are unquoted
+// and the other values, such as numbers, arrays or null, are kept as JSON.
+func parseLogEntry(line []byte) (logEntry, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return nil, err
+	}
+
+	entry := make(logEntry, len(raw))
+	for key, value := range raw {
+		s := string(value)
+		if len(value) > 0 && value[0] == '"' {
+			if err := json.Unmarshal(value, &s); err != nil {
+				return nil, err
+			}
+		}
+		entry[key] = s
+	}
+	return entry, nil
+}
+
+func (c *consoleWriter) paint(color string, s string) string {
+	if !c.colored || color == "" {
+		return s
+	}
+	return color + s + reset
+}
+
+func (c *consoleWriter) formatTime(timeStr string) string {
+	if !c.relative {
+		return formatTimestamp(timeStr)
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, timeStr)
+	if err != nil {
+		t = time.Now()
+	}
+	return formatElapsed(t.Sub(c.start))
+}
 
-	formatLine := fmt.Sprintf("%s%s%s%s %s%s%s [%s%s%s %s] %s%s%s (%s%s:%s%s) %s\n",
-		bold, white, timestamp, reset,
-		levelColor, entry["level"], reset,
+func (c *consoleWriter) getLevelColor(level string) string {
+	l, ok := logengine.ParseLevel(level)
+	if !ok {
+		return reset
+	}
+	return c.theme[l]
+}
 
-		cyan, entry["package"], reset,
-		functionName,
+// useColor resolves the color mode for the given target.
+func useColor(mode ColorMode, target io.Writer) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
 
-		levelColor, entry["msg"], reset,
+	if v, ok := os.LookupEnv("NO_COLOR"); ok && v != "" {
+		return false
+	}
+	if v, ok := os.LookupEnv("FORCE_COLOR"); ok && v != "0" {
+		return true
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
 
-		magenta, entry["file"],
-		entry["line"], reset,
+	return isTerminal(target)
+}
 
-		staticField,
-	)
+// isTerminal reports whether w is a character device, such as a TTY.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return false
+	}
 
-	return []byte(formatLine), nil
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
 
 func formatTimestamp(timeStr string) string {
@@ -89,34 +308,28 @@ func formatTimestamp(timeStr string) string {
 	return t.Format(time.RFC3339)
 }
 
+func formatElapsed(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return fmt.Sprintf("+%.3fs", d.Seconds())
+}
+
 func formatFunctionName(function string) string {
 	parts := strings.Split(function, ".")
 	if len(parts) > 1 {
-		return blue + parts[len(parts)-1] + reset
-	}
-	return blue + function + reset
-}
-
-func getLevelColor(level string) string {
-	switch strings.ToUpper(level) {
-	case "DEBUG":
-		return white
-	case "INFO":
-		return green
-	case "WARN":
-		return yellow
-	case "ERROR":
-		return red
-	case "FATAL", "PANIC":
-		return bgRed + bold + white
-	case "TRACE":
-		return cyan
-	default:
-		return reset
+		return parts[len(parts)-1]
 	}
+	return function
 }
+
 func formatStaticField(entry map[string]string) string {
-	numStaticFields := len(entry) - len(logEntryKeyDefault)
+	numStaticFields := 0
+	for k := range entry {
+		if !slices.Contains(logEntryKeyDefault, k) {
+			numStaticFields++
+		}
+	}
 	if numStaticFields == 0 {
 		return ""
 	}
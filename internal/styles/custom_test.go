@@ -1,10 +1,15 @@
 package styles
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"maps"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,25 +28,178 @@ func TestWrite(t *testing.T) {
 	reportLog := fmt.Sprintf(`{"time":"%s","level":"%s","msg":"%s","file":"%s","package":"%s","function":"%s","line":"%d"}
 `, r.Time, r.Level, r.Msg, r.CallerInfo.File, r.CallerInfo.Package, r.CallerInfo.Function, r.CallerInfo.Line)
 
-	t.Run("should write slice of byte on stdout", func(t *testing.T) {
-		processedLog, err := processLogLine([]byte(reportLog))
+	t.Run("should write the formatted log on the target", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		c := NewConsoleWriter(buf, WithColorMode(ColorAlways)).(*consoleWriter)
+
+		processedLog, err := c.processLogLine([]byte(reportLog))
 		if err != nil {
 			t.Errorf("expected no error, but got %q", err)
 		}
 
-		l, err := CustomOutput.Write([]byte(reportLog))
+		l, err := c.Write([]byte(reportLog))
 		if err != nil {
 			t.Errorf("expected no error, but got %q", err)
 		}
-		if l != len(processedLog) {
-			t.Errorf("expected report log to be %v, but got %v", len(processedLog), l)
+		if l != len(reportLog) {
+			t.Errorf("expected written length to be %v, but got %v", len(reportLog), l)
+		}
+		if buf.String() != string(processedLog) {
+			t.Errorf("expected target to receive %q, but got %q", processedLog, buf.String())
+		}
+	})
+
+	t.Run("should not paint the log when color is disabled", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		c := NewConsoleWriter(buf, WithColorMode(ColorNever))
+
+		if _, err := c.Write([]byte(reportLog)); err != nil {
+			t.Errorf("expected no error, but got %q", err)
+		}
+		if strings.Contains(buf.String(), "\033[") {
+			t.Errorf("expected no ANSI codes, but got %q", buf.String())
+		}
+
+		expected := fmt.Sprintf("%s %s [%s %s] %s (%s:%d) \n",
+			formatTimestamp(r.Time), r.Level, r.CallerInfo.Package, formatFunctionName(r.CallerInfo.Function),
+			r.Msg, r.CallerInfo.File, r.CallerInfo.Line)
+		if buf.String() != expected {
+			t.Errorf("expected log to be %q, but got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("should return the error of the target", func(t *testing.T) {
+		targetErr := errors.New("target error")
+		c := NewConsoleWriter(&errorWriter{err: targetErr}, WithColorMode(ColorNever))
+
+		if _, err := c.Write([]byte(reportLog)); !errors.Is(err, targetErr) {
+			t.Errorf("expected error to be %q, but got %q", targetErr, err)
+		}
+	})
+
+	t.Run("should fail when the line is not a log", func(t *testing.T) {
+		c := NewConsoleWriter(io.Discard)
+
+		if _, err := c.Write([]byte("not a log")); err == nil {
+			t.Error("expected an error, but got nil")
+		}
+	})
+}
+
+type errorWriter struct {
+	err error
+}
+
+func (e *errorWriter) Write(p []byte) (int, error) {
+	return 0, e.err
+}
+
+func TestConsoleOptions(t *testing.T) {
+	reportLog := `{"time":"2025-06-17T10:00:00Z","level":"WARN","msg":"disk almost full","file":"main.go","package":"main","function":"main","line":"42"}
+`
+
+	t.Run("should use the custom theme", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		c := NewConsoleWriter(buf, WithColorMode(ColorAlways), WithTheme(Theme{logengine.Warn: bgYellow}))
+
+		if _, err := c.Write([]byte(reportLog)); err != nil {
+			t.Errorf("expected no error, but got %q", err)
+		}
+		if !strings.Contains(buf.String(), bgYellow+"WARN"+reset) {
+			t.Errorf("expected the level painted with the theme, but got %q", buf.String())
+		}
+	})
+
+	t.Run("should keep the default colors not overridden by the theme", func(t *testing.T) {
+		c := NewConsoleWriter(io.Discard, WithTheme(Theme{logengine.Warn: bgYellow})).(*consoleWriter)
+
+		if c.theme[logengine.Info] != green {
+			t.Errorf("expected info color to be %q, but got %q", green, c.theme[logengine.Info])
+		}
+	})
+
+	t.Run("should follow the layout", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		c := NewConsoleWriter(buf, WithColorMode(ColorNever), WithLayout(LayoutLevel, LayoutMessage, LayoutSource))
+
+		if _, err := c.Write([]byte(reportLog)); err != nil {
+			t.Errorf("expected no error, but got %q", err)
+		}
+
+		expected := "WARN disk almost full (main.go:42)\n"
+		if buf.String() != expected {
+			t.Errorf("expected log to be %q, but got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("should show the relative time", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		c := NewConsoleWriter(buf, WithColorMode(ColorNever), WithRelativeTime(true), WithLayout(LayoutTime)).(*consoleWriter)
+		c.start = time.Date(2025, 6, 17, 9, 59, 58, 500_000_000, time.UTC)
+
+		if _, err := c.Write([]byte(reportLog)); err != nil {
+			t.Errorf("expected no error, but got %q", err)
+		}
+
+		expected := "+1.500s\n"
+		if buf.String() != expected {
+			t.Errorf("expected log to be %q, but got %q", expected, buf.String())
+		}
+	})
+}
+
+func TestUseColor(t *testing.T) {
+	t.Run("should respect the forced modes", func(t *testing.T) {
+		if !useColor(ColorAlways, io.Discard) {
+			t.Error("expected color to be enabled")
+		}
+		if useColor(ColorNever, os.Stdout) {
+			t.Error("expected color to be disabled")
+		}
+	})
+
+	t.Run("should not paint when NO_COLOR is set", func(t *testing.T) {
+		t.Setenv("NO_COLOR", "1")
+		t.Setenv("FORCE_COLOR", "1")
+
+		if useColor(ColorAuto, io.Discard) {
+			t.Error("expected color to be disabled")
+		}
+	})
+
+	t.Run("should paint when FORCE_COLOR is set", func(t *testing.T) {
+		t.Setenv("NO_COLOR", "")
+		t.Setenv("FORCE_COLOR", "1")
+
+		if !useColor(ColorAuto, io.Discard) {
+			t.Error("expected color to be enabled")
+		}
+	})
+
+	t.Run("should not paint when target is not a terminal", func(t *testing.T) {
+		t.Setenv("NO_COLOR", "")
+		os.Unsetenv("FORCE_COLOR")
+
+		f, err := os.CreateTemp(t.TempDir(), "console")
+		if err != nil {
+			t.Fatalf("expected no error, but got %q", err)
+		}
+		defer f.Close()
+
+		if useColor(ColorAuto, f) {
+			t.Error("expected color to be disabled for a regular file")
+		}
+		if useColor(ColorAuto, &bytes.Buffer{}) {
+			t.Error("expected color to be disabled for a buffer")
 		}
 	})
 }
 
 func TestProcessLogline(t *testing.T) {
+	colored := NewConsoleWriter(io.Discard, WithColorMode(ColorAlways)).(*consoleWriter)
+
 	t.Run("should return nil when line is nil", func(t *testing.T) {
-		format, err := processLogLine(nil)
+		format, err := colored.processLogLine(nil)
 		if err == nil {
 			t.Errorf("expected an error when line is nil, but got nil")
 		}
@@ -54,7 +212,7 @@ func TestProcessLogline(t *testing.T) {
 	t.Run("should return nil when could not decode the json", func(t *testing.T) {
 		line := []byte(`"key":"value"`)
 
-		log, err := processLogLine(line)
+		log, err := colored.processLogLine(line)
 		if err == nil {
 			t.Errorf("expected an error when decoding json, but got nil")
 		}
@@ -131,8 +289,8 @@ func TestProcessLogline(t *testing.T) {
 		for _, tt := range testCase {
 			t.Run(tt.report.Level.String(), func(t *testing.T) {
 				timestamp := formatTimestamp(tt.report.Time)
-				levelColor := getLevelColor(tt.report.Level.String())
-				functionName := formatFunctionName(tt.report.CallerInfo.Function)
+				levelColor := colored.getLevelColor(tt.report.Level.String())
+				functionName := blue + formatFunctionName(tt.report.CallerInfo.Function) + reset
 
 				tt.expectFormatLog = fmt.Sprintf("%s %s [%s %s] %s (%s:%d%s) \n",
 					bold+white+timestamp+reset,
@@ -150,7 +308,7 @@ func TestProcessLogline(t *testing.T) {
 				tt.reportLog = fmt.Sprintf(`{"time":"%s","level":"%s","msg":"%s","file":"%s","package":"%s","function":"%s","line":"%d"}
 `, tt.report.Time, tt.report.Level, tt.report.Msg, tt.report.CallerInfo.File, tt.report.CallerInfo.Package, tt.report.CallerInfo.Function, tt.report.CallerInfo.Line)
 
-				gotLog, err := processLogLine([]byte(tt.reportLog))
+				gotLog, err := colored.processLogLine([]byte(tt.reportLog))
 				if err != nil {
 					t.Errorf("expected no error, but got %q", err)
 				}
@@ -181,8 +339,8 @@ func TestProcessLogline(t *testing.T) {
 		maps.Copy(entry, staticFieldMap)
 
 		timestamp := formatTimestamp(report.Time)
-		levelColor := getLevelColor(report.Level.String())
-		functionName := formatFunctionName(report.CallerInfo.Function)
+		levelColor := colored.getLevelColor(report.Level.String())
+		functionName := blue + formatFunctionName(report.CallerInfo.Function) + reset
 		staticField := formatStaticField(entry)
 
 		expectFormatLog := fmt.Sprintf("%s %s [%s %s] %s (%s:%d%s) %s\n",
@@ -199,7 +357,7 @@ func TestProcessLogline(t *testing.T) {
 			staticField,
 		)
 
-		gotLog, err := processLogLine([]byte(reportLog))
+		gotLog, err := colored.processLogLine([]byte(reportLog))
 		if err != nil {
 			t.Errorf("expected no error, but got %q", err)
 		}
@@ -225,13 +383,57 @@ func BenchmarkProcessLogLine(b *testing.B) {
 		b.Errorf("expected no error, but got %q", err)
 	}
 
+	colored := NewConsoleWriter(io.Discard, WithColorMode(ColorAlways)).(*consoleWriter)
+
 	b.ResetTimer()
 
 	for range b.N {
-		_, _ = processLogLine([]byte(reportLog))
+		_, _ = colored.processLogLine([]byte(reportLog))
 	}
 }
 
+func TestTypedValues(t *testing.T) {
+	t.Run("should write the values that are not strings as JSON", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		w := NewConsoleWriter(buf, WithColorMode(ColorNever), WithLayout(LayoutLevel, LayoutMessage, LayoutFields))
+
+		line := `{"time":"2025-01-02T03:04:05Z","level":"INFO","msg":"typed","file":"a.go","package":"main","function":"main.main","line":"1","n":5,"ok":true,"err":null,"stack":[{"line":1}]}` + "\n"
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		got := buf.String()
+		if !strings.HasPrefix(got, "INFO typed ") {
+			t.Errorf("expected the level and the message, but got %q", got)
+		}
+		for _, field := range []string{"n:5 ", "ok:true ", "err:null ", `stack:[{"line":1}] `} {
+			if !strings.Contains(got, field) {
+				t.Errorf("expected %q in %q", field, got)
+			}
+		}
+	})
+}
+
+func TestWithoutCallerInfo(t *testing.T) {
+	t.Run("should skip the caller and the source when the caller info is disabled", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		w := NewConsoleWriter(buf, WithColorMode(ColorNever))
+
+		line := `{"time":"2025-01-02T03:04:05Z","level":"INFO","msg":"no caller","user":"alice"}` + "\n"
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		got := buf.String()
+		if strings.ContainsAny(got, "[(") {
+			t.Errorf("expected no caller nor source, but got %q", got)
+		}
+		if !strings.Contains(got, "INFO no caller") || !strings.Contains(got, "user:alice") {
+			t.Errorf("expected the level, the message and the field, but got %q", got)
+		}
+	})
+}
+
 func TestFormatStaticField(t *testing.T) {
 	t.Run("should return empty when does not exist static fields", func(t *testing.T) {
 		expectedFormatStaticFields := ""
@@ -332,7 +534,7 @@ func BenchmarkFormatTimestamp(b *testing.B) {
 func TestFormatFunctionName(t *testing.T) {
 	t.Run("should return the last function", func(t *testing.T) {
 		function := "func1.func2.func3"
-		expectedFormat := blue + "func3" + reset
+		expectedFormat := "func3"
 
 		if format := formatFunctionName(function); format != expectedFormat {
 			t.Errorf("expected format of function to be %q, but got %q", expectedFormat, format)
@@ -341,7 +543,7 @@ func TestFormatFunctionName(t *testing.T) {
 
 	t.Run("should return the correct function name format", func(t *testing.T) {
 		function := "func1"
-		expectedFormat := blue + "func1" + reset
+		expectedFormat := "func1"
 
 		if format := formatFunctionName(function); format != expectedFormat {
 			t.Errorf("expected format of function to be %q, but got %q", expectedFormat, format)
@@ -399,19 +601,30 @@ func TestGetLevelColor(t *testing.T) {
 	}
 
 	t.Run("should return the correct color for each level type", func(t *testing.T) {
+		c := NewConsoleWriter(io.Discard).(*consoleWriter)
 		for _, tt := range testCase {
-			if color := getLevelColor(tt.level); color != tt.expectedColor {
+			if color := c.getLevelColor(tt.level); color != tt.expectedColor {
 				t.Errorf("expected the color of %q to be %q, but got %q", tt.level, tt.expectedColor, color)
 			}
 		}
+	})
 
+	t.Run("should return the color of the theme", func(t *testing.T) {
+		c := NewConsoleWriter(io.Discard, WithTheme(Theme{logengine.Info: blue})).(*consoleWriter)
+		if color := c.getLevelColor("INFO"); color != blue {
+			t.Errorf("expected the color of INFO to be %q, but got %q", blue, color)
+		}
+		if color := c.getLevelColor("ERROR"); color != red {
+			t.Errorf("expected the color of ERROR to be %q, but got %q", red, color)
+		}
 	})
 }
 
 func BenchmarkGetLevelColor(b *testing.B) {
+	c := NewConsoleWriter(io.Discard).(*consoleWriter)
 	b.ResetTimer()
 
 	for range b.N {
-		_ = getLevelColor("INFO")
+		_ = c.getLevelColor("INFO")
 	}
 }
@@ -3,13 +3,36 @@ package usecases
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/IonicHealthUsa/ionlog/internal/infrastructure/memory"
 )
 
+// keyPrefix separates the explicit keys from the call sites, so a key never matches a call site.
+const keyPrefix = "key\x00"
+
+// LogOnce reports whether msg must be logged, it is false when the last message
+// logged from the same call site, identified by args, was msg.
 func LogOnce(logsMemory memory.IRecordMemory, msg string, args ...string) bool {
-	id := memory.GenHash(fmt.Sprintf("%s%s%s", args[0], args[1], args[2]))
+	return logOnce(logsMemory, memory.GenHash(strings.Join(args, "")), msg)
+}
+
+// LogOnceKey is like LogOnce, but the event is identified by key instead of the call site.
+func LogOnceKey(logsMemory memory.IRecordMemory, key string, msg string) bool {
+	return logOnce(logsMemory, KeyID(key), msg)
+}
+
+// ResetLogOnce forgets the last message of key, so the next one is logged.
+func ResetLogOnce(logsMemory memory.IRecordMemory, key string) {
+	logsMemory.RemoveRecord(KeyID(key))
+}
+
+// KeyID returns the record ID of an explicit key.
+func KeyID(key string) uint64 {
+	return memory.GenHash(keyPrefix + key)
+}
 
+func logOnce(logsMemory memory.IRecordMemory, id uint64, msg string) bool {
 	rec := logsMemory.GetRecord(id)
 	if rec == nil {
 		err := logsMemory.AddRecord(id, msg)
@@ -99,3 +99,70 @@ func TestLogOnce(t *testing.T) {
 		}
 	})
 }
+
+func TestLogOnceStats(t *testing.T) {
+	r := memory.NewRecordMemory()
+
+	LogOnce(r, "pkg", "function", "file", "msg")
+	LogOnce(r, "pkg", "function", "file", "msg")
+
+	if st := r.Stats(); st.Misses != 1 || st.Hits != 1 {
+		t.Errorf("expected 1 miss and 1 hit, got %+v", st)
+	}
+}
+
+func TestLogOnceCallSite(t *testing.T) {
+	t.Run("should keep apart the call sites of the same function", func(t *testing.T) {
+		r := memory.NewRecordMemory()
+
+		// the messages alternate, each call site remembers only its own
+		LogOnce(r, "disk full", "file", "pkg", "function", "10")
+		LogOnce(r, "cache miss", "file", "pkg", "function", "20")
+
+		if LogOnce(r, "disk full", "file", "pkg", "function", "10") {
+			t.Error("expected the message of line 10 to be logged only once")
+		}
+		if LogOnce(r, "cache miss", "file", "pkg", "function", "20") {
+			t.Error("expected the message of line 20 to be logged only once")
+		}
+	})
+}
+
+func TestLogOnceKey(t *testing.T) {
+	t.Run("should identify the event by the key", func(t *testing.T) {
+		r := memory.NewRecordMemory()
+
+		if !LogOnceKey(r, "disk-full", "disk is full") {
+			t.Error("expected the return to be 'true', but got 'false'")
+		}
+		if LogOnceKey(r, "disk-full", "disk is full") {
+			t.Error("expected the return to be 'false', but got 'true'")
+		}
+		if !LogOnceKey(r, "disk-almost-full", "disk is full") {
+			t.Error("expected another key to be logged")
+		}
+		if !LogOnceKey(r, "disk-full", "disk is still full") {
+			t.Error("expected a new message of the key to be logged")
+		}
+	})
+
+	t.Run("should not match a call site", func(t *testing.T) {
+		r := memory.NewRecordMemory()
+
+		LogOnce(r, "msg", "site")
+		if !LogOnceKey(r, "site", "msg") {
+			t.Error("expected the key to be apart from the call site")
+		}
+	})
+
+	t.Run("should log again after a reset", func(t *testing.T) {
+		r := memory.NewRecordMemory()
+
+		LogOnceKey(r, "disk-full", "disk is full")
+		ResetLogOnce(r, "disk-full")
+
+		if !LogOnceKey(r, "disk-full", "disk is full") {
+			t.Error("expected the message to be logged after the reset")
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package ionlog
+
+import (
+	"io"
+
+	"github.com/IonicHealthUsa/ionlog/internal/infrastructure/journald"
+)
+
+type JournaldOption = journald.Option
+
+// NewJournaldWriter sends the logs to the systemd journal with the native protocol.
+// The level becomes PRIORITY, the caller CODE_FILE, CODE_LINE and CODE_FUNC,
+// and every static field an uppercase journal field. It fails on systems other than linux.
+func NewJournaldWriter(opts ...JournaldOption) (io.WriteCloser, error) {
+	return journald.NewJournaldWriter(opts...)
+}
+
+// JournaldIdentifier sets SYSLOG_IDENTIFIER, the program name by default.
+func JournaldIdentifier(identifier string) JournaldOption {
+	return journald.WithIdentifier(identifier)
+}
+
+// JournaldSocket sets the journald socket, /run/systemd/journal/socket by default.
+func JournaldSocket(path string) JournaldOption {
+	return journald.WithSocket(path)
+}
@@ -1,148 +1,124 @@
 package ionlog
 
 import (
+	"context"
 	"fmt"
-	"sync"
+	"strconv"
 	"time"
 
 	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
 	"github.com/IonicHealthUsa/ionlog/internal/core/runtimeinfo"
+	"github.com/IonicHealthUsa/ionlog/internal/infrastructure/memory"
 	"github.com/IonicHealthUsa/ionlog/internal/service"
 	"github.com/IonicHealthUsa/ionlog/internal/usecases"
 )
 
-// Start begin the ionlog reports when it does not running
-func Start() {
-	startSync := sync.WaitGroup{}
-	startSync.Add(1)
-	go logger.Start(&startSync)
-	startSync.Wait()
+// ErrDraining is returned by Start and Stop while the logger is stopping.
+var ErrDraining = service.ErrServiceDraining
+
+// Start begins the ionlog reports, it does nothing when they are already running.
+func Start() error {
+	return logger.Start()
+}
+
+// Stop writes the pending logs and stops the ionlog reports, it does nothing when they are stopped.
+// The writers, the static fields and the rotation settings are kept, so Start resumes the logger.
+// The logs made until then are dropped, the first one is reported on stderr.
+func Stop() error {
+	return logger.Stop()
+}
+
+// Reset stops the ionlog reports and discards the configuration of the logger.
+func Reset() error {
+	err := logger.Stop()
+	logger = service.NewCoreService() // Reset the logger, without the early buffer of the initial one
+	return err
 }
 
-// Stop stop the ionlog reports and reset the logger
-func Stop() {
-	logger.Stop()
-	logger = service.NewCoreService() // Reset the logger
+// ErrEntriesLost is returned by Shutdown when entries were dropped before reaching the writers,
+// or by the writers themselves.
+var ErrEntriesLost = logengine.ErrEntriesLost
+
+// Shutdown stops accepting logs, writes the queued ones, syncs and closes the writers that
+// implement io.Closer, except stdout and stderr, stops the rotation and resets the logger.
+// When ctx is done first the logs still queued are discarded. The error wraps ErrEntriesLost
+// with the number of logs lost during the shutdown, along with the failures of the writers and the error of ctx.
+func Shutdown(ctx context.Context) error {
+	err := logger.Shutdown(ctx)
+	logger = service.NewCoreService() // Reset the logger, without the early buffer of the initial one
+	return err
 }
 
-// Flush flushes the reports to the output writers.
+// Flush returns once every log made before the call is written by the output writers.
 func Flush() {
 	logger.LogEngine().FlushReports()
 }
 
-// Info logs a message with level info.
-func Info(msg string) {
-	logger.LogEngine().AsyncReport(
-		logengine.ReportType{
-			Time:       time.Now().Format(time.RFC3339),
-			Level:      logengine.Info,
-			Msg:        msg,
-			CallerInfo: runtimeinfo.GetCallerInfo(2),
-		},
-	)
+// ErrFlushReentrant is returned by FlushContext when it is called from a writer error handler
+// or a LogValuer, they run while the logs are written and the flush would wait for itself.
+var ErrFlushReentrant = logengine.ErrFlushReentrant
+
+// FlushContext is Flush with a deadline, it returns the error of ctx when it is done first,
+// or the errors of the writers that failed to flush.
+func FlushContext(ctx context.Context) error {
+	return logger.LogEngine().FlushContext(ctx)
+}
+
+// Info logs a message with level info, with the given fields.
+func Info(msg string, fields ...Field) {
+	report(logengine.Info, msg, fields)
 }
 
 // Infof logs a message with level info.
-// Arguments are handled in the manner of fmt.Printf.
+// Arguments are handled in the manner of fmt.Printf, by the report goroutine,
+// so they must not be modified after the call, see WithEagerFormatting.
 func Infof(msg string, args ...any) {
-	logger.LogEngine().AsyncReport(
-		logengine.ReportType{
-			Time:       time.Now().Format(time.RFC3339),
-			Level:      logengine.Info,
-			Msg:        fmt.Sprintf(msg, args...),
-			CallerInfo: runtimeinfo.GetCallerInfo(2),
-		},
-	)
+	reportf(logengine.Info, msg, args)
 }
 
-// Error logs a message with level error.
-func Error(msg string) {
-	logger.LogEngine().AsyncReport(
-		logengine.ReportType{
-			Time:       time.Now().Format(time.RFC3339),
-			Level:      logengine.Error,
-			Msg:        msg,
-			CallerInfo: runtimeinfo.GetCallerInfo(2),
-		},
-	)
+// Error logs a message with level error, with the given fields.
+func Error(msg string, fields ...Field) {
+	report(logengine.Error, msg, fields)
 }
 
 // Errorf logs a message with level error.
-// Arguments are handled in the manner of fmt.Printf.
+// Arguments are handled in the manner of fmt.Printf, by the report goroutine,
+// so they must not be modified after the call, see WithEagerFormatting.
 func Errorf(msg string, args ...any) {
-	logger.LogEngine().AsyncReport(
-		logengine.ReportType{
-			Time:       time.Now().Format(time.RFC3339),
-			Level:      logengine.Error,
-			Msg:        fmt.Sprintf(msg, args...),
-			CallerInfo: runtimeinfo.GetCallerInfo(2),
-		},
-	)
+	reportf(logengine.Error, msg, args)
 }
 
-// Warn logs a message with level warn.
-func Warn(msg string) {
-	logger.LogEngine().AsyncReport(
-		logengine.ReportType{
-			Time:       time.Now().Format(time.RFC3339),
-			Level:      logengine.Warn,
-			Msg:        msg,
-			CallerInfo: runtimeinfo.GetCallerInfo(2),
-		},
-	)
+// Warn logs a message with level warn, with the given fields.
+func Warn(msg string, fields ...Field) {
+	report(logengine.Warn, msg, fields)
 }
 
 // Warnf logs a message with level warn.
-// Arguments are handled in the manner of fmt.Printf.
+// Arguments are handled in the manner of fmt.Printf, by the report goroutine,
+// so they must not be modified after the call, see WithEagerFormatting.
 func Warnf(msg string, args ...any) {
-	logger.LogEngine().AsyncReport(
-		logengine.ReportType{
-			Time:       time.Now().Format(time.RFC3339),
-			Level:      logengine.Warn,
-			Msg:        fmt.Sprintf(msg, args...),
-			CallerInfo: runtimeinfo.GetCallerInfo(2),
-		},
-	)
+	reportf(logengine.Warn, msg, args)
 }
 
-// Debug logs a message with level debug.
-func Debug(msg string) {
-	logger.LogEngine().AsyncReport(
-		logengine.ReportType{
-			Time:       time.Now().Format(time.RFC3339),
-			Level:      logengine.Debug,
-			Msg:        msg,
-			CallerInfo: runtimeinfo.GetCallerInfo(2),
-		},
-	)
+// Debug logs a message with level debug, with the given fields.
+func Debug(msg string, fields ...Field) {
+	report(logengine.Debug, msg, fields)
 }
 
 // Debugf logs a message with level debug.
-// Arguments are handled in the manner of fmt.Printf.
+// Arguments are handled in the manner of fmt.Printf, by the report goroutine,
+// so they must not be modified after the call, see WithEagerFormatting.
 func Debugf(msg string, args ...any) {
-	logger.LogEngine().AsyncReport(
-		logengine.ReportType{
-			Time:       time.Now().Format(time.RFC3339),
-			Level:      logengine.Debug,
-			Msg:        fmt.Sprintf(msg, args...),
-			CallerInfo: runtimeinfo.GetCallerInfo(2),
-		},
-	)
+	reportf(logengine.Debug, msg, args)
 }
 
-// Trace logs a message with level trace only when trace mode is enable.
-func Trace(msg string) {
+// Trace logs a message with level trace only when trace mode is enable, with the given fields.
+func Trace(msg string, fields ...Field) {
 	if !logger.LogEngine().TraceMode() {
 		return
 	}
-	logger.LogEngine().Report(
-		logengine.ReportType{
-			Time:       time.Now().Format(time.RFC3339),
-			Level:      logengine.Trace,
-			Msg:        msg,
-			CallerInfo: runtimeinfo.GetCallerInfo(2),
-		},
-	)
+
+	report(logengine.Trace, msg, fields)
 }
 
 // Tracef logs a message with level trace only when trace mode is enable.
@@ -151,14 +127,8 @@ func Tracef(msg string, args ...any) {
 	if !logger.LogEngine().TraceMode() {
 		return
 	}
-	logger.LogEngine().Report(
-		logengine.ReportType{
-			Time:       time.Now().Format(time.RFC3339),
-			Level:      logengine.Trace,
-			Msg:        fmt.Sprintf(msg, args...),
-			CallerInfo: runtimeinfo.GetCallerInfo(2),
-		},
-	)
+
+	reportf(logengine.Trace, msg, args)
 }
 
 // LogOnceInfo logs a message with level info only once time.
@@ -208,8 +178,51 @@ func LogOnceDebugf(msg string, args ...any) {
 	logOnce(logengine.Debug, fmt.Sprintf(msg, args...))
 }
 
+// LogOnceKey logs a message with the given level only when it differs from the
+// last message logged with the same key, wherever it is called from.
+func LogOnceKey(level Level, key string, msg string) {
+	logOnceKey(level, key, msg)
+}
+
+// LogOnceKeyf logs a message with the given level only when it differs from the
+// last message logged with the same key, wherever it is called from.
+// Arguments are handled in the manner of fmt.Printf.
+func LogOnceKeyf(level Level, key string, msg string, args ...any) {
+	logOnceKey(level, key, fmt.Sprintf(msg, args...))
+}
+
+// ResetLogOnce forgets the last message logged with key, so the next one is logged.
+func ResetLogOnce(key string) {
+	usecases.ResetLogOnce(logger.LogEngine().Memory(), key)
+}
+
+// LogOnceStats is a snapshot of the counters of the LogOnce memory.
+type LogOnceStats = memory.Stats
+
+// LogOnceMemoryStats returns how many call sites and keys are remembered by the
+// LogOnce functions, and how many were evicted or expired.
+func LogOnceMemoryStats() LogOnceStats {
+	return logger.LogEngine().Memory().Stats()
+}
+
+func logOnceKey(level logengine.Level, key string, recordMsg string) {
+	if !usecases.LogOnceKey(logger.LogEngine().Memory(), key, recordMsg) {
+		return
+	}
+
+	logger.LogEngine().AsyncReport(
+		logengine.ReportType{
+			At:    time.Now(),
+			Level: level,
+			Msg:   recordMsg,
+			PC:    callerPC(3),
+		},
+	)
+}
+
 // logOnce send the information about the function
 // which called the log level to report queue asynchronously.
+// Every call site, down to the line, is remembered apart.
 func logOnce(level logengine.Level, recordMsg string) {
 	callerInfo := runtimeinfo.GetCallerInfo(3)
 
@@ -217,8 +230,9 @@ func logOnce(level logengine.Level, recordMsg string) {
 		logger.LogEngine().Memory(),
 		recordMsg,
 		callerInfo.File,
-		callerInfo.Package,
+		callerInfo.PackagePath,
 		callerInfo.Function,
+		strconv.Itoa(callerInfo.Line),
 	)
 
 	if !proceed {
@@ -227,10 +241,42 @@ func logOnce(level logengine.Level, recordMsg string) {
 
 	logger.LogEngine().AsyncReport(
 		logengine.ReportType{
-			Time:       time.Now().Format(time.RFC3339),
+			At:         time.Now(),
 			Level:      level,
 			Msg:        recordMsg,
 			CallerInfo: callerInfo,
 		},
 	)
 }
+
+// callerPC returns the program counter of the function skip frames above its caller,
+// the report goroutine resolves it when the caller info is enabled.
+// It is captured even when disabled, the samplers tell the call sites apart with it.
+func callerPC(skip int) uintptr {
+	return runtimeinfo.CallerPC(skip + 1)
+}
+
+// report queues the entry in a pooled report, the fields are copied into it
+// and encoded by the report goroutine, so logging does not allocate.
+func report(level logengine.Level, msg string, fields []Field) {
+	r := logengine.AcquireReport()
+	r.At = time.Now()
+	r.Level = level
+	r.Msg = msg
+	r.PC = callerPC(3)
+	r.Fields = append(r.Fields, fields...)
+
+	logger.LogEngine().Submit(r)
+}
+
+// reportf queues the entry in a pooled report, the message is formatted by the report goroutine.
+func reportf(level logengine.Level, format string, args []any) {
+	r := logengine.AcquireReport()
+	r.At = time.Now()
+	r.Level = level
+	r.Format = format
+	r.PC = callerPC(3)
+	r.Args = append(r.Args, args...)
+
+	logger.LogEngine().Submit(r)
+}
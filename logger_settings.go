@@ -2,7 +2,9 @@ package ionlog
 
 import (
 	"io"
+	"time"
 
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
 	"github.com/IonicHealthUsa/ionlog/internal/core/rotationengine"
 	"github.com/IonicHealthUsa/ionlog/internal/service"
 )
@@ -65,6 +67,7 @@ func WithLogFileRotation(
 
 // WithQueueSize sets the size of the reports queue,
 // which stores logs before sending them to a file descriptor.
+// The logs already queued are kept.
 func WithQueueSize(size uint) customAttrs {
 	return func(i service.ICoreService) {
 		i.LogEngine().SetReportQueueSize(size)
@@ -79,3 +82,109 @@ func WithTraceMode(mode bool) customAttrs {
 		i.LogEngine().SetTraceMode(mode)
 	}
 }
+
+// WithAsyncWriter adds w as a write target isolated behind its own bounded queue
+// and goroutine, so a slow or hung w does not delay the other writers.
+// The writer can be removed with WithoutWriters(w).
+func WithAsyncWriter(w io.Writer, opts ...AsyncOption) customAttrs {
+	return func(i service.ICoreService) {
+		i.LogEngine().Writer().AddWriter(logengine.NewAsyncWriter(w, opts...))
+	}
+}
+
+// WithWriterErrorHandler sets the function called when a writer fails to write an entry,
+// it may be called from the goroutine of an async writer.
+// Without a handler, the failures are printed to stderr.
+func WithWriterErrorHandler(handler func(w io.Writer, err error)) customAttrs {
+	return func(i service.ICoreService) {
+		i.LogEngine().Writer().SetErrorHandler(handler)
+	}
+}
+
+// WithWriterHealthHandler sets the function called when the health of a writer changes.
+func WithWriterHealthHandler(handler func(w io.Writer, health WriterHealth)) customAttrs {
+	return func(i service.ICoreService) {
+		i.LogEngine().Writer().SetHealthHandler(handler)
+	}
+}
+
+// WithLogOnceMemory bounds the memory of the LogOnce functions: at most capacity call sites
+// and keys are remembered, the least recently used are forgotten first, and a call site
+// or key not used for ttl is forgotten, so its next message is logged again.
+// A zero capacity or ttl removes that bound.
+func WithLogOnceMemory(capacity int, ttl time.Duration) customAttrs {
+	return func(i service.ICoreService) {
+		i.LogEngine().Memory().SetLimits(capacity, ttl)
+	}
+}
+
+// WithDedup collapses the consecutive identical entries, same level, caller and message,
+// into the first one followed by a "message repeated N times" entry, written when
+// a different entry arrives or window passes since the first repeat.
+// A zero window disables the deduplication.
+func WithDedup(window time.Duration) customAttrs {
+	return func(i service.ICoreService) {
+		i.LogEngine().SetDedupWindow(window)
+	}
+}
+
+// WithStackTrace attaches the stack trace of the caller, as the "stack" array of frames,
+// to the entries at or above level.
+func WithStackTrace(level Level) customAttrs {
+	return func(i service.ICoreService) {
+		i.LogEngine().SetStackTrace(true, level)
+	}
+}
+
+// WithoutStackTrace stops attaching the stack trace to the entries.
+func WithoutStackTrace() customAttrs {
+	return func(i service.ICoreService) {
+		i.LogEngine().SetStackTrace(false, 0)
+	}
+}
+
+// WithCallerInfo sets whether the entries have the file, package, function and line
+// of the caller, it is enabled by default. Disabling it skips the lookup of the file, function
+// and line of the caller for a higher throughput, the samplers still tell the call sites apart.
+func WithCallerInfo(enabled bool) customAttrs {
+	return func(i service.ICoreService) {
+		i.LogEngine().SetCallerInfo(enabled)
+	}
+}
+
+// WithCallerFormat sets how the file, package and function of the caller are written,
+// the formats can be combined, e.g. CallerFullPackage|CallerModuleFile.
+func WithCallerFormat(format CallerFormat) customAttrs {
+	return func(i service.ICoreService) {
+		i.LogEngine().SetCallerFormat(format)
+	}
+}
+
+// WithEagerFormatting sets whether the messages of the functions that format, the lazy fields
+// and the Any and Err fields are formatted by the goroutine that logs before the entry is queued.
+// By default they are formatted by the report goroutine, so a pointer, slice or map argument
+// must not be modified after the call; enabling it snapshots them, at the cost of the caller.
+func WithEagerFormatting(enabled bool) customAttrs {
+	return func(i service.ICoreService) {
+		i.LogEngine().SetEagerFormat(enabled)
+	}
+}
+
+// WithEarlyBuffer sets how many logs made before the first Start are kept, 10000 by default
+// until the logger is reset, they are written in order once the logger starts, and the logs
+// beyond size are dropped.
+// Zero stops keeping them, they are queued as the logs made after the start.
+func WithEarlyBuffer(size uint) customAttrs {
+	return func(i service.ICoreService) {
+		i.LogEngine().SetEarlyBuffer(size)
+	}
+}
+
+// WithBootstrapWriter writes the logs made before the first Start to w at once, e.g. os.Stderr,
+// instead of keeping them until the start, so they are seen even if the start never comes.
+// Nil keeps them again.
+func WithBootstrapWriter(w io.Writer) customAttrs {
+	return func(i service.ICoreService) {
+		i.LogEngine().SetBootstrapWriter(w)
+	}
+}
@@ -0,0 +1,87 @@
+package ionlog
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/batchwriter"
+	"github.com/IonicHealthUsa/ionlog/internal/infrastructure/otlp"
+)
+
+type BatchOption = batchwriter.Option
+
+// BatchSize sends a batch when it holds maxEntries entries or maxBytes bytes.
+func BatchSize(maxEntries int, maxBytes int) BatchOption {
+	return batchwriter.WithBatchSize(maxEntries, maxBytes)
+}
+
+// BatchInterval sends the batch being filled after interval, even if it is not full.
+// An interval that is not positive keeps the default of one second.
+func BatchInterval(interval time.Duration) BatchOption {
+	return batchwriter.WithInterval(interval)
+}
+
+// BatchMaxPending bounds the bytes waiting to be sent, the oldest batches are dropped first.
+func BatchMaxPending(maxBytes int) BatchOption {
+	return batchwriter.WithMaxPending(maxBytes)
+}
+
+// BatchRetries sets how many times a failed batch is sent again, the backoff doubles on each retry.
+func BatchRetries(retries int, backoff time.Duration) BatchOption {
+	return batchwriter.WithRetries(retries, backoff)
+}
+
+type OTLPEncoding = otlp.Encoding
+
+const (
+	OTLPProtobuf = otlp.Protobuf
+	OTLPJSON     = otlp.JSON
+)
+
+// OTLPDefaultEndpoint is the logs endpoint of a collector running on the same host.
+const OTLPDefaultEndpoint = otlp.DefaultEndpoint
+
+type OTLPOption = otlp.Option
+
+// NewOTLPWriter exports the logs as OpenTelemetry log records to the collector at endpoint.
+// The static fields become resource attributes and the "trace_id" and "span_id" fields
+// correlate the records with the traces.
+func NewOTLPWriter(endpoint string, opts ...OTLPOption) (io.WriteCloser, error) {
+	return otlp.NewOTLPWriter(endpoint, opts...)
+}
+
+// OTLPEncodingOf sets the payload format, OTLPProtobuf by default.
+func OTLPEncodingOf(encoding OTLPEncoding) OTLPOption {
+	return otlp.WithEncoding(encoding)
+}
+
+// OTLPHeaders adds headers to every request, e.g. the authorization of the collector.
+func OTLPHeaders(headers map[string]string) OTLPOption {
+	return otlp.WithHeaders(headers)
+}
+
+// OTLPHTTPClient sets the client used to send the requests.
+func OTLPHTTPClient(client *http.Client) OTLPOption {
+	return otlp.WithHTTPClient(client)
+}
+
+// OTLPTimeout bounds every export request.
+func OTLPTimeout(timeout time.Duration) OTLPOption {
+	return otlp.WithTimeout(timeout)
+}
+
+// OTLPServiceName sets the service.name resource attribute.
+func OTLPServiceName(name string) OTLPOption {
+	return otlp.WithServiceName(name)
+}
+
+// OTLPTraceKeys sets the fields that hold the trace and span IDs.
+func OTLPTraceKeys(traceKey string, spanKey string) OTLPOption {
+	return otlp.WithTraceKeys(traceKey, spanKey)
+}
+
+// OTLPBatching sets how the records are grouped in requests.
+func OTLPBatching(opts ...BatchOption) OTLPOption {
+	return otlp.WithBatching(opts...)
+}
@@ -0,0 +1,34 @@
+package ionlog
+
+import (
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/sampler"
+	"github.com/IonicHealthUsa/ionlog/internal/service"
+)
+
+// Sampler limits how many entries a call site emits.
+// The next entry emitted has a "suppressed" field with the count of the entries dropped before it.
+type Sampler = sampler.ISampler
+
+// Every emits at most one entry per interval from each call site.
+func Every(interval time.Duration) Sampler {
+	return sampler.NewEvery(interval)
+}
+
+// Burst emits at most n entries per period from each call site.
+func Burst(n uint, per time.Duration) Sampler {
+	return sampler.NewBurst(n, per)
+}
+
+// FirstThenEvery emits the first entries of each call site, then one of every entries.
+func FirstThenEvery(first uint, every uint) Sampler {
+	return sampler.NewFirstThenEvery(first, every)
+}
+
+// WithLevelSampler samples the entries of level from every call site, nil removes the sampler.
+func WithLevelSampler(level Level, s Sampler) customAttrs {
+	return func(i service.ICoreService) {
+		i.LogEngine().SetLevelSampler(level, s)
+	}
+}
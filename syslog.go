@@ -0,0 +1,89 @@
+package ionlog
+
+import (
+	"crypto/tls"
+	"io"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/infrastructure/syslog"
+)
+
+type SyslogFormat = syslog.Format
+
+const (
+	RFC5424 = syslog.RFC5424
+	RFC3164 = syslog.RFC3164
+)
+
+type SyslogFraming = syslog.Framing
+
+const (
+	DefaultFraming = syslog.DefaultFraming
+	OctetCounting  = syslog.OctetCounting
+	NonTransparent = syslog.NonTransparent
+	NoFraming      = syslog.NoFraming
+)
+
+type SyslogFacility = syslog.Facility
+
+const (
+	FacilityUser   = syslog.User
+	FacilityDaemon = syslog.Daemon
+	FacilityLocal0 = syslog.Local0
+	FacilityLocal1 = syslog.Local1
+	FacilityLocal2 = syslog.Local2
+	FacilityLocal3 = syslog.Local3
+	FacilityLocal4 = syslog.Local4
+	FacilityLocal5 = syslog.Local5
+	FacilityLocal6 = syslog.Local6
+	FacilityLocal7 = syslog.Local7
+)
+
+type SyslogOption = syslog.Option
+
+// NewSyslogWriter connects to a syslog server, network is "udp", "tcp", "tls", "unix" or "unixgram".
+// Empty network and address connect to the local syslog socket.
+// The static fields are sent as RFC 5424 structured data.
+func NewSyslogWriter(network string, address string, opts ...SyslogOption) (io.WriteCloser, error) {
+	return syslog.NewSyslogWriter(network, address, opts...)
+}
+
+// SyslogFormatOf sets the message format, RFC5424 by default.
+func SyslogFormatOf(format SyslogFormat) SyslogOption {
+	return syslog.WithFormat(format)
+}
+
+// SyslogFramingOf sets how messages are delimited on TCP, TLS and unix streams.
+func SyslogFramingOf(framing SyslogFraming) SyslogOption {
+	return syslog.WithFraming(framing)
+}
+
+// SyslogFacilityOf sets the facility of the messages, FacilityUser by default.
+func SyslogFacilityOf(facility SyslogFacility) SyslogOption {
+	return syslog.WithFacility(facility)
+}
+
+// SyslogAppName sets the APP-NAME of the messages, the program name by default.
+func SyslogAppName(appName string) SyslogOption {
+	return syslog.WithAppName(appName)
+}
+
+// SyslogHostname sets the HOSTNAME of the messages.
+func SyslogHostname(hostname string) SyslogOption {
+	return syslog.WithHostname(hostname)
+}
+
+// SyslogEnterpriseID sets the private enterprise number of the structured data IDs.
+func SyslogEnterpriseID(id string) SyslogOption {
+	return syslog.WithEnterpriseID(id)
+}
+
+// SyslogTLS sets the TLS configuration of the "tls" network.
+func SyslogTLS(config *tls.Config) SyslogOption {
+	return syslog.WithTLSConfig(config)
+}
+
+// SyslogDialTimeout bounds the time to connect to the server.
+func SyslogDialTimeout(timeout time.Duration) SyslogOption {
+	return syslog.WithDialTimeout(timeout)
+}
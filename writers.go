@@ -0,0 +1,111 @@
+package ionlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/IonicHealthUsa/ionlog/internal/core/logengine"
+	"github.com/IonicHealthUsa/ionlog/internal/core/retrywriter"
+	"github.com/IonicHealthUsa/ionlog/internal/core/spool"
+)
+
+type DropPolicy = logengine.DropPolicy
+
+const (
+	DropNewest       = logengine.DropNewest
+	DropOldest       = logengine.DropOldest
+	BlockWithTimeout = logengine.BlockWithTimeout
+)
+
+type WriterHealth = logengine.WriterHealth
+
+const (
+	Healthy  = logengine.Healthy
+	Degraded = logengine.Degraded
+	Stalled  = logengine.Stalled
+)
+
+type AsyncOption = logengine.AsyncOption
+
+// AsyncQueueSize sets how many entries may wait for an async writer.
+func AsyncQueueSize(size uint) AsyncOption {
+	return logengine.WithQueueCapacity(size)
+}
+
+// AsyncDropPolicy sets what an async writer does with entries when its queue is full.
+func AsyncDropPolicy(policy DropPolicy) AsyncOption {
+	return logengine.WithDropPolicy(policy)
+}
+
+// AsyncEnqueueTimeout sets how long BlockWithTimeout waits for room in the queue.
+func AsyncEnqueueTimeout(timeout time.Duration) AsyncOption {
+	return logengine.WithEnqueueTimeout(timeout)
+}
+
+// AsyncWriteTimeout sets how long a write may take before the writer is reported as stalled.
+func AsyncWriteTimeout(timeout time.Duration) AsyncOption {
+	return logengine.WithWriteTimeout(timeout)
+}
+
+// Health returns the health of a registered writer,
+// the boolean is false when w is not registered.
+func Health(w io.Writer) (WriterHealth, bool) {
+	return logger.LogEngine().Writer().Health(w)
+}
+
+type WriterStats = logengine.WriterStats
+
+// NamedWriter gives w a name, used to identify it in the error handler and in the stats.
+func NamedWriter(name string, w io.Writer) io.Writer {
+	return logengine.NewNamedWriter(name, w)
+}
+
+// Stats returns the counters of bytes, entries and errors of every registered writer.
+func Stats() []WriterStats {
+	return logger.LogEngine().Writer().Stats()
+}
+
+type RetryOption = retrywriter.Option
+
+// NewRetryWriter wraps a network writer, such as a net.Conn, so the entries it fails to
+// deliver are retried with exponential backoff, held in a bounded memory buffer while
+// it is down and replayed in order when it recovers. After repeated failures a circuit
+// breaker stops trying until a cooldown expires.
+func NewRetryWriter(w io.Writer, opts ...RetryOption) io.Writer {
+	return retrywriter.NewRetryWriter(w, opts...)
+}
+
+// RetryBuffer bounds the entries held while the writer is down, the oldest are dropped first.
+func RetryBuffer(maxEntries int, maxBytes int) RetryOption {
+	return retrywriter.WithBuffer(maxEntries, maxBytes)
+}
+
+// RetryBackoff sets the first delay between retries, it doubles up to max.
+func RetryBackoff(initial time.Duration, max time.Duration) RetryOption {
+	return retrywriter.WithBackoff(initial, max)
+}
+
+// RetryBreaker opens the circuit after threshold consecutive failures for the cooldown.
+func RetryBreaker(threshold int, cooldown time.Duration) RetryOption {
+	return retrywriter.WithBreaker(threshold, cooldown)
+}
+
+// RetryDialer reconnects after a failed delivery, the writer is replaced by a new one from dial
+// and the previous one is closed. Without it a broken connection is retried as is.
+func RetryDialer(dial func() (io.Writer, error)) RetryOption {
+	return retrywriter.WithDialer(dial)
+}
+
+// RetrySpool keeps the undelivered entries in segment files in folder, bounded by maxSize bytes
+// or unbounded with NoMaxFolderSize, when the memory buffer is full or the circuit is open. They are replayed in order when the
+// target recovers, also after a restart. If the folder can not be used, the spool is disabled.
+func RetrySpool(folder string, maxSize uint) RetryOption {
+	s, err := spool.NewSpool(folder, spool.WithMaxSize(maxSize))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open the spool %q: %v\n", folder, err)
+		return retrywriter.WithSpool(nil)
+	}
+	return retrywriter.WithSpool(s)
+}